@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// noGroup is the name of the group of nodes that do not have the label a matrix is grouped by.
+const noGroup = "<none>"
+
+// Stats summarize the latencies between two groups of nodes.
+type Stats struct {
+	Median       time.Duration `json:"median"`
+	P95          time.Duration `json:"p95"`
+	Max          time.Duration `json:"max"`
+	FailureRatio float64       `json:"failureRatio"`
+	// Samples is the number of pairs of nodes, including failed ones.
+	Samples int `json:"samples"`
+}
+
+func (s Stats) String() string {
+	if s.Samples == 0 {
+		return ""
+	}
+	if s.FailureRatio == 1 {
+		return "- (100%)"
+	}
+	return fmt.Sprintf("%v/%v/%v (%.0f%%)", s.Median, s.P95, s.Max, s.FailureRatio*100)
+}
+
+// percentile returns the p-th percentile of the sorted durations
+// using the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// median returns the median of the sorted durations.
+func median(sorted []time.Duration) time.Duration {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+func newStats(durations []time.Duration, failed int) Stats {
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+	s := Stats{
+		Samples: len(durations) + failed,
+	}
+	if s.Samples == 0 {
+		return s
+	}
+	s.FailureRatio = float64(failed) / float64(s.Samples)
+	if len(durations) == 0 {
+		return s
+	}
+	s.Median = median(durations)
+	s.P95 = percentile(durations, 0.95)
+	s.Max = durations[len(durations)-1]
+	return s
+}
+
+func groupOf(labels map[string]string, key string) string {
+	if v := labelValue(labels, key); v != "" {
+		return v
+	}
+	return noGroup
+}
+
+// GroupBy collapses a padded matrix into a matrix of the groups of nodes
+// that share the same value for the given label.
+// Every cell of the returned matrix holds the statistics of all latencies
+// between the nodes of the two groups; its duration is the median.
+// Latencies of nodes to themselves are ignored.
+func (m matrix) GroupBy(key string) matrix {
+	type cell struct {
+		durations []time.Duration
+		failed    int
+	}
+	cells := make(map[string]map[string]*cell)
+	rows := make(map[string]bool)
+	cols := make(map[string]bool)
+	for _, v := range m {
+		src := groupOf(v.Labels, key)
+		rows[src] = rows[src] || v.Ok
+		if cells[src] == nil {
+			cells[src] = make(map[string]*cell)
+		}
+		for _, l := range v.Latencies {
+			if l.Destination == dummy {
+				continue
+			}
+			dst := groupOf(l.Labels, key)
+			cols[dst] = true
+			if l.id() == v.id() {
+				continue
+			}
+			c, ok := cells[src][dst]
+			if !ok {
+				c = &cell{}
+				cells[src][dst] = c
+			}
+			if l.Ok {
+				c.durations = append(c.durations, l.Duration)
+			} else {
+				c.failed++
+			}
+		}
+	}
+	sorted := func(set map[string]bool) []string {
+		var s []string
+		for k := range set {
+			s = append(s, k)
+		}
+		sort.Strings(s)
+		return s
+	}
+	dsts := sorted(cols)
+	var gm matrix
+	for _, src := range sorted(rows) {
+		v := Vector{
+			Source: src,
+			Host:   src,
+			IP:     naIP,
+			Labels: map[string]string{key: src},
+			Ok:     rows[src],
+		}
+		for _, dst := range dsts {
+			l := Latency{
+				Destination: dst,
+				Host:        dst,
+				IP:          naIP,
+				Labels:      map[string]string{key: dst},
+			}
+			var s Stats
+			if c, ok := cells[src][dst]; ok {
+				s = newStats(c.durations, c.failed)
+			}
+			l.Stats = &s
+			l.Duration = s.Median
+			l.Ok = s.Samples > 0 && s.FailureRatio < 1
+			v.Latencies = append(v.Latencies, l)
+		}
+		gm = append(gm, v)
+	}
+	return gm
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestPercentile(t *testing.T) {
+	d := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	for i, tc := range []struct {
+		p float64
+		d time.Duration
+	}{
+		{p: 0, d: 1},
+		{p: 0.5, d: 5},
+		{p: 0.95, d: 10},
+		{p: 1, d: 10},
+	} {
+		if got := percentile(d, tc.p); got != tc.d {
+			t.Errorf("%d: got %v, expected %v", i, got, tc.d)
+		}
+	}
+	if got := median(d); got != 5 {
+		t.Errorf("got median %v, expected 5", got)
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	za := map[string]string{"topology.kubernetes.io/zone": "a"}
+	zb := map[string]string{"topology.kubernetes.io/zone": "b"}
+	lat := func(dst string, labels map[string]string, ok bool, d time.Duration) Latency {
+		return Latency{Destination: dst, Labels: labels, Ok: ok, Duration: d}
+	}
+	m := matrix{
+		{
+			Source: "http://1:3000/vector",
+			Labels: za,
+			Ok:     true,
+			Latencies: []Latency{
+				lat("http://1:3000", za, true, 0),
+				lat("http://2:3000", za, true, 2*time.Millisecond),
+				lat("http://3:3000", zb, true, 10*time.Millisecond),
+				{Destination: dummy},
+			},
+		},
+		{
+			Source: "http://2:3000/vector",
+			Labels: za,
+			Ok:     true,
+			Latencies: []Latency{
+				lat("http://1:3000", za, true, 4*time.Millisecond),
+				lat("http://2:3000", za, true, 0),
+				lat("http://3:3000", zb, false, 0),
+				lat("http://4:3000", nil, true, time.Millisecond),
+			},
+		},
+		{
+			Source: "http://3:3000/vector",
+			Labels: zb,
+			Ok:     true,
+			Latencies: []Latency{
+				lat("http://1:3000", za, true, 20*time.Millisecond),
+				lat("http://2:3000", za, true, 30*time.Millisecond),
+				lat("http://3:3000", zb, true, 0),
+				{Destination: dummy},
+			},
+		},
+	}
+	cell := func(dst string, s Stats) Latency {
+		return Latency{
+			Destination: dst,
+			Host:        dst,
+			IP:          naIP,
+			Labels:      map[string]string{"zone": dst},
+			Duration:    s.Median,
+			Ok:          s.Samples > 0 && s.FailureRatio < 1,
+			Stats:       &s,
+		}
+	}
+	em := matrix{
+		{
+			Source: "a",
+			Host:   "a",
+			IP:     naIP,
+			Labels: map[string]string{"zone": "a"},
+			Ok:     true,
+			Latencies: []Latency{
+				cell(noGroup, Stats{Median: time.Millisecond, P95: time.Millisecond, Max: time.Millisecond, Samples: 1}),
+				cell("a", Stats{Median: 3 * time.Millisecond, P95: 4 * time.Millisecond, Max: 4 * time.Millisecond, Samples: 2}),
+				cell("b", Stats{Median: 10 * time.Millisecond, P95: 10 * time.Millisecond, Max: 10 * time.Millisecond, FailureRatio: 0.5, Samples: 2}),
+			},
+		},
+		{
+			Source: "b",
+			Host:   "b",
+			IP:     naIP,
+			Labels: map[string]string{"zone": "b"},
+			Ok:     true,
+			Latencies: []Latency{
+				cell(noGroup, Stats{}),
+				cell("a", Stats{Median: 25 * time.Millisecond, P95: 30 * time.Millisecond, Max: 30 * time.Millisecond, Samples: 2}),
+				cell("b", Stats{}),
+			},
+		},
+	}
+	if diff := pretty.Compare(m.GroupBy("zone"), em); diff != "" {
+		t.Errorf("unexpected matrix:\n%s", diff)
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The names of the alerts.
+const (
+	latencyAlert     = "AdjacencyLatencyHigh"
+	unreachableAlert = "AdjacencyNodeUnreachable"
+	partitionAlert   = "AdjacencyNetworkPartitioned"
+)
+
+// The states of an alert in notifications.
+const (
+	firing   = "firing"
+	resolved = "resolved"
+)
+
+// urlsFlag implements the flag.Value interface
+// so that URLs can be given as repeated flags.
+type urlsFlag []string
+
+func (u *urlsFlag) String() string {
+	return strings.Join(*u, ",")
+}
+
+func (u *urlsFlag) Set(s string) error {
+	*u = append(*u, s)
+	return nil
+}
+
+// alertRules configure when alerts fire.
+type alertRules struct {
+	// latency is the latency of a pair of nodes above which an alert fires
+	// after latencyRounds consecutive snapshots.
+	// A value of 0 disables the rule.
+	latency       time.Duration
+	latencyRounds int
+	// unreachableFrom is the number of peers a node must be unreachable from
+	// for an alert to fire.
+	// A negative value disables the rule.
+	unreachableFrom int
+	// partition enables the alert for partitioned networks.
+	partition bool
+}
+
+func (r alertRules) enabled() bool {
+	return r.latency > 0 || r.unreachableFrom >= 0 || r.partition
+}
+
+// An Alert is a condition of the network that was detected in the matrix.
+// It uses the same fields as alerts for Alertmanager.
+type Alert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    time.Time         `json:"startsAt"`
+	// EndsAt is only set for resolved alerts.
+	EndsAt *time.Time `json:"endsAt,omitempty"`
+}
+
+// fingerprint identifies an alert by its labels.
+func (a Alert) fingerprint() string {
+	keys := make([]string, 0, len(a.Labels))
+	for k := range a.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var s []string
+	for _, k := range keys {
+		s = append(s, fmt.Sprintf("%s=%q", k, a.Labels[k]))
+	}
+	return strings.Join(s, ",")
+}
+
+// alerter evaluates the alert rules for every snapshot
+// and notifies webhooks when alerts fire or resolve.
+// It is safe to use concurrently.
+type alerter struct {
+	rules alertRules
+	// webhooks receive a notification when an alert fires or resolves.
+	webhooks []string
+	// alertmanagers receive all active alerts after every evaluation,
+	// as Alertmanager expects, and resolved alerts once.
+	alertmanagers []string
+	client        *http.Client
+
+	mu sync.Mutex
+	// active are the firing alerts by fingerprint.
+	active map[string]Alert
+	// rounds counts the consecutive snapshots in which
+	// the latency of a pair of nodes was above the threshold.
+	rounds map[[2]string]int
+}
+
+func newAlerter(rules alertRules, webhooks, alertmanagers []string) *alerter {
+	return &alerter{
+		rules:         rules,
+		webhooks:      webhooks,
+		alertmanagers: alertmanagers,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		active:        make(map[string]Alert),
+		rounds:        make(map[[2]string]int),
+	}
+}
+
+// conditions returns the alerts whose conditions are met in the matrix.
+// It must be called with the lock held, because it counts the rounds of high latencies.
+func (a *alerter) conditions(m matrix, now time.Time) []Alert {
+	var as []Alert
+	if a.rules.latency > 0 {
+		rounds := make(map[[2]string]int)
+		for _, v := range m {
+			for _, l := range v.Latencies {
+				if l.Destination == dummy || !l.Ok || l.Duration <= a.rules.latency {
+					continue
+				}
+				k := [2]string{v.id(), l.id()}
+				rounds[k] = a.rounds[k] + 1
+				if rounds[k] < a.rules.latencyRounds {
+					continue
+				}
+				src, dst := v.name(), l.name()
+				as = append(as, Alert{
+					Labels: map[string]string{"alertname": latencyAlert, "source": src, "destination": dst},
+					Annotations: map[string]string{
+						"summary": fmt.Sprintf("latency from %s to %s is %v, above %v for %d rounds", src, dst, l.Duration, a.rules.latency, rounds[k]),
+					},
+					StartsAt: now,
+				})
+			}
+		}
+		// Pairs that are not above the threshold anymore start over.
+		a.rounds = rounds
+	}
+	if a.rules.unreachableFrom >= 0 {
+		g := m.graph()
+		for j := range g.names {
+			var peers []string
+			for i := range g.names {
+				if i != j && g.lat[i][j] != nil && !g.lat[i][j].Ok {
+					peers = append(peers, g.names[i])
+				}
+			}
+			if len(peers) > a.rules.unreachableFrom {
+				as = append(as, Alert{
+					Labels: map[string]string{"alertname": unreachableAlert, "node": g.names[j]},
+					Annotations: map[string]string{
+						"summary": fmt.Sprintf("%s is unreachable from %d peers: %s", g.names[j], len(peers), strings.Join(peers, ", ")),
+					},
+					StartsAt: now,
+				})
+			}
+		}
+	}
+	if a.rules.partition {
+		if r := m.Reachability(); len(r.Partitions) > 1 {
+			var ps []string
+			for _, p := range r.Partitions {
+				ps = append(ps, "["+strings.Join(p, ", ")+"]")
+			}
+			as = append(as, Alert{
+				Labels: map[string]string{"alertname": partitionAlert},
+				Annotations: map[string]string{
+					"summary": fmt.Sprintf("the network is partitioned into %d partitions: %s", len(r.Partitions), strings.Join(ps, " ")),
+				},
+				StartsAt: now,
+			})
+		}
+	}
+	return as
+}
+
+// evaluate updates the active alerts with the conditions in the matrix
+// and returns the alerts that started firing and that resolved.
+// Alerts that are already active are not returned again.
+func (a *alerter) evaluate(m matrix, now time.Time) (fired, resolvedAlerts []Alert) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	current := make(map[string]bool)
+	for _, al := range a.conditions(m, now) {
+		f := al.fingerprint()
+		current[f] = true
+		if old, ok := a.active[f]; ok {
+			// Keep the start time, but update the summary.
+			al.StartsAt = old.StartsAt
+			a.active[f] = al
+			continue
+		}
+		a.active[f] = al
+		fired = append(fired, al)
+	}
+	for f, al := range a.active {
+		if !current[f] {
+			al.EndsAt = &now
+			resolvedAlerts = append(resolvedAlerts, al)
+			delete(a.active, f)
+		}
+	}
+	sortAlerts(fired)
+	sortAlerts(resolvedAlerts)
+	return fired, resolvedAlerts
+}
+
+func sortAlerts(as []Alert) {
+	sort.Slice(as, func(i, j int) bool {
+		return as[i].fingerprint() < as[j].fingerprint()
+	})
+}
+
+// Active returns the firing alerts.
+func (a *alerter) Active() []Alert {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	as := make([]Alert, 0, len(a.active))
+	for _, al := range a.active {
+		as = append(as, al)
+	}
+	sortAlerts(as)
+	return as
+}
+
+// Evaluate evaluates the alert rules for the snapshot and sends notifications.
+func (a *alerter) Evaluate(s Snapshot) {
+	fired, res := a.evaluate(s.Matrix, s.Time)
+	ctx := context.Background()
+	if len(fired) > 0 {
+		a.notifyWebhooks(ctx, firing, fired)
+	}
+	if len(res) > 0 {
+		a.notifyWebhooks(ctx, resolved, res)
+	}
+	if len(a.alertmanagers) > 0 {
+		if as := append(a.Active(), res...); len(as) > 0 {
+			for _, u := range a.alertmanagers {
+				if err := a.post(ctx, u, as); err != nil {
+					log.Printf("failed to notify Alertmanager %s: %v\n", u, err)
+					errorCounter.Inc()
+				}
+			}
+		}
+	}
+}
+
+// webhookMessage is the body of notifications to generic webhooks.
+type webhookMessage struct {
+	Status string  `json:"status"`
+	Alerts []Alert `json:"alerts"`
+}
+
+func (a *alerter) notifyWebhooks(ctx context.Context, status string, as []Alert) {
+	for _, u := range a.webhooks {
+		if err := a.post(ctx, u, webhookMessage{Status: status, Alerts: as}); err != nil {
+			log.Printf("failed to notify webhook %s: %v\n", u, err)
+			errorCounter.Inc()
+		}
+	}
+}
+
+func (a *alerter) post(ctx context.Context, u string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal data: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("content-type", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make POST request: %w", err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(ioutil.Discard, resp.Body); err != nil {
+		log.Printf("failed to discard body: %v\n", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("expected status code 2xx, got %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func alertsHandler(a *alerter) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, a.Active())
+	}
+}
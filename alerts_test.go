@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func alertNames(as []Alert) []string {
+	names := []string{}
+	for _, a := range as {
+		n := a.Labels["alertname"]
+		if v, ok := a.Labels["node"]; ok {
+			n += " " + v
+		}
+		if v, ok := a.Labels["source"]; ok {
+			n += " " + v + "->" + a.Labels["destination"]
+		}
+		names = append(names, n)
+	}
+	return names
+}
+
+func TestAlerterEvaluate(t *testing.T) {
+	a := newAlerter(alertRules{
+		latency:         10 * time.Millisecond,
+		latencyRounds:   2,
+		unreachableFrom: 1,
+		partition:       true,
+	}, nil, nil)
+	now := time.Now()
+	for i, tc := range []struct {
+		name     string
+		m        matrix
+		fired    []string
+		resolved []string
+		active   []string
+	}{
+		{
+			name: "first round of high latency",
+			m: squareMatrix([][]int{
+				{0, 20, 1},
+				{1, 0, 1},
+				{1, 1, 0},
+			}),
+			fired:    []string{},
+			resolved: []string{},
+			active:   []string{},
+		},
+		{
+			name: "second round of high latency",
+			m: squareMatrix([][]int{
+				{0, 20, 1},
+				{1, 0, 1},
+				{1, 1, 0},
+			}),
+			fired:    []string{latencyAlert + " n0->n1"},
+			resolved: []string{},
+			active:   []string{latencyAlert + " n0->n1"},
+		},
+		{
+			name: "deduplicated",
+			m: squareMatrix([][]int{
+				{0, 30, 1},
+				{1, 0, 1},
+				{1, 1, 0},
+			}),
+			fired:    []string{},
+			resolved: []string{},
+			active:   []string{latencyAlert + " n0->n1"},
+		},
+		{
+			name: "partition",
+			m: squareMatrix([][]int{
+				{0, 1, -1},
+				{1, 0, -1},
+				{-1, -1, 0},
+			}),
+			fired:    []string{partitionAlert, unreachableAlert + " n2"},
+			resolved: []string{latencyAlert + " n0->n1"},
+			active:   []string{partitionAlert, unreachableAlert + " n2"},
+		},
+		{
+			name: "healthy",
+			m: squareMatrix([][]int{
+				{0, 1, 1},
+				{1, 0, 1},
+				{1, 1, 0},
+			}),
+			fired:    []string{},
+			resolved: []string{partitionAlert, unreachableAlert + " n2"},
+			active:   []string{},
+		},
+		{
+			// A node that fails to answer for its vector is no partition
+			// as long as the other nodes reach it.
+			name: "unresponsive",
+			m: func() matrix {
+				m := squareMatrix([][]int{
+					{0, 1, 1},
+					{1, 0, 1},
+					{-1, -1, 0},
+				})
+				m[2].Ok = false
+				return m
+			}(),
+			fired:    []string{},
+			resolved: []string{},
+			active:   []string{},
+		},
+	} {
+		fired, res := a.evaluate(tc.m, now.Add(time.Duration(i)*time.Second))
+		if diff := pretty.Compare(alertNames(fired), tc.fired); diff != "" {
+			t.Errorf("%d (%s): unexpected fired alerts:\n%s", i, tc.name, diff)
+		}
+		if diff := pretty.Compare(alertNames(res), tc.resolved); diff != "" {
+			t.Errorf("%d (%s): unexpected resolved alerts:\n%s", i, tc.name, diff)
+		}
+		for _, r := range res {
+			if r.EndsAt == nil {
+				t.Errorf("%d (%s): expected resolved alert to have an end", i, tc.name)
+			}
+		}
+		if diff := pretty.Compare(alertNames(a.Active()), tc.active); diff != "" {
+			t.Errorf("%d (%s): unexpected active alerts:\n%s", i, tc.name, diff)
+		}
+	}
+}
+
+func TestAlerterNotify(t *testing.T) {
+	var mu sync.Mutex
+	var messages []webhookMessage
+	var posts [][]Alert
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m webhookMessage
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Errorf("failed to decode webhook message: %v", err)
+		}
+		mu.Lock()
+		messages = append(messages, m)
+		mu.Unlock()
+	}))
+	defer webhook.Close()
+	am := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var as []Alert
+		if err := json.NewDecoder(r.Body).Decode(&as); err != nil {
+			t.Errorf("failed to decode alerts: %v", err)
+		}
+		mu.Lock()
+		posts = append(posts, as)
+		mu.Unlock()
+	}))
+	defer am.Close()
+	a := newAlerter(alertRules{unreachableFrom: 0, partition: false}, []string{webhook.URL}, []string{am.URL})
+	down := squareMatrix([][]int{{0, -1}, {1, 0}})
+	up := squareMatrix([][]int{{0, 1}, {1, 0}})
+	for _, m := range []matrix{down, down, up, up} {
+		a.Evaluate(Snapshot{Matrix: m, Time: time.Now()})
+	}
+	if len(messages) != 2 || messages[0].Status != firing || messages[1].Status != resolved {
+		t.Errorf("expected one firing and one resolved notification, got %v", messages)
+	}
+	// Alertmanager receives the active alerts after every evaluation
+	// and the resolved alert once.
+	if len(posts) != 3 || posts[2][0].EndsAt == nil {
+		t.Errorf("expected three posts to Alertmanager, the last one resolved, got %v", posts)
+	}
+}
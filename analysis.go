@@ -0,0 +1,518 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// nodeGraph is the square part of a matrix, i.e. the nodes that are
+// both sources and destinations and the latencies between them.
+type nodeGraph struct {
+	// names are the names of the nodes as shown in the output.
+	names []string
+	// keys are the hosts and ports of the nodes.
+	keys []string
+	// lat[i][j] is the latency from node i to node j.
+	// It is nil if the latency is unknown.
+	lat [][]*Latency
+}
+
+// graph returns the square part of the matrix.
+// The sources of the matrix are the nodes of the graph
+// and destinations are matched to sources by their host and port.
+func (m matrix) graph() nodeGraph {
+	g := nodeGraph{
+		names: make([]string, len(m)),
+		keys:  make([]string, len(m)),
+		lat:   make([][]*Latency, len(m)),
+	}
+	idx := make(map[string]int, len(m))
+	for i, v := range m {
+		g.names[i] = v.name()
+		g.keys[i] = v.id()
+		idx[g.keys[i]] = i
+	}
+	for i := range m {
+		g.lat[i] = make([]*Latency, len(m))
+		for k, l := range m[i].Latencies {
+			if l.Destination == dummy {
+				continue
+			}
+			if j, ok := idx[l.id()]; ok {
+				g.lat[i][j] = &m[i].Latencies[k]
+			}
+		}
+	}
+	return g
+}
+
+// An Asymmetry is a pair of nodes whose latencies or reachability
+// differ depending on the direction.
+type Asymmetry struct {
+	A         string        `json:"a"`
+	B         string        `json:"b"`
+	Forward   time.Duration `json:"forward"`
+	ForwardOk bool          `json:"forwardOk"`
+	Reverse   time.Duration `json:"reverse"`
+	ReverseOk bool          `json:"reverseOk"`
+	// Ratio is the ratio of the slower to the faster direction.
+	// It is 0 if only one direction is reachable.
+	Ratio float64 `json:"ratio"`
+}
+
+// Asymmetries returns all pairs of nodes where only one direction is reachable
+// or where one direction is at least ratio times slower than the other.
+// Pairs with differing reachability come first,
+// followed by the other pairs ordered by descending ratio.
+func (m matrix) Asymmetries(ratio float64) []Asymmetry {
+	g := m.graph()
+	var as []Asymmetry
+	for i := range g.names {
+		for j := i + 1; j < len(g.names); j++ {
+			f, r := g.lat[i][j], g.lat[j][i]
+			if f == nil || r == nil || (!f.Ok && !r.Ok) {
+				continue
+			}
+			a := Asymmetry{
+				A:         g.names[i],
+				B:         g.names[j],
+				Forward:   f.Duration,
+				ForwardOk: f.Ok,
+				Reverse:   r.Duration,
+				ReverseOk: r.Ok,
+			}
+			if f.Ok && r.Ok {
+				lo, hi := f.Duration, r.Duration
+				if lo > hi {
+					lo, hi = hi, lo
+				}
+				// Avoid dividing by zero for unrealistically fast probes.
+				if lo <= 0 {
+					lo = 1
+				}
+				a.Ratio = float64(hi) / float64(lo)
+				if a.Ratio < ratio {
+					continue
+				}
+			}
+			as = append(as, a)
+		}
+	}
+	sort.SliceStable(as, func(i, j int) bool {
+		if (as[i].Ratio == 0) != (as[j].Ratio == 0) {
+			return as[i].Ratio == 0
+		}
+		return as[i].Ratio > as[j].Ratio
+	})
+	return as
+}
+
+type asymmetries []Asymmetry
+
+func (as asymmetries) String() string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetHeader([]string{"A", "B", "A->B", "B->A", "Ratio"})
+	table.SetAutoFormatHeaders(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	dur := func(d time.Duration, ok bool) string {
+		if !ok {
+			return "-"
+		}
+		return d.String()
+	}
+	for _, a := range as {
+		ratio := "unreachable"
+		if a.Ratio != 0 {
+			ratio = strconv.FormatFloat(a.Ratio, 'f', 2, 64)
+		}
+		table.Append([]string{a.A, a.B, dur(a.Forward, a.ForwardOk), dur(a.Reverse, a.ReverseOk), ratio})
+	}
+	table.Render()
+	return tableString.String()
+}
+
+// Reachability describes the shape of the failures in a square matrix.
+type Reachability struct {
+	// Partitions are the groups of nodes that can reach each other in both directions,
+	// directly or via other nodes, ordered by descending size.
+	// More than one partition means that the network is partitioned.
+	Partitions [][]string `json:"partitions"`
+	// Isolated nodes can neither reach any other node nor be reached by any,
+	// although there are other nodes; a node alone in the matrix is not isolated.
+	Isolated []string `json:"isolated"`
+	// OutboundOnly nodes can reach other nodes but cannot be reached by any.
+	OutboundOnly []string `json:"outboundOnly"`
+	// InboundOnly nodes can be reached by other nodes but cannot reach any.
+	InboundOnly []string `json:"inboundOnly"`
+	// Unresponsive nodes did not answer the request for their vector,
+	// so their outbound reachability is unknown.
+	// They belong to the partition of the nodes that reach them
+	// and are only isolated if no node reaches them.
+	Unresponsive []string `json:"unresponsive"`
+}
+
+// Reachability computes the connected components of the graph of nodes
+// that can reach each other in both directions and classifies nodes
+// that can only reach or only be reached by others.
+func (m matrix) Reachability() Reachability {
+	g := m.graph()
+	n := len(g.names)
+	// parent is a union-find forest of the nodes.
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	ok := func(i, j int) bool {
+		return g.lat[i][j] != nil && g.lat[i][j].Ok
+	}
+	// reaches is true if node i can reach node j as far as is known.
+	// The outbound reachability of unresponsive nodes is unknown,
+	// so they are assumed to reach the nodes that reach them.
+	reaches := func(i, j int) bool {
+		return ok(i, j) || !m[i].Ok && ok(j, i)
+	}
+	r := Reachability{
+		Isolated:     []string{},
+		OutboundOnly: []string{},
+		InboundOnly:  []string{},
+		Unresponsive: []string{},
+	}
+	for i := 0; i < n; i++ {
+		// peers is true if the node probed or was probed by any other node.
+		var out, in, peers bool
+		for j := 0; j < n; j++ {
+			if i == j {
+				continue
+			}
+			peers = peers || g.lat[i][j] != nil || g.lat[j][i] != nil
+			out = out || ok(i, j)
+			in = in || ok(j, i)
+			if reaches(i, j) && reaches(j, i) {
+				parent[find(i)] = find(j)
+			}
+		}
+		switch {
+		case !peers:
+			// A node without other nodes can neither reach nor be reached by any.
+		case !out && !in:
+			r.Isolated = append(r.Isolated, g.names[i])
+		case !m[i].Ok:
+			// Whether an unresponsive node can reach others is unknown.
+		case !in:
+			r.OutboundOnly = append(r.OutboundOnly, g.names[i])
+		case !out:
+			r.InboundOnly = append(r.InboundOnly, g.names[i])
+		}
+		if !m[i].Ok {
+			r.Unresponsive = append(r.Unresponsive, g.names[i])
+		}
+	}
+	components := make(map[int][]string)
+	var roots []int
+	for i := 0; i < n; i++ {
+		root := find(i)
+		if _, ok := components[root]; !ok {
+			roots = append(roots, root)
+		}
+		components[root] = append(components[root], g.names[i])
+	}
+	r.Partitions = [][]string{}
+	for _, root := range roots {
+		r.Partitions = append(r.Partitions, components[root])
+	}
+	sort.SliceStable(r.Partitions, func(i, j int) bool {
+		return len(r.Partitions[i]) > len(r.Partitions[j])
+	})
+	return r
+}
+
+// String returns a one-line summary of the reachability.
+func (r Reachability) String() string {
+	sizes := make([]string, len(r.Partitions))
+	for i, p := range r.Partitions {
+		sizes[i] = strconv.Itoa(len(p))
+	}
+	s := fmt.Sprintf("reachability: %d partition(s) of %s node(s)", len(r.Partitions), strings.Join(sizes, ", "))
+	for _, c := range []struct {
+		name  string
+		nodes []string
+	}{
+		{"isolated", r.Isolated},
+		{"outbound only", r.OutboundOnly},
+		{"inbound only", r.InboundOnly},
+		{"unresponsive", r.Unresponsive},
+	} {
+		if len(c.nodes) > 0 {
+			s += fmt.Sprintf("; %s: %s", c.name, strings.Join(c.nodes, ", "))
+		}
+	}
+	return s
+}
+
+func reachabilityHandler(srv string, timeout time.Duration) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m, _, ok := collectFromRequest(w, r, srv, timeout)
+		if !ok {
+			return
+		}
+		writeJSON(w, m.Reachability())
+	}
+}
+
+// A Violation is a pair of nodes whose direct path is slower than the path via a relay node
+// by more than a margin, i.e. the latencies violate the triangle inequality.
+type Violation struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	// Direct is the latency of the direct path, if DirectOk is true.
+	Direct   time.Duration `json:"direct"`
+	DirectOk bool          `json:"directOk"`
+	// Relay is the relay node with the fastest path and Via is the latency of that path.
+	Relay string        `json:"relay"`
+	Via   time.Duration `json:"via"`
+	// Relays is the number of relay nodes that violate the triangle inequality.
+	Relays int `json:"relays"`
+}
+
+// A Route is the shortest path between two nodes that is faster than their direct path.
+type Route struct {
+	Source      string        `json:"source"`
+	Destination string        `json:"destination"`
+	Direct      time.Duration `json:"direct"`
+	DirectOk    bool          `json:"directOk"`
+	Shortest    time.Duration `json:"shortest"`
+	// Path lists all nodes of the shortest path, including the source and the destination.
+	Path []string `json:"path"`
+}
+
+// Paths is the result of the analysis of the paths between the nodes of a square matrix.
+type Paths struct {
+	Violations []Violation `json:"violations"`
+	// Recommendations are the routes via relays that are faster than the direct path by more than the margin.
+	Recommendations []Route `json:"recommendations"`
+
+	// highlight maps the keys of pairs of nodes to the color of their edge in the SVG output.
+	highlight map[[2]string]string
+}
+
+const (
+	violationColor = "red"
+	relayColor     = "blue"
+)
+
+// Paths finds all pairs of nodes that violate the triangle inequality by more than the margin
+// and computes the shortest paths between all nodes using the Floyd-Warshall algorithm
+// to recommend relay nodes.
+// Unreachable direct paths are slower than any path via a relay.
+func (m matrix) Paths(margin time.Duration) Paths {
+	g := m.graph()
+	n := len(g.names)
+	const inf = time.Duration(1<<63 - 1)
+	dist := make([][]time.Duration, n)
+	next := make([][]int, n)
+	for i := range dist {
+		dist[i] = make([]time.Duration, n)
+		next[i] = make([]int, n)
+		for j := range dist[i] {
+			dist[i][j], next[i][j] = inf, -1
+			if i == j {
+				dist[i][j], next[i][j] = 0, j
+			} else if l := g.lat[i][j]; l != nil && l.Ok {
+				dist[i][j], next[i][j] = l.Duration, j
+			}
+		}
+	}
+	p := Paths{
+		Violations:      []Violation{},
+		Recommendations: []Route{},
+		highlight:       make(map[[2]string]string),
+	}
+	direct := func(i, j int) (time.Duration, bool) {
+		if dist[i][j] == inf {
+			return 0, false
+		}
+		return dist[i][j], true
+	}
+	// Find the violations before the distances are updated.
+	for i := 0; i < n; i++ {
+		for k := 0; k < n; k++ {
+			if i == k {
+				continue
+			}
+			d, ok := direct(i, k)
+			v := Violation{Source: g.names[i], Destination: g.names[k], Direct: d, DirectOk: ok}
+			for j := 0; j < n; j++ {
+				if j == i || j == k || dist[i][j] == inf || dist[j][k] == inf {
+					continue
+				}
+				via := dist[i][j] + dist[j][k]
+				if ok && via+margin >= d {
+					continue
+				}
+				v.Relays++
+				if v.Relays == 1 || via < v.Via {
+					v.Relay, v.Via = g.names[j], via
+				}
+			}
+			if v.Relays > 0 {
+				p.Violations = append(p.Violations, v)
+				p.highlight[[2]string{g.keys[i], g.keys[k]}] = violationColor
+			}
+		}
+	}
+	for k := 0; k < n; k++ {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				if dist[i][k] == inf || dist[k][j] == inf {
+					continue
+				}
+				if d := dist[i][k] + dist[k][j]; d < dist[i][j] {
+					dist[i][j], next[i][j] = d, next[i][k]
+				}
+			}
+		}
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i == j || next[i][j] == -1 {
+				continue
+			}
+			l := g.lat[i][j]
+			directOk := l != nil && l.Ok
+			if directOk && dist[i][j]+margin >= l.Duration {
+				continue
+			}
+			r := Route{
+				Source:      g.names[i],
+				Destination: g.names[j],
+				DirectOk:    directOk,
+				Shortest:    dist[i][j],
+				Path:        []string{g.names[i]},
+			}
+			if directOk {
+				r.Direct = l.Duration
+			}
+			for u := i; u != j; {
+				v := next[u][j]
+				p.highlight[[2]string{g.keys[u], g.keys[v]}] = relayColor
+				r.Path = append(r.Path, g.names[v])
+				u = v
+			}
+			p.Recommendations = append(p.Recommendations, r)
+		}
+	}
+	return p
+}
+
+// marginFromRequest returns the value of the margin query parameter
+// or the given default, if the parameter is not set.
+func marginFromRequest(r *http.Request, def time.Duration) (time.Duration, error) {
+	v := r.URL.Query().Get("margin")
+	if v == "" {
+		return def, nil
+	}
+	margin, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse margin: %w", err)
+	}
+	if margin < 0 {
+		return 0, fmt.Errorf("margin must not be negative, got %v", margin)
+	}
+	return margin, nil
+}
+
+func pathsHandler(srv string, timeout time.Duration, defaultMargin time.Duration) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		margin, err := marginFromRequest(r, defaultMargin)
+		if err != nil {
+			errorCounter.Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		m, q, ok := collectFromRequest(w, r, srv, timeout)
+		if !ok {
+			return
+		}
+		p := m.Paths(margin)
+		if r.URL.Query().Get("format") != "svg" {
+			writeJSON(w, p)
+			return
+		}
+		o, err := svgOptionsFromRequest(r)
+		if err != nil {
+			errorCounter.Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		o.square = q.target == srv
+		o.highlight = p.highlight
+		writeSVG(w, m, o)
+	}
+}
+
+// ratioFromRequest returns the value of the ratio query parameter
+// or the given default, if the parameter is not set.
+func ratioFromRequest(r *http.Request, def float64) (float64, error) {
+	v := r.URL.Query().Get("ratio")
+	if v == "" {
+		return def, nil
+	}
+	ratio, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse ratio: %w", err)
+	}
+	if ratio < 1 {
+		return 0, fmt.Errorf("ratio must be at least 1, got %v", ratio)
+	}
+	return ratio, nil
+}
+
+// writeJSON writes the given value as JSON to the response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("failed to marshal data: %v\n", err)
+		errorCounter.Inc()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	w.Write(data)
+}
+
+func asymmetryHandler(srv string, timeout time.Duration, defaultRatio float64) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ratio, err := ratioFromRequest(r, defaultRatio)
+		if err != nil {
+			errorCounter.Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		m, _, ok := collectFromRequest(w, r, srv, timeout)
+		if !ok {
+			return
+		}
+		as := m.Asymmetries(ratio)
+		if as == nil {
+			as = []Asymmetry{}
+		}
+		writeJSON(w, as)
+	}
+}
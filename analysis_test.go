@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+// squareMatrix returns a matrix of the nodes n0, n1, ...
+// with the given latencies in milliseconds.
+// Negative latencies are failed probes.
+func squareMatrix(ms [][]int) matrix {
+	m := make(matrix, len(ms))
+	for i := range ms {
+		m[i] = Vector{
+			Source: fmt.Sprintf("http://n%d:3000/vector", i),
+			IP:     naIP,
+			Host:   fmt.Sprintf("n%d", i),
+			Ok:     true,
+		}
+		for j, d := range ms[i] {
+			m[i].Latencies = append(m[i].Latencies, Latency{
+				Destination: fmt.Sprintf("http://n%d:3000", j),
+				IP:          naIP,
+				Host:        fmt.Sprintf("n%d", j),
+				Duration:    time.Duration(d) * time.Millisecond,
+				Ok:          d >= 0,
+			})
+		}
+	}
+	return m
+}
+
+func TestAsymmetries(t *testing.T) {
+	m := squareMatrix([][]int{
+		{0, 1, 10, -1},
+		{1, 0, 3, -1},
+		{2, 9, 0, 4},
+		{1, -1, 4, 0},
+	})
+	for i, tc := range []struct {
+		ratio float64
+		as    []Asymmetry
+	}{
+		{
+			ratio: 2,
+			as: []Asymmetry{
+				{A: "n0", B: "n3", Forward: -time.Millisecond, ForwardOk: false, Reverse: time.Millisecond, ReverseOk: true},
+				{A: "n0", B: "n2", Forward: 10 * time.Millisecond, ForwardOk: true, Reverse: 2 * time.Millisecond, ReverseOk: true, Ratio: 5},
+				{A: "n1", B: "n2", Forward: 3 * time.Millisecond, ForwardOk: true, Reverse: 9 * time.Millisecond, ReverseOk: true, Ratio: 3},
+			},
+		},
+		{
+			ratio: 4,
+			as: []Asymmetry{
+				{A: "n0", B: "n3", Forward: -time.Millisecond, ForwardOk: false, Reverse: time.Millisecond, ReverseOk: true},
+				{A: "n0", B: "n2", Forward: 10 * time.Millisecond, ForwardOk: true, Reverse: 2 * time.Millisecond, ReverseOk: true, Ratio: 5},
+			},
+		},
+	} {
+		if diff := pretty.Compare(m.Asymmetries(tc.ratio), tc.as); diff != "" {
+			t.Errorf("%d: unexpected asymmetries:\n%s", i, diff)
+		}
+	}
+}
+
+func TestGraphWithPadding(t *testing.T) {
+	m := squareMatrix([][]int{
+		{0, 1},
+		{2, 0},
+	})
+	m[1].Latencies = append(m[1].Latencies, Latency{Destination: dummy})
+	m[0].Latencies = append(m[0].Latencies, Latency{Destination: "http://other:3000", Ok: true})
+	g := m.graph()
+	if len(g.names) != 2 {
+		t.Fatalf("expected 2 nodes, got %d", len(g.names))
+	}
+	for i := range g.lat {
+		for j := range g.lat[i] {
+			if g.lat[i][j] == nil {
+				t.Errorf("latency from %d to %d is missing", i, j)
+			}
+		}
+	}
+}
+
+func TestReachability(t *testing.T) {
+	for i, tc := range []struct {
+		name string
+		m    matrix
+		r    Reachability
+		s    string
+	}{
+		{
+			name: "healthy",
+			m: squareMatrix([][]int{
+				{0, 1, 1},
+				{1, 0, 1},
+				{1, 1, 0},
+			}),
+			r: Reachability{
+				Partitions:   [][]string{{"n0", "n1", "n2"}},
+				Isolated:     []string{},
+				OutboundOnly: []string{},
+				InboundOnly:  []string{},
+				Unresponsive: []string{},
+			},
+			s: "reachability: 1 partition(s) of 3 node(s)",
+		},
+		{
+			name: "partitioned",
+			m: squareMatrix([][]int{
+				{0, 1, -1, -1, 1},
+				{1, 0, -1, -1, -1},
+				{-1, -1, 0, 1, -1},
+				{-1, -1, 1, 0, -1},
+				{-1, -1, -1, -1, 0},
+			}),
+			r: Reachability{
+				Partitions:   [][]string{{"n0", "n1"}, {"n2", "n3"}, {"n4"}},
+				Isolated:     []string{},
+				OutboundOnly: []string{},
+				InboundOnly:  []string{"n4"},
+				Unresponsive: []string{},
+			},
+			s: "reachability: 3 partition(s) of 2, 2, 1 node(s); inbound only: n4",
+		},
+		{
+			name: "isolated and outbound only",
+			m: func() matrix {
+				m := squareMatrix([][]int{
+					{0, 1, -1, -1},
+					{1, 0, -1, -1},
+					{1, 1, 0, -1},
+					{-1, -1, -1, 0},
+				})
+				m[3].Ok = false
+				return m
+			}(),
+			r: Reachability{
+				Partitions:   [][]string{{"n0", "n1"}, {"n2"}, {"n3"}},
+				Isolated:     []string{"n3"},
+				OutboundOnly: []string{"n2"},
+				InboundOnly:  []string{},
+				Unresponsive: []string{"n3"},
+			},
+			s: "reachability: 3 partition(s) of 2, 1, 1 node(s); isolated: n3; outbound only: n2; unresponsive: n3",
+		},
+		{
+			name: "unresponsive but reachable",
+			m: func() matrix {
+				m := squareMatrix([][]int{
+					{0, 1, 1},
+					{1, 0, 1},
+					{-1, -1, 0},
+				})
+				m[2].Ok = false
+				return m
+			}(),
+			r: Reachability{
+				Partitions:   [][]string{{"n0", "n1", "n2"}},
+				Isolated:     []string{},
+				OutboundOnly: []string{},
+				InboundOnly:  []string{},
+				Unresponsive: []string{"n2"},
+			},
+			s: "reachability: 1 partition(s) of 3 node(s); unresponsive: n2",
+		},
+		{
+			name: "single node",
+			m:    squareMatrix([][]int{{0}}),
+			r: Reachability{
+				Partitions:   [][]string{{"n0"}},
+				Isolated:     []string{},
+				OutboundOnly: []string{},
+				InboundOnly:  []string{},
+				Unresponsive: []string{},
+			},
+			s: "reachability: 1 partition(s) of 1 node(s)",
+		},
+	} {
+		r := tc.m.Reachability()
+		if diff := pretty.Compare(r, tc.r); diff != "" {
+			t.Errorf("%d (%s): unexpected reachability:\n%s", i, tc.name, diff)
+		}
+		if s := r.String(); s != tc.s {
+			t.Errorf("%d (%s): got summary %q, expected %q", i, tc.name, s, tc.s)
+		}
+	}
+}
+
+func TestPaths(t *testing.T) {
+	m := squareMatrix([][]int{
+		{0, 1, 10},
+		{1, 0, 1},
+		{-1, 1, 0},
+	})
+	for i, tc := range []struct {
+		name   string
+		margin time.Duration
+		p      Paths
+	}{
+		{
+			name:   "violations",
+			margin: time.Millisecond,
+			p: Paths{
+				Violations: []Violation{
+					{Source: "n0", Destination: "n2", Direct: 10 * time.Millisecond, DirectOk: true, Relay: "n1", Via: 2 * time.Millisecond, Relays: 1},
+					{Source: "n2", Destination: "n0", Relay: "n1", Via: 2 * time.Millisecond, Relays: 1},
+				},
+				Recommendations: []Route{
+					{Source: "n0", Destination: "n2", Direct: 10 * time.Millisecond, DirectOk: true, Shortest: 2 * time.Millisecond, Path: []string{"n0", "n1", "n2"}},
+					{Source: "n2", Destination: "n0", Shortest: 2 * time.Millisecond, Path: []string{"n2", "n1", "n0"}},
+				},
+				highlight: map[[2]string]string{
+					{"n0:3000", "n2:3000"}: violationColor,
+					{"n2:3000", "n0:3000"}: violationColor,
+					{"n0:3000", "n1:3000"}: relayColor,
+					{"n1:3000", "n2:3000"}: relayColor,
+					{"n2:3000", "n1:3000"}: relayColor,
+					{"n1:3000", "n0:3000"}: relayColor,
+				},
+			},
+		},
+		{
+			name:   "large margin",
+			margin: 10 * time.Millisecond,
+			p: Paths{
+				Violations: []Violation{
+					{Source: "n2", Destination: "n0", Relay: "n1", Via: 2 * time.Millisecond, Relays: 1},
+				},
+				Recommendations: []Route{
+					{Source: "n2", Destination: "n0", Shortest: 2 * time.Millisecond, Path: []string{"n2", "n1", "n0"}},
+				},
+				highlight: map[[2]string]string{
+					{"n2:3000", "n0:3000"}: violationColor,
+					{"n2:3000", "n1:3000"}: relayColor,
+					{"n1:3000", "n0:3000"}: relayColor,
+				},
+			},
+		},
+	} {
+		if diff := pretty.Compare(m.Paths(tc.margin), tc.p); diff != "" {
+			t.Errorf("%d (%s): unexpected paths:\n%s", i, tc.name, diff)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// anomalyColor is the color of anomalous edges in the SVG output.
+const anomalyColor = "orange"
+
+// minRelDev is the minimum standard deviation of a baseline relative to its mean,
+// so that pairs with very stable latencies are not flagged for tiny deviations.
+const minRelDev = 0.05
+
+// pairBaseline is the exponentially weighted moving average and variance
+// of the latency of a pair of nodes in nanoseconds.
+type pairBaseline struct {
+	Mean     float64
+	Variance float64
+	Samples  int
+}
+
+func (p *pairBaseline) update(x, alpha float64) {
+	p.Samples++
+	if p.Samples == 1 {
+		p.Mean = x
+		return
+	}
+	diff := x - p.Mean
+	incr := alpha * diff
+	p.Mean += incr
+	p.Variance = (1 - alpha) * (p.Variance + diff*incr)
+}
+
+func (p pairBaseline) stdDev() float64 {
+	return math.Max(math.Sqrt(p.Variance), minRelDev*p.Mean)
+}
+
+// score returns the deviation of x from the mean in standard deviations.
+func (p pairBaseline) score(x float64) float64 {
+	sd := p.stdDev()
+	if sd == 0 {
+		return 0
+	}
+	return (x - p.Mean) / sd
+}
+
+// An Anomaly is a latency that deviates from the baseline of its pair of nodes.
+type Anomaly struct {
+	Source      string        `json:"source"`
+	Destination string        `json:"destination"`
+	Latency     time.Duration `json:"latency"`
+	Mean        time.Duration `json:"mean"`
+	StdDev      time.Duration `json:"stdDev"`
+	// Score is the deviation of the latency from the mean in standard deviations.
+	Score float64 `json:"score"`
+}
+
+// baseline learns the usual latency of every pair of nodes from the snapshots
+// and flags latencies that deviate from it.
+// It is safe to use concurrently.
+type baseline struct {
+	// alpha is the weight of a new sample in the moving average.
+	alpha float64
+	// threshold is the number of standard deviations
+	// above which a latency is anomalous.
+	threshold float64
+	// warmup is the number of samples of a pair
+	// before its latencies can be anomalous.
+	warmup int
+	gauge  *prometheus.GaugeVec
+
+	mu        sync.Mutex
+	pairs     map[[2]string]*pairBaseline
+	anomalies []Anomaly
+}
+
+func newBaseline(alpha, threshold float64, warmup int, gauge *prometheus.GaugeVec) *baseline {
+	return &baseline{
+		alpha:     alpha,
+		threshold: threshold,
+		warmup:    warmup,
+		gauge:     gauge,
+		pairs:     make(map[[2]string]*pairBaseline),
+		anomalies: []Anomaly{},
+	}
+}
+
+// check returns the anomaly of the latency, if it is anomalous.
+// It must be called with the lock held.
+func (b *baseline) check(src Vector, l Latency) (Anomaly, bool) {
+	if l.Destination == dummy || !l.Ok || l.Stats != nil {
+		return Anomaly{}, false
+	}
+	p, ok := b.pairs[[2]string{src.id(), l.id()}]
+	if !ok || p.Samples < b.warmup {
+		return Anomaly{}, false
+	}
+	s := p.score(float64(l.Duration))
+	if math.Abs(s) <= b.threshold {
+		return Anomaly{}, false
+	}
+	return Anomaly{
+		Source:      src.name(),
+		Destination: l.name(),
+		Latency:     l.Duration,
+		Mean:        time.Duration(p.Mean),
+		StdDev:      time.Duration(p.stdDev()),
+		Score:       s,
+	}, true
+}
+
+// Update flags the anomalies in the snapshot and then adds its latencies to the baseline.
+func (b *baseline) Update(s Snapshot) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.anomalies = []Anomaly{}
+	if b.gauge != nil {
+		b.gauge.Reset()
+	}
+	for _, v := range s.Matrix {
+		for _, l := range v.Latencies {
+			if l.Destination == dummy || !l.Ok {
+				continue
+			}
+			a, anomalous := b.check(v, l)
+			if anomalous {
+				b.anomalies = append(b.anomalies, a)
+			}
+			if b.gauge != nil {
+				var g float64
+				if anomalous {
+					g = 1
+				}
+				b.gauge.With(prometheus.Labels{"source": v.name(), "destination": l.name()}).Set(g)
+			}
+			k := [2]string{v.id(), l.id()}
+			if b.pairs[k] == nil {
+				b.pairs[k] = &pairBaseline{}
+			}
+			b.pairs[k].update(float64(l.Duration), b.alpha)
+		}
+	}
+	sort.SliceStable(b.anomalies, func(i, j int) bool {
+		return math.Abs(b.anomalies[i].Score) > math.Abs(b.anomalies[j].Score)
+	})
+}
+
+// Mark sets the anomaly score of all latencies of the matrix
+// that deviate from the baseline without updating it.
+func (b *baseline) Mark(m matrix) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range m {
+		for j, l := range m[i].Latencies {
+			if a, ok := b.check(m[i], l); ok {
+				m[i].Latencies[j].Anomaly = a.Score
+			}
+		}
+	}
+}
+
+// Anomalies returns the anomalies of the latest snapshot,
+// ordered by descending deviation.
+func (b *baseline) Anomalies() []Anomaly {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Anomaly{}, b.anomalies...)
+}
+
+func anomaliesHandler(b *baseline) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, b.Anomalies())
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// gaugeValues returns the values of the gauge by source and destination.
+func gaugeValues(t *testing.T, gauge *prometheus.GaugeVec) map[[2]string]float64 {
+	r := prometheus.NewRegistry()
+	r.MustRegister(gauge)
+	mfs, err := r.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	vs := make(map[[2]string]float64)
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			var k [2]string
+			for _, l := range m.GetLabel() {
+				switch l.GetName() {
+				case "source":
+					k[0] = l.GetValue()
+				case "destination":
+					k[1] = l.GetValue()
+				}
+			}
+			vs[k] = m.GetGauge().GetValue()
+		}
+	}
+	return vs
+}
+
+func TestPairBaseline(t *testing.T) {
+	var p pairBaseline
+	for _, x := range []float64{10, 12, 8, 10, 12, 8, 10} {
+		p.update(x, 0.5)
+	}
+	if p.Samples != 7 {
+		t.Errorf("expected 7 samples, got %d", p.Samples)
+	}
+	if math.Abs(p.Mean-10) > 1 {
+		t.Errorf("expected mean close to 10, got %v", p.Mean)
+	}
+	if p.Variance <= 0 {
+		t.Errorf("expected positive variance, got %v", p.Variance)
+	}
+	// The standard deviation has a floor relative to the mean.
+	if sd := (pairBaseline{Mean: 100, Samples: 2}).stdDev(); sd != 5 {
+		t.Errorf("expected standard deviation of 5, got %v", sd)
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "anomaly"}, []string{"source", "destination"})
+	b := newBaseline(0.2, 3, 3, gauge)
+	now := time.Now()
+	// Pairs with different usual latencies,
+	// e.g. in the same region and in different regions.
+	for i := 0; i < 5; i++ {
+		jitter := i % 2
+		b.Update(Snapshot{Time: now, Matrix: squareMatrix([][]int{
+			{0, 2 + jitter},
+			{80 + jitter, 0},
+		})})
+		if len(b.Anomalies()) != 0 {
+			t.Fatalf("round %d: expected no anomalies while learning, got %v", i, b.Anomalies())
+		}
+	}
+	m := squareMatrix([][]int{
+		{0, 10},
+		{81, 0},
+	})
+	b.Mark(m)
+	if m[0].Latencies[1].Anomaly <= 3 {
+		t.Errorf("expected 10ms to be anomalous for a pair with a baseline of 2ms, got %v", m[0].Latencies[1].Anomaly)
+	}
+	if m[1].Latencies[0].Anomaly != 0 {
+		t.Errorf("expected 81ms not to be anomalous for a pair with a baseline of 80ms, got %v", m[1].Latencies[0].Anomaly)
+	}
+	if s := m[0].Latencies[1].String(); s != "10ms !" {
+		t.Errorf("expected anomaly to be highlighted, got %q", s)
+	}
+	b.Update(Snapshot{Time: now, Matrix: m})
+	as := b.Anomalies()
+	if len(as) != 1 || as[0].Source != "n0" || as[0].Destination != "n1" || as[0].Latency != 10*time.Millisecond {
+		t.Errorf("expected a single anomaly from n0 to n1, got %v", as)
+	}
+	gv := gaugeValues(t, gauge)
+	if v, ok := gv[[2]string{"n0", "n1"}]; !ok || v != 1 {
+		t.Errorf("expected anomaly gauge to be 1, got %v", v)
+	}
+	if v, ok := gv[[2]string{"n1", "n0"}]; !ok || v != 0 {
+		t.Errorf("expected anomaly gauge to be 0, got %v", v)
+	}
+	buf := &bytes.Buffer{}
+	if err := m.SVG(buf, svgOptions{square: true}); err != nil {
+		t.Fatalf("failed to render matrix: %v", err)
+	}
+	if !strings.Contains(buf.String(), `stroke="#ffa500"`) {
+		t.Errorf("expected anomalous edge to be orange:\n%s", buf.String())
+	}
+}
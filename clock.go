@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/kilo-io/adjacency_service/pkg/prober"
+)
+
+const (
+	// statClock is the value of the stat query parameter that makes the nodes estimate the clocks of their destinations.
+	statClock = "clock"
+	// clockOffsetTTL is the time after which the offset of a destination whose clock was not estimated again
+	// is removed from the metrics, so that nodes that left the cluster do not stay in them.
+	clockOffsetTTL = 10 * time.Minute
+)
+
+// Clock is the estimate of the clock of a destination and of the one-way delays to it.
+type Clock struct {
+	// Offset is the offset of the clock of the destination from the clock of the source.
+	Offset time.Duration `json:"offset"`
+	// Bound is the uncertainty of the offset and the one-way delays, which are within ±Bound.
+	Bound time.Duration `json:"bound"`
+	// Forward is the one-way delay from the source to the destination
+	// and Backward the one-way delay from the destination to the source.
+	Forward  time.Duration `json:"forward"`
+	Backward time.Duration `json:"backward"`
+	Samples  int           `json:"samples"`
+	Ok       bool          `json:"ok"`
+}
+
+func (c *Clock) String() string {
+	if !c.Ok {
+		return "-"
+	}
+	return fmt.Sprintf("%v/%v (±%v)", c.Forward, c.Backward, c.Bound)
+}
+
+// probeClock estimates the clocks of the given URLs concurrently and returns them by destination.
+func (vr *vectorer) probeClock(ctx context.Context, urls []*url.URL) map[string]*Clock {
+	clocks := make(map[string]*Clock, len(urls))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, u := range urls {
+		wg.Add(1)
+		go func(u *url.URL) {
+			defer wg.Done()
+			ctxT, cancelT := context.WithTimeout(ctx, vr.timeout)
+			defer cancelT()
+			c := &Clock{}
+			r, err := vr.clock.Clock(ctxT, *u)
+			if err != nil {
+				log.Printf("failed to estimate clock of %s: %v\n", u.Host, err)
+				errorCounter.Inc()
+			} else {
+				c.Offset, c.Bound, c.Forward, c.Backward, c.Samples, c.Ok = r.Offset, r.Bound, r.Forward, r.Backward, r.Samples, true
+			}
+			mu.Lock()
+			clocks[u.String()] = c
+			mu.Unlock()
+		}(u)
+	}
+	wg.Wait()
+	return clocks
+}
+
+// clockOffsets exports the offsets of the clocks of the nodes of the adjacency service itself.
+// The series are labeled by the host and port of the destination
+// and removed once their clocks were not estimated for clockOffsetTTL.
+// It is safe to use concurrently.
+type clockOffsets struct {
+	gauge *prometheus.GaugeVec
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func newClockOffsets(gauge *prometheus.GaugeVec) *clockOffsets {
+	return &clockOffsets{gauge: gauge, seen: make(map[string]time.Time)}
+}
+
+// Record sets the offsets of the clocks by destination that were estimated at the given time,
+// removes the offsets of the destinations whose clocks failed and expires the old ones.
+func (o *clockOffsets) Record(clocks map[string]*Clock, now time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for dst, c := range clocks {
+		d := hostPort(dst)
+		if !c.Ok {
+			o.gauge.DeleteLabelValues(d)
+			delete(o.seen, d)
+			continue
+		}
+		o.gauge.WithLabelValues(d).Set(c.Offset.Seconds())
+		o.seen[d] = now
+	}
+	for d, t := range o.seen {
+		if now.Sub(t) > clockOffsetTTL {
+			o.gauge.DeleteLabelValues(d)
+			delete(o.seen, d)
+		}
+	}
+}
+
+// timeHandler answers with the times at which it received and answered the request,
+// so that other nodes can estimate the clock of this node.
+func timeHandler(w http.ResponseWriter, r *http.Request) {
+	receive := time.Now().UnixNano()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(prober.TimeResponse{Receive: receive, Transmit: time.Now().UnixNano()}); err != nil {
+		log.Printf("failed to write time: %v\n", err)
+		errorCounter.Inc()
+	}
+}
+
+// A ClockSkew is the offset of the clock of a node from the clocks of the other nodes.
+type ClockSkew struct {
+	Node string `json:"node"`
+	// Offset and Bound are the medians of the offsets of the clock of the node
+	// that the node and its peers estimated and of their uncertainties.
+	Offset time.Duration `json:"offset"`
+	Bound  time.Duration `json:"bound"`
+	// Peers is the number of nodes that estimated the clock of the node or whose clock the node estimated.
+	Peers int `json:"peers"`
+	// Skewed is true if the offset is beyond the threshold even within its bound.
+	Skewed bool `json:"skewed"`
+}
+
+// ClockReport is the result of the analysis of the clocks of the nodes of a square matrix.
+type ClockReport struct {
+	Threshold time.Duration `json:"threshold"`
+	// Nodes are ordered by descending absolute offset.
+	Nodes []ClockSkew `json:"nodes"`
+	// Skewed lists the names of the nodes whose clocks are skewed.
+	Skewed []string `json:"skewed"`
+}
+
+// ClockSkews estimates the offset of the clock of every node from the clocks of its peers.
+// The estimates of both directions of every pair count,
+// so the offset of a node is relative to the majority of the nodes,
+// which is assumed to be synchronized.
+func (m matrix) ClockSkews(threshold time.Duration) ClockReport {
+	g := m.graph()
+	r := ClockReport{Threshold: threshold, Nodes: []ClockSkew{}, Skewed: []string{}}
+	for i := range g.names {
+		var offsets, bounds []time.Duration
+		peers := 0
+		for j := range g.names {
+			if i == j {
+				continue
+			}
+			peer := false
+			// The peer estimated the offset of the clock of this node.
+			if l := g.lat[j][i]; l != nil && l.Clock != nil && l.Clock.Ok {
+				offsets, bounds, peer = append(offsets, l.Clock.Offset), append(bounds, l.Clock.Bound), true
+			}
+			// This node estimated the offset of the clock of the peer.
+			if l := g.lat[i][j]; l != nil && l.Clock != nil && l.Clock.Ok {
+				offsets, bounds, peer = append(offsets, -l.Clock.Offset), append(bounds, l.Clock.Bound), true
+			}
+			if peer {
+				peers++
+			}
+		}
+		if peers == 0 {
+			continue
+		}
+		sort.Slice(offsets, func(a, b int) bool { return offsets[a] < offsets[b] })
+		sort.Slice(bounds, func(a, b int) bool { return bounds[a] < bounds[b] })
+		s := ClockSkew{Node: g.names[i], Offset: median(offsets), Bound: median(bounds), Peers: peers}
+		s.Skewed = absDuration(s.Offset)-s.Bound > threshold
+		r.Nodes = append(r.Nodes, s)
+	}
+	sort.SliceStable(r.Nodes, func(a, b int) bool {
+		return absDuration(r.Nodes[a].Offset) > absDuration(r.Nodes[b].Offset)
+	})
+	for _, s := range r.Nodes {
+		if s.Skewed {
+			r.Skewed = append(r.Skewed, s.Node)
+		}
+	}
+	return r
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func clocksHandler(srv string, timeout time.Duration, defaultThreshold time.Duration) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		threshold, err := thresholdFromRequest(r, defaultThreshold)
+		if err != nil {
+			errorCounter.Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		m, _, ok := collectStatFromRequest(w, r, srv, timeout, statClock)
+		if !ok {
+			return
+		}
+		writeJSON(w, m.ClockSkews(threshold))
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/kilo-io/adjacency_service/pkg/prober"
+)
+
+func TestClockString(t *testing.T) {
+	for _, tc := range []struct {
+		c        Clock
+		expected string
+	}{
+		{c: Clock{Forward: 3 * time.Millisecond, Backward: time.Millisecond, Bound: 200 * time.Microsecond, Ok: true}, expected: "3ms/1ms (±200µs)"},
+		{c: Clock{}, expected: "-"},
+	} {
+		if s := (Latency{Clock: &tc.c, Ok: true, Duration: time.Millisecond}).String(); s != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, s)
+		}
+	}
+	q, err := url.ParseQuery("stat=clock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o, err := vectorOptionsFromValues(q); err != nil || o != (vectorOptions{clock: true}) {
+		t.Errorf("expected the clock option, got %+v, %v", o, err)
+	}
+}
+
+func TestClockSkews(t *testing.T) {
+	// The clocks of the nodes n0 to n4 are ahead by these milliseconds;
+	// the clock of n4 is skewed and n5 does not estimate clocks.
+	clocks := []int{0, 2, -1, 1, 300}
+	m := squareMatrix([][]int{
+		{0, 1, 1, 1, 1, 1},
+		{1, 0, 1, 1, 1, 1},
+		{1, 1, 0, 1, 1, 1},
+		{1, 1, 1, 0, 1, 1},
+		{1, 1, 1, 1, 0, 1},
+		{1, 1, 1, 1, 1, 0},
+	})
+	for i := range clocks {
+		for j := range clocks {
+			if i != j {
+				m[i].Latencies[j].Clock = &Clock{Offset: time.Duration(clocks[j]-clocks[i]) * time.Millisecond, Bound: time.Millisecond, Ok: true}
+			}
+		}
+	}
+	skew := func(node string, offset time.Duration, skewed bool) ClockSkew {
+		return ClockSkew{Node: node, Offset: offset, Bound: time.Millisecond, Peers: 4, Skewed: skewed}
+	}
+	for _, tc := range []struct {
+		threshold time.Duration
+		expected  ClockReport
+	}{
+		{
+			threshold: 50 * time.Millisecond,
+			expected: ClockReport{
+				Threshold: 50 * time.Millisecond,
+				Nodes: []ClockSkew{
+					skew("n4", 299500*time.Microsecond, true),
+					skew("n2", -2500*time.Microsecond, false),
+					skew("n0", -1500*time.Microsecond, false),
+					skew("n1", 1500*time.Microsecond, false),
+					skew("n3", 0, false),
+				},
+				Skewed: []string{"n4"},
+			},
+		},
+		{
+			// The offset of n4 is within its bound of the threshold.
+			threshold: 299 * time.Millisecond,
+			expected: ClockReport{
+				Threshold: 299 * time.Millisecond,
+				Nodes: []ClockSkew{
+					skew("n4", 299500*time.Microsecond, false),
+					skew("n2", -2500*time.Microsecond, false),
+					skew("n0", -1500*time.Microsecond, false),
+					skew("n1", 1500*time.Microsecond, false),
+					skew("n3", 0, false),
+				},
+				Skewed: []string{},
+			},
+		},
+	} {
+		if diff := pretty.Compare(m.ClockSkews(tc.threshold), tc.expected); diff != "" {
+			t.Errorf("threshold %v: got diff:\n%s", tc.threshold, diff)
+		}
+	}
+}
+
+func TestProbeClock(t *testing.T) {
+	m := http.NewServeMux()
+	m.HandleFunc(prober.TimePath, timeHandler)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	// This server does not run the adjacency service.
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+
+	w := httptest.NewRecorder()
+	timeHandler(w, httptest.NewRequest(http.MethodGet, prober.TimePath, nil))
+	var tr prober.TimeResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &tr); err != nil || tr.Receive == 0 || tr.Transmit < tr.Receive {
+		t.Errorf("unexpected response %q: %v", w.Body, err)
+	}
+
+	cp, err := prober.NewClockProber(3, prober.NewSourceClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	vr := &vectorer{clock: cp, timeout: 5 * time.Second}
+	var urls []*url.URL
+	for _, s := range []*httptest.Server{s, plain} {
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls = append(urls, u)
+	}
+	clocks := vr.probeClock(context.Background(), urls)
+	// Both servers share the clock of the test.
+	if c := clocks[s.URL]; c == nil || !c.Ok || c.Samples != 3 || absDuration(c.Offset) > c.Bound+time.Millisecond {
+		t.Errorf("unexpected clock %+v", c)
+	}
+	if diff := pretty.Compare(clocks[plain.URL], &Clock{}); diff != "" {
+		t.Errorf("got diff:\n%s", diff)
+	}
+}
+
+func TestClockOffsets(t *testing.T) {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "clock_offset_seconds"}, []string{"destination"})
+	o := newClockOffsets(gauge)
+	start := time.Now()
+	o.Record(map[string]*Clock{
+		"http://n0:3000/vector?format=json": {Offset: time.Second, Ok: true},
+		"http://n1:3000/vector":             {Offset: -time.Second, Ok: true},
+		"http://n2:3000/vector":             {Offset: 2 * time.Second, Ok: true},
+	}, start)
+	// The clock of n1 failed and n2 was not estimated again for too long.
+	o.Record(map[string]*Clock{
+		"http://n0:3000/vector": {Offset: 3 * time.Second, Ok: true},
+		"http://n1:3000/vector": {},
+	}, start.Add(clockOffsetTTL+time.Second))
+	expected := map[[2]string]float64{{"", "n0:3000"}: 3}
+	if diff := pretty.Compare(gaugeValues(t, gauge), expected); diff != "" {
+		t.Errorf("got diff:\n%s", diff)
+	}
+}
@@ -0,0 +1,491 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// The kinds of changes of a cell of the matrix.
+const (
+	reachabilityChange = "reachability"
+	latencyChange      = "latency"
+)
+
+// colors of changes in the SVG output.
+const (
+	worseColor  = "red"
+	betterColor = "green"
+)
+
+// A CellChange is a pair of nodes whose latency or reachability changed.
+type CellChange struct {
+	Source      string        `json:"source"`
+	Destination string        `json:"destination"`
+	From        time.Duration `json:"from"`
+	FromOk      bool          `json:"fromOk"`
+	To          time.Duration `json:"to"`
+	ToOk        bool          `json:"toOk"`
+	// Delta is the difference of the latencies.
+	// It is 0 if the reachability changed.
+	Delta time.Duration `json:"delta"`
+	Kind  string        `json:"kind"`
+
+	key [2]string
+}
+
+// worse returns true if the change is a regression.
+func (c CellChange) worse() bool {
+	if c.Kind == reachabilityChange {
+		return !c.ToOk
+	}
+	return c.Delta > 0
+}
+
+// A MatrixDiff describes the changes between two matrices.
+type MatrixDiff struct {
+	AddedSources        []string     `json:"addedSources"`
+	RemovedSources      []string     `json:"removedSources"`
+	AddedDestinations   []string     `json:"addedDestinations"`
+	RemovedDestinations []string     `json:"removedDestinations"`
+	Changes             []CellChange `json:"changes"`
+
+	// merged contains the cells of the new matrix and the cells of removed
+	// nodes from the old matrix, so that the diff can be drawn.
+	merged matrix
+	// added and removed are the identities of added and removed nodes, see Vector.id.
+	added, removed map[string]bool
+}
+
+// align returns both matrices with the same rows and columns,
+// i.e. the union of the sources and destinations of both.
+// Like Pad, it orders rows and columns and fills missing cells with dummies.
+// Rows and columns are matched by node name or, for nodes that do not report it, by host and port,
+// so that neither changed addresses of nodes nor different query parameters of the sources matter.
+func align(a, b matrix) (matrix, matrix) {
+	rows := make(map[string]Vector)
+	cols := make(map[string]Latency)
+	for _, m := range []matrix{b, a} {
+		for _, v := range m {
+			rows[v.id()] = v
+			for _, l := range v.Latencies {
+				if l.Destination != dummy {
+					cols[l.id()] = l
+				}
+			}
+		}
+	}
+	rowKeys := make([]string, 0, len(rows))
+	for k := range rows {
+		rowKeys = append(rowKeys, k)
+	}
+	sort.Strings(rowKeys)
+	colKeys := make([]string, 0, len(cols))
+	for k := range cols {
+		colKeys = append(colKeys, k)
+	}
+	sort.Strings(colKeys)
+	alignOne := func(m matrix) matrix {
+		idx := make(map[string]int, len(m))
+		for i, v := range m {
+			idx[v.id()] = i
+		}
+		am := make(matrix, len(rowKeys))
+		for i, k := range rowKeys {
+			var lats map[string]Latency
+			if j, ok := idx[k]; ok {
+				am[i] = m[j]
+				lats = make(map[string]Latency, len(m[j].Latencies))
+				for _, l := range m[j].Latencies {
+					if l.Destination != dummy {
+						lats[l.id()] = l
+					}
+				}
+			} else {
+				// The source is missing in this matrix.
+				r := rows[k]
+				am[i] = Vector{Source: r.Source, IP: r.IP, Host: r.Host, Labels: r.Labels, Node: r.Node}
+			}
+			am[i].Latencies = make([]Latency, len(colKeys))
+			for j, c := range colKeys {
+				if l, ok := lats[c]; ok {
+					am[i].Latencies[j] = l
+				} else {
+					am[i].Latencies[j] = Latency{Destination: dummy}
+				}
+			}
+		}
+		return am
+	}
+	return alignOne(a), alignOne(b)
+}
+
+// keys returns the hosts and ports of the sources and the destinations of the matrix.
+func (m matrix) keys() (map[string]bool, map[string]bool) {
+	srcs, dsts := make(map[string]bool, len(m)), make(map[string]bool)
+	for _, v := range m {
+		srcs[v.id()] = true
+		for _, l := range v.Latencies {
+			if l.Destination != dummy {
+				dsts[l.id()] = true
+			}
+		}
+	}
+	return srcs, dsts
+}
+
+// columns returns a latency of every column of the aligned matrices,
+// which identifies the destination of the column.
+func columns(a, b matrix) []Latency {
+	if len(a) == 0 {
+		return nil
+	}
+	cols := make([]Latency, len(a[0].Latencies))
+	for _, m := range []matrix{a, b} {
+		for _, v := range m {
+			for j, l := range v.Latencies {
+				if l.Destination != dummy {
+					cols[j] = l
+				}
+			}
+		}
+	}
+	return cols
+}
+
+// Diff returns the changes from the matrix m to the matrix to.
+// Latency changes are only reported if they exceed the threshold.
+// Cells of nodes that were added or removed are not compared.
+func (m matrix) Diff(to matrix, threshold time.Duration) MatrixDiff {
+	a, b := align(m, to)
+	d := MatrixDiff{
+		AddedSources:        []string{},
+		RemovedSources:      []string{},
+		AddedDestinations:   []string{},
+		RemovedDestinations: []string{},
+		Changes:             []CellChange{},
+		merged:              make(matrix, len(b)),
+		added:               make(map[string]bool),
+		removed:             make(map[string]bool),
+	}
+	srcA, dstA := m.keys()
+	srcB, dstB := to.keys()
+	for i := range b {
+		d.merged[i] = b[i]
+		d.merged[i].Latencies = append([]Latency{}, b[i].Latencies...)
+		k := b[i].id()
+		switch {
+		case srcA[k] && !srcB[k]:
+			d.RemovedSources = append(d.RemovedSources, a[i].name())
+			d.removed[k] = true
+			d.merged[i] = a[i]
+		case !srcA[k] && srcB[k]:
+			d.AddedSources = append(d.AddedSources, b[i].name())
+			d.added[k] = true
+		}
+	}
+	for _, j := range columns(a, b) {
+		k := j.id()
+		switch {
+		case dstA[k] && !dstB[k]:
+			d.RemovedDestinations = append(d.RemovedDestinations, j.name())
+			d.removed[k] = true
+		case !dstA[k] && dstB[k]:
+			d.AddedDestinations = append(d.AddedDestinations, j.name())
+			d.added[k] = true
+		}
+	}
+	for i := range b {
+		for j := range b[i].Latencies {
+			la, lb := a[i].Latencies[j], b[i].Latencies[j]
+			if la.Destination == dummy || lb.Destination == dummy {
+				if lb.Destination == dummy && la.Destination != dummy {
+					d.merged[i].Latencies[j] = la
+				}
+				continue
+			}
+			c := CellChange{
+				Source:      b[i].name(),
+				Destination: lb.name(),
+				From:        la.Duration,
+				FromOk:      la.Ok,
+				To:          lb.Duration,
+				ToOk:        lb.Ok,
+				key:         [2]string{b[i].id(), lb.id()},
+			}
+			switch {
+			case la.Ok != lb.Ok:
+				c.Kind = reachabilityChange
+			case la.Ok:
+				c.Delta = lb.Duration - la.Duration
+				if c.Delta <= threshold && -c.Delta <= threshold {
+					continue
+				}
+				c.Kind = latencyChange
+			default:
+				continue
+			}
+			d.Changes = append(d.Changes, c)
+		}
+	}
+	abs := func(d time.Duration) time.Duration {
+		if d < 0 {
+			return -d
+		}
+		return d
+	}
+	sort.SliceStable(d.Changes, func(i, j int) bool {
+		if (d.Changes[i].Kind == reachabilityChange) != (d.Changes[j].Kind == reachabilityChange) {
+			return d.Changes[i].Kind == reachabilityChange
+		}
+		return abs(d.Changes[i].Delta) > abs(d.Changes[j].Delta)
+	})
+	return d
+}
+
+func (d MatrixDiff) String() string {
+	s := &strings.Builder{}
+	for _, l := range []struct {
+		name  string
+		nodes []string
+	}{
+		{"added sources", d.AddedSources},
+		{"removed sources", d.RemovedSources},
+		{"added destinations", d.AddedDestinations},
+		{"removed destinations", d.RemovedDestinations},
+	} {
+		if len(l.nodes) > 0 {
+			fmt.Fprintf(s, "%s: %s\n", l.name, strings.Join(l.nodes, ", "))
+		}
+	}
+	if len(d.Changes) == 0 {
+		s.WriteString("no changes\n")
+		return s.String()
+	}
+	table := tablewriter.NewWriter(s)
+	table.SetHeader([]string{"Source", "Destination", "From", "To", "Delta"})
+	table.SetAutoFormatHeaders(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	dur := func(d time.Duration, ok bool) string {
+		if !ok {
+			return "-"
+		}
+		return d.String()
+	}
+	for _, c := range d.Changes {
+		delta := "unreachable"
+		switch {
+		case c.Kind == reachabilityChange && c.ToOk:
+			delta = "reachable"
+		case c.Kind == latencyChange && c.Delta > 0:
+			delta = "+" + c.Delta.String()
+		case c.Kind == latencyChange:
+			delta = c.Delta.String()
+		}
+		table.Append([]string{c.Source, c.Destination, dur(c.From, c.FromOk), dur(c.To, c.ToOk), delta})
+	}
+	table.Render()
+	return s.String()
+}
+
+// SVG renders the new matrix with changed edges in red or green,
+// depending on whether they got worse or better.
+// Added nodes are green and removed nodes are red.
+func (d MatrixDiff) SVG(w io.Writer, o svgOptions) error {
+	o.square = d.merged.square()
+	o.highlight = make(map[[2]string]string, len(d.Changes))
+	for _, c := range d.Changes {
+		o.highlight[c.key] = betterColor
+		if c.worse() {
+			o.highlight[c.key] = worseColor
+		}
+	}
+	o.highlightNodes = make(map[string]string, len(d.added)+len(d.removed))
+	for k := range d.added {
+		o.highlightNodes[k] = betterColor
+	}
+	for k := range d.removed {
+		o.highlightNodes[k] = worseColor
+	}
+	return d.merged.SVG(w, o)
+}
+
+// square returns true if the sources and the destinations of the padded matrix
+// are the same nodes in the same order.
+func (m matrix) square() bool {
+	if len(m) == 0 || len(m) != len(m[0].Latencies) {
+		return false
+	}
+	for j, dst := range m.columns() {
+		if dst.id() != m[j].id() {
+			return false
+		}
+	}
+	return true
+}
+
+// parseMatrix parses a matrix in the JSON format of the matrix or of a snapshot.
+func parseMatrix(data []byte) (matrix, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var m matrix
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return s.Matrix, nil
+}
+
+// thresholdFromRequest returns the value of the threshold query parameter
+// or the given default, if the parameter is not set.
+func thresholdFromRequest(r *http.Request, def time.Duration) (time.Duration, error) {
+	v := r.URL.Query().Get("threshold")
+	if v == "" {
+		return def, nil
+	}
+	threshold, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse threshold: %w", err)
+	}
+	if threshold < 0 {
+		return 0, fmt.Errorf("threshold must not be negative, got %v", threshold)
+	}
+	return threshold, nil
+}
+
+// matricesFromRequest returns the matrices that should be compared.
+// They are either uploaded as JSON in the body of a POST request
+// or referenced as stored snapshots by the from and to query parameters.
+func matricesFromRequest(r *http.Request, s *snapshotStore) (matrix, matrix, int, error) {
+	if r.Method == http.MethodPost {
+		var body struct {
+			From json.RawMessage `json:"from"`
+			To   json.RawMessage `json:"to"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, nil, http.StatusBadRequest, fmt.Errorf("failed to decode body: %w", err)
+		}
+		from, err := parseMatrix(body.From)
+		if err != nil {
+			return nil, nil, http.StatusBadRequest, fmt.Errorf("failed to parse from: %w", err)
+		}
+		to, err := parseMatrix(body.To)
+		if err != nil {
+			return nil, nil, http.StatusBadRequest, fmt.Errorf("failed to parse to: %w", err)
+		}
+		return from, to, 0, nil
+	}
+	fromID, toID := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if fromID == "" {
+		fromID = "previous"
+	}
+	if toID == "" {
+		toID = "latest"
+	}
+	from, err := s.Get(fromID)
+	if err != nil {
+		return nil, nil, http.StatusNotFound, err
+	}
+	to, err := s.Get(toID)
+	if err != nil {
+		return nil, nil, http.StatusNotFound, err
+	}
+	return from.Matrix, to.Matrix, 0, nil
+}
+
+func diffHandler(s *snapshotStore, defaultThreshold time.Duration) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		threshold, err := thresholdFromRequest(r, defaultThreshold)
+		if err != nil {
+			errorCounter.Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		from, to, code, err := matricesFromRequest(r, s)
+		if err != nil {
+			errorCounter.Inc()
+			http.Error(w, err.Error(), code)
+			return
+		}
+		d := from.Diff(to, threshold)
+		switch f := r.URL.Query().Get("format"); f {
+		case "json":
+			writeJSON(w, d)
+		case "svg":
+			o, err := svgOptionsFromRequest(r)
+			if err != nil {
+				errorCounter.Inc()
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			buf := &bytes.Buffer{}
+			if err := d.SVG(buf, o); err != nil {
+				errorCounter.Inc()
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Add("content-type", "image/svg+xml")
+			w.Write(buf.Bytes())
+		default:
+			w.Write([]byte(d.String()))
+		}
+	}
+}
+
+// diffCommand implements the diff subcommand,
+// which compares two matrices stored as JSON files.
+func diffCommand(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	threshold := fs.Duration("threshold", 5*time.Millisecond, "The difference of latencies above which a pair of nodes is reported")
+	f := fs.String("format", "table", "The output format; one of table, json and svg")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s diff [flags] from.json to.json\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("expected exactly two files")
+	}
+	var ms [2]matrix
+	for i, name := range fs.Args() {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("failed to read matrix: %w", err)
+		}
+		if ms[i], err = parseMatrix(data); err != nil {
+			return fmt.Errorf("failed to parse matrix in %s: %w", name, err)
+		}
+	}
+	d := ms[0].Diff(ms[1], *threshold)
+	switch *f {
+	case "table":
+		_, err := io.WriteString(stdout, d.String())
+		return err
+	case "json":
+		return json.NewEncoder(stdout).Encode(d)
+	case "svg":
+		return d.SVG(stdout, svgOptions{})
+	default:
+		return fmt.Errorf("format must be one of table, json and svg, got %q", *f)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestDiff(t *testing.T) {
+	for i, tc := range []struct {
+		name      string
+		from, to  matrix
+		threshold time.Duration
+		d         MatrixDiff
+	}{
+		{
+			name: "no changes",
+			from: squareMatrix([][]int{{0, 1}, {1, 0}}),
+			to:   squareMatrix([][]int{{0, 2}, {1, 0}}),
+			d: MatrixDiff{
+				AddedSources:        []string{},
+				RemovedSources:      []string{},
+				AddedDestinations:   []string{},
+				RemovedDestinations: []string{},
+				Changes:             []CellChange{},
+			},
+			threshold: 5 * time.Millisecond,
+		},
+		{
+			name: "latency and reachability",
+			from: squareMatrix([][]int{
+				{0, 1, 10},
+				{1, 0, 3},
+				{2, -1, 0},
+			}),
+			to: squareMatrix([][]int{
+				{0, 20, 1},
+				{-1, 0, 3},
+				{2, 4, 0},
+			}),
+			threshold: 5 * time.Millisecond,
+			d: MatrixDiff{
+				AddedSources:        []string{},
+				RemovedSources:      []string{},
+				AddedDestinations:   []string{},
+				RemovedDestinations: []string{},
+				Changes: []CellChange{
+					{Source: "n1", Destination: "n0", From: time.Millisecond, FromOk: true, To: -time.Millisecond, Kind: reachabilityChange},
+					{Source: "n2", Destination: "n1", From: -time.Millisecond, To: 4 * time.Millisecond, ToOk: true, Kind: reachabilityChange},
+					{Source: "n0", Destination: "n1", From: time.Millisecond, FromOk: true, To: 20 * time.Millisecond, ToOk: true, Delta: 19 * time.Millisecond, Kind: latencyChange},
+					{Source: "n0", Destination: "n2", From: 10 * time.Millisecond, FromOk: true, To: time.Millisecond, ToOk: true, Delta: -9 * time.Millisecond, Kind: latencyChange},
+				},
+			},
+		},
+		{
+			name: "removed node",
+			from: squareMatrix([][]int{
+				{0, 1, 10},
+				{1, 0, 3},
+				{2, 4, 0},
+			}),
+			to: squareMatrix([][]int{
+				{0, 1},
+				{1, 0},
+			}),
+			d: MatrixDiff{
+				AddedSources:        []string{},
+				RemovedSources:      []string{"n2"},
+				AddedDestinations:   []string{},
+				RemovedDestinations: []string{"n2"},
+				Changes:             []CellChange{},
+			},
+		},
+		{
+			name: "added node",
+			from: squareMatrix([][]int{
+				{0, 1},
+				{1, 0},
+			}),
+			to: squareMatrix([][]int{
+				{0, 1, 10},
+				{1, 0, 3},
+				{2, 4, 0},
+			}),
+			d: MatrixDiff{
+				AddedSources:        []string{"n2"},
+				RemovedSources:      []string{},
+				AddedDestinations:   []string{"n2"},
+				RemovedDestinations: []string{},
+				Changes:             []CellChange{},
+			},
+		},
+	} {
+		d := tc.from.Diff(tc.to, tc.threshold)
+		// Only compare the exported fields.
+		ed := d
+		ed.merged, ed.added, ed.removed = nil, nil, nil
+		ed.Changes = append([]CellChange{}, d.Changes...)
+		for j := range ed.Changes {
+			ed.Changes[j].key = [2]string{}
+		}
+		if diff := pretty.Compare(ed, tc.d); diff != "" {
+			t.Errorf("%d (%s): unexpected diff:\n%s", i, tc.name, diff)
+		}
+		if !d.merged.square() {
+			t.Errorf("%d (%s): expected merged matrix to be square", i, tc.name)
+		}
+		if err := d.SVG(&bytes.Buffer{}, svgOptions{}); err != nil {
+			t.Errorf("%d (%s): failed to render diff: %v", i, tc.name, err)
+		}
+	}
+}
+
+func TestDiffSVG(t *testing.T) {
+	from := squareMatrix([][]int{{0, 1}, {1, 0}})
+	to := squareMatrix([][]int{{0, 20}, {-1, 0}})
+	buf := &bytes.Buffer{}
+	if err := from.Diff(to, 0).SVG(buf, svgOptions{}); err != nil {
+		t.Fatalf("failed to render diff: %v", err)
+	}
+	if !strings.Contains(buf.String(), `stroke="#ff0000"`) {
+		t.Errorf("expected regressions to be red:\n%s", buf.String())
+	}
+}
+
+func TestDiffCommand(t *testing.T) {
+	dir := t.TempDir()
+	from := squareMatrix([][]int{{0, 1}, {1, 0}})
+	to := Snapshot{ID: 2, Matrix: squareMatrix([][]int{{0, 20}, {1, 0}})}
+	var files []string
+	for i, v := range []interface{}{from, to} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Join(dir, []string{"from.json", "to.json"}[i])
+		if err := ioutil.WriteFile(name, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, name)
+	}
+	buf := &bytes.Buffer{}
+	if err := diffCommand(append([]string{"-format", "json"}, files...), buf); err != nil {
+		t.Fatalf("failed to run diff: %v", err)
+	}
+	var d MatrixDiff
+	if err := json.Unmarshal(buf.Bytes(), &d); err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	if len(d.Changes) != 1 || d.Changes[0].Delta != 19*time.Millisecond {
+		t.Errorf("expected a single latency change, got %v", d.Changes)
+	}
+	if err := diffCommand(files[:1], &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for a single file")
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// addressTTL is the time for which the advertised addresses of a node are cached.
+const addressTTL = time.Minute
+
+// PathLatency is the latency to a destination via one of its addresses.
+type PathLatency struct {
+	IP       string        `json:"ip"`
+	Duration time.Duration `json:"duration"`
+	Ok       bool          `json:"ok"`
+	Prober   string        `json:"prober"`
+}
+
+func (p *PathLatency) String() string {
+	if p == nil || !p.Ok {
+		return "-"
+	}
+	return p.Duration.String()
+}
+
+// overhead returns the latency of the overlay minus the latency of the underlay,
+// if both were probed successfully.
+func (l Latency) overhead() (time.Duration, bool) {
+	if l.Overlay == nil || l.Underlay == nil || !l.Overlay.Ok || !l.Underlay.Ok {
+		return 0, false
+	}
+	return l.Overlay.Duration - l.Underlay.Duration, true
+}
+
+// dualString returns the latencies via the overlay and the underlay and the overhead,
+// e.g. 3ms/2ms (+1ms).
+func (l Latency) dualString() string {
+	s := l.Overlay.String() + "/" + l.Underlay.String()
+	if o, ok := l.overhead(); ok {
+		sign := "+"
+		if o < 0 {
+			sign, o = "-", -o
+		}
+		s += fmt.Sprintf(" (%s%v)", sign, o)
+	}
+	return s
+}
+
+// addressFromFlag returns the given IP address or the first address of the given interface,
+// preferring IPv4.
+func addressFromFlag(s string) (string, error) {
+	if s == "" || net.ParseIP(s) != nil {
+		return s, nil
+	}
+	iface, err := net.InterfaceByName(s)
+	if err != nil {
+		return "", fmt.Errorf("%q is neither an IP address nor an interface: %w", s, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", fmt.Errorf("failed to list addresses of interface %s: %w", s, err)
+	}
+	var first string
+	for _, a := range addrs {
+		n, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if n.IP.To4() != nil {
+			return n.IP.String(), nil
+		}
+		if first == "" {
+			first = n.IP.String()
+		}
+	}
+	if first == "" {
+		return "", fmt.Errorf("interface %s has no IP address", s)
+	}
+	return first, nil
+}
+
+// dualFromValues returns true if the dual matrix is requested.
+func dualFromValues(q url.Values) (bool, error) {
+	v := q.Get("dual")
+	if v == "" {
+		return false, nil
+	}
+	d, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse dual: %w", err)
+	}
+	return d, nil
+}
+
+// nodeAddresses are the overlay and underlay addresses that a node advertises in /info.
+type nodeAddresses struct {
+	overlay, underlay string
+	expires           time.Time
+}
+
+// addressBook fetches and caches the advertised addresses of nodes.
+// It is safe to use concurrently.
+type addressBook struct {
+	mu    sync.Mutex
+	nodes map[string]nodeAddresses
+}
+
+func newAddressBook() *addressBook {
+	return &addressBook{nodes: make(map[string]nodeAddresses)}
+}
+
+// fetchInfo gets the description of the adjacency service at the given URL.
+func fetchInfo(ctx context.Context, u url.URL) (*NodeInfo, error) {
+	u.Path = "/info"
+	u.RawQuery = ""
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make GET request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("expected status code 200, got %d", resp.StatusCode)
+	}
+	var i NodeInfo
+	if err := json.Unmarshal(body, &i); err != nil {
+		return nil, fmt.Errorf("response from node has wrong format: maybe it is not running this service?: %w", err)
+	}
+	return &i, nil
+}
+
+// Addresses returns the overlay and underlay addresses of the node at the given URL.
+// Nodes whose info cannot be fetched have no addresses until addressTTL passed.
+func (b *addressBook) Addresses(ctx context.Context, u url.URL, now time.Time) (string, string) {
+	b.mu.Lock()
+	a, ok := b.nodes[u.Host]
+	b.mu.Unlock()
+	if ok && now.Before(a.expires) {
+		return a.overlay, a.underlay
+	}
+	a = nodeAddresses{expires: now.Add(addressTTL)}
+	if i, err := fetchInfo(ctx, u); err != nil {
+		log.Printf("failed to get info from %s: %v\n", u.Host, err)
+	} else {
+		a.overlay, a.underlay = i.Overlay, i.Underlay
+	}
+	b.mu.Lock()
+	b.nodes[u.Host] = a
+	b.mu.Unlock()
+	return a.overlay, a.underlay
+}
+
+// dualPaths are the latencies to the overlay and underlay addresses of a destination.
+// They are nil if the destination does not advertise the address.
+type dualPaths struct {
+	overlay, underlay *PathLatency
+}
+
+// probeDual probes the overlay and underlay addresses of the given URLs on their ports
+// and returns the latencies by destination.
+// All addresses are probed concurrently and within dualTimeout, if it is set,
+// so that the probes fit into the time of the vector alongside the latency probes.
+func (vr *vectorer) probeDual(ctx context.Context, urls []*url.URL) map[string]dualPaths {
+	if vr.dualTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, vr.dualTimeout)
+		defer cancel()
+	}
+	probe := func(u url.URL, ip string) *PathLatency {
+		if ip == "" {
+			return nil
+		}
+		u.Host = net.JoinHostPort(ip, u.Port())
+		l := timeHTTPRequest(ctx, vr.probers, &u, vr.timeout)
+		return &PathLatency{IP: ip, Duration: l.Duration, Ok: l.Ok, Prober: l.Prober}
+	}
+	paths := make(map[string]dualPaths, len(urls))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, u := range urls {
+		wg.Add(1)
+		go func(u url.URL) {
+			defer wg.Done()
+			overlay, underlay := vr.ab.Addresses(ctx, u, time.Now())
+			var p dualPaths
+			var pwg sync.WaitGroup
+			pwg.Add(2)
+			go func() {
+				defer pwg.Done()
+				p.overlay = probe(u, overlay)
+			}()
+			go func() {
+				defer pwg.Done()
+				p.underlay = probe(u, underlay)
+			}()
+			pwg.Wait()
+			mu.Lock()
+			paths[u.String()] = p
+			mu.Unlock()
+		}(*u)
+	}
+	wg.Wait()
+	return paths
+}
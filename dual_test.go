@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+
+	"github.com/kilo-io/adjacency_service/pkg/prober"
+)
+
+func TestDualString(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		l        Latency
+		expected string
+		overhead time.Duration
+		ok       bool
+	}{
+		{
+			name: "overhead",
+			l: Latency{
+				Overlay:  &PathLatency{Duration: 3 * time.Millisecond, Ok: true},
+				Underlay: &PathLatency{Duration: 2 * time.Millisecond, Ok: true},
+			},
+			expected: "3ms/2ms (+1ms)",
+			overhead: time.Millisecond,
+			ok:       true,
+		},
+		{
+			name: "negative overhead",
+			l: Latency{
+				Overlay:  &PathLatency{Duration: 2 * time.Millisecond, Ok: true},
+				Underlay: &PathLatency{Duration: 3 * time.Millisecond, Ok: true},
+			},
+			expected: "2ms/3ms (-1ms)",
+			overhead: -time.Millisecond,
+			ok:       true,
+		},
+		{
+			name: "failed underlay",
+			l: Latency{
+				Overlay:  &PathLatency{Duration: 3 * time.Millisecond, Ok: true},
+				Underlay: &PathLatency{},
+			},
+			expected: "3ms/-",
+		},
+		{
+			name: "no overlay",
+			l: Latency{
+				Underlay: &PathLatency{Duration: 2 * time.Millisecond, Ok: true},
+			},
+			expected: "-/2ms",
+		},
+	} {
+		if s := tc.l.dualString(); s != tc.expected {
+			t.Errorf("test case %q: expected %q, got %q", tc.name, tc.expected, s)
+		}
+		o, ok := tc.l.overhead()
+		if o != tc.overhead || ok != tc.ok {
+			t.Errorf("test case %q: expected overhead %v, %t, got %v, %t", tc.name, tc.overhead, tc.ok, o, ok)
+		}
+		if s := tc.l.String(); s != tc.expected {
+			t.Errorf("test case %q: expected cell %q, got %q", tc.name, tc.expected, s)
+		}
+	}
+}
+
+func TestDualFromValues(t *testing.T) {
+	for _, tc := range []struct {
+		q        string
+		expected bool
+		err      bool
+	}{
+		{q: ""},
+		{q: "dual=true", expected: true},
+		{q: "dual=1", expected: true},
+		{q: "dual=false"},
+		{q: "dual=maybe", err: true},
+	} {
+		q, err := url.ParseQuery(tc.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := dualFromValues(q)
+		if (err != nil) != tc.err {
+			t.Errorf("query %q: expected error %t, got %v", tc.q, tc.err, err)
+		}
+		if d != tc.expected {
+			t.Errorf("query %q: expected %t, got %t", tc.q, tc.expected, d)
+		}
+	}
+}
+
+func TestAddressFromFlag(t *testing.T) {
+	for _, tc := range []struct {
+		s        string
+		expected string
+		err      bool
+	}{
+		{s: ""},
+		{s: "10.4.0.1", expected: "10.4.0.1"},
+		{s: "fd00::1", expected: "fd00::1"},
+		{s: "lo", expected: "127.0.0.1"},
+		{s: "does-not-exist0", err: true},
+	} {
+		a, err := addressFromFlag(tc.s)
+		if (err != nil) != tc.err {
+			t.Errorf("flag %q: expected error %t, got %v", tc.s, tc.err, err)
+		}
+		if a != tc.expected {
+			t.Errorf("flag %q: expected %q, got %q", tc.s, tc.expected, a)
+		}
+	}
+}
+
+func TestAddressBook(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path != "/info" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(NodeInfo{Overlay: "10.4.0.1", Underlay: "192.0.2.1"})
+	}))
+	defer s.Close()
+	broken := httptest.NewServer(http.NotFoundHandler())
+	defer broken.Close()
+
+	b := newAddressBook()
+	now := time.Now()
+	u, _ := url.Parse(s.URL + "/?srv=_service._tcp.example.com")
+	for _, at := range []time.Time{now, now.Add(addressTTL / 2)} {
+		overlay, underlay := b.Addresses(context.Background(), *u, at)
+		if diff := pretty.Compare([]string{overlay, underlay}, []string{"10.4.0.1", "192.0.2.1"}); diff != "" {
+			t.Errorf("got diff:\n%s", diff)
+		}
+	}
+	if c := atomic.LoadInt32(&calls); c != 1 {
+		t.Errorf("expected the addresses to be cached, got %d requests", c)
+	}
+	b.Addresses(context.Background(), *u, now.Add(2*addressTTL))
+	if c := atomic.LoadInt32(&calls); c != 2 {
+		t.Errorf("expected the addresses to expire, got %d requests", c)
+	}
+
+	bu, _ := url.Parse(broken.URL)
+	if overlay, underlay := b.Addresses(context.Background(), *bu, now); overlay != "" || underlay != "" {
+		t.Errorf("expected no addresses for a node without info, got %q and %q", overlay, underlay)
+	}
+}
+
+// blackholeProber probes the loopback address with its prober
+// and waits for the context of all other addresses, as if their packets were dropped.
+type blackholeProber struct {
+	prober.Prober
+}
+
+func (p blackholeProber) Probe(ctx context.Context, u url.URL) (time.Duration, error) {
+	if u.Hostname() != "127.0.0.1" {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	return p.Prober.Probe(ctx, u)
+}
+
+func TestProbeDual(t *testing.T) {
+	// The node advertises the loopback address as both overlay and underlay,
+	// so that both paths reach the same server.
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(NodeInfo{Overlay: "127.0.0.1", Underlay: "127.0.0.1"})
+	}))
+	defer s.Close()
+	// This node advertises nothing.
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+	// The underlay address of this node is unreachable.
+	unreachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(NodeInfo{Overlay: "127.0.0.1", Underlay: "192.0.2.1"})
+	}))
+	defer unreachable.Close()
+
+	vr := &vectorer{
+		probers:     []prober.Prober{blackholeProber{prober.NewTCPProber()}},
+		timeout:     5 * time.Second,
+		ab:          newAddressBook(),
+		dualTimeout: 500 * time.Millisecond,
+	}
+	var urls []*url.URL
+	for _, s := range []*httptest.Server{s, plain, unreachable} {
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls = append(urls, u)
+	}
+	start := time.Now()
+	paths := vr.probeDual(context.Background(), urls)
+	// The unreachable address must not delay the vector beyond the time of the dual probes.
+	if d := time.Since(start); d > 2*vr.dualTimeout {
+		t.Errorf("expected the probes to end after %v, took %v", vr.dualTimeout, d)
+	}
+
+	p := paths[s.URL]
+	for _, pl := range []*PathLatency{p.overlay, p.underlay} {
+		if pl == nil || !pl.Ok || pl.IP != "127.0.0.1" || pl.Prober != "tcp-prober" {
+			t.Errorf("expected a successful probe of 127.0.0.1, got %+v", pl)
+		}
+	}
+	if p := paths[plain.URL]; p.overlay != nil || p.underlay != nil {
+		t.Errorf("expected a node without addresses to be left alone, got %+v", p)
+	}
+	if p := paths[unreachable.URL]; p.overlay == nil || !p.overlay.Ok || p.underlay == nil || p.underlay.Ok {
+		t.Errorf("expected only the probe of the underlay address to fail, got %+v and %+v", p.overlay, p.underlay)
+	}
+}
@@ -0,0 +1,587 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/kilo-io/adjacency_service/pkg/api"
+	"github.com/kilo-io/adjacency_service/pkg/prober"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func statsToProto(s *Stats) *api.Stats {
+	if s == nil {
+		return nil
+	}
+	return &api.Stats{
+		Median:       int64(s.Median),
+		P95:          int64(s.P95),
+		Max:          int64(s.Max),
+		FailureRatio: s.FailureRatio,
+		Samples:      int32(s.Samples),
+	}
+}
+
+func statsFromProto(s *api.Stats) *Stats {
+	if s == nil {
+		return nil
+	}
+	return &Stats{
+		Median:       time.Duration(s.Median),
+		P95:          time.Duration(s.P95),
+		Max:          time.Duration(s.Max),
+		FailureRatio: s.FailureRatio,
+		Samples:      int(s.Samples),
+	}
+}
+
+func detailsToProto(d *prober.Details) *api.Details {
+	if d == nil {
+		return nil
+	}
+	pd := &api.Details{}
+	if t := d.TLS; t != nil {
+		pd.Tls = &api.TLSDetails{
+			Connect:     int64(t.Connect),
+			Handshake:   int64(t.Handshake),
+			Version:     t.Version,
+			CipherSuite: t.CipherSuite,
+			Alpn:        t.ALPN,
+			NameMatch:   t.NameMatch,
+			Verified:    t.Verified,
+		}
+		if !t.NotAfter.IsZero() {
+			pd.Tls.NotAfter = timestamppb.New(t.NotAfter)
+		}
+	}
+	if dd := d.DNS; dd != nil {
+		pd.Dns = &api.DNSDetails{
+			Transport: dd.Transport,
+			Name:      dd.Name,
+			Type:      dd.Type,
+			Rcode:     dd.Rcode,
+			Answers:   int32(dd.Answers),
+			Truncated: dd.Truncated,
+		}
+	}
+	if g := d.GRPC; g != nil {
+		pd.Grpc = &api.GRPCDetails{
+			Connect: int64(g.Connect),
+			Check:   int64(g.Check),
+			Service: g.Service,
+			Status:  g.Status,
+		}
+	}
+	return pd
+}
+
+func detailsFromProto(pd *api.Details) *prober.Details {
+	if pd == nil {
+		return nil
+	}
+	d := &prober.Details{}
+	if t := pd.Tls; t != nil {
+		d.TLS = &prober.TLSDetails{
+			Connect:     time.Duration(t.Connect),
+			Handshake:   time.Duration(t.Handshake),
+			Version:     t.Version,
+			CipherSuite: t.CipherSuite,
+			ALPN:        t.Alpn,
+			NameMatch:   t.NameMatch,
+			Verified:    t.Verified,
+		}
+		if t.NotAfter != nil {
+			d.TLS.NotAfter = t.NotAfter.AsTime()
+		}
+	}
+	if dd := pd.Dns; dd != nil {
+		d.DNS = &prober.DNSDetails{
+			Transport: dd.Transport,
+			Name:      dd.Name,
+			Type:      dd.Type,
+			Rcode:     dd.Rcode,
+			Answers:   int(dd.Answers),
+			Truncated: dd.Truncated,
+		}
+	}
+	if g := pd.Grpc; g != nil {
+		d.GRPC = &prober.GRPCDetails{
+			Connect: time.Duration(g.Connect),
+			Check:   time.Duration(g.Check),
+			Service: g.Service,
+			Status:  g.Status,
+		}
+	}
+	return d
+}
+
+func wireGuardToProto(p *WireGuardPeer) *api.WireGuardPeer {
+	if p == nil {
+		return nil
+	}
+	pp := &api.WireGuardPeer{
+		PublicKey:    p.PublicKey,
+		Endpoint:     p.Endpoint,
+		HandshakeAge: int64(p.HandshakeAge),
+		RxBytes:      p.RxBytes,
+		TxBytes:      p.TxBytes,
+		Stale:        p.Stale,
+	}
+	if !p.LastHandshake.IsZero() {
+		pp.LastHandshake = timestamppb.New(p.LastHandshake)
+	}
+	return pp
+}
+
+func wireGuardFromProto(pp *api.WireGuardPeer) *WireGuardPeer {
+	if pp == nil {
+		return nil
+	}
+	p := &WireGuardPeer{
+		PublicKey:    pp.PublicKey,
+		Endpoint:     pp.Endpoint,
+		HandshakeAge: time.Duration(pp.HandshakeAge),
+		RxBytes:      pp.RxBytes,
+		TxBytes:      pp.TxBytes,
+		Stale:        pp.Stale,
+	}
+	if pp.LastHandshake != nil {
+		p.LastHandshake = pp.LastHandshake.AsTime()
+	}
+	return p
+}
+
+func pathToProto(p *PathLatency) *api.PathLatency {
+	if p == nil {
+		return nil
+	}
+	return &api.PathLatency{Ip: p.IP, Duration: int64(p.Duration), Ok: p.Ok, Prober: p.Prober}
+}
+
+func pathFromProto(p *api.PathLatency) *PathLatency {
+	if p == nil {
+		return nil
+	}
+	return &PathLatency{IP: p.Ip, Duration: time.Duration(p.Duration), Ok: p.Ok, Prober: p.Prober}
+}
+
+func mtuToProto(m *MTU) *api.MTU {
+	if m == nil {
+		return nil
+	}
+	return &api.MTU{Size: int32(m.Size), Method: m.Method, Ok: m.Ok, Expected: int32(m.Expected), Below: m.Below}
+}
+
+func mtuFromProto(m *api.MTU) *MTU {
+	if m == nil {
+		return nil
+	}
+	return &MTU{Size: int(m.Size), Method: m.Method, Ok: m.Ok, Expected: int(m.Expected), Below: m.Below}
+}
+
+func throughputToProto(t *Throughput) *api.Throughput {
+	if t == nil {
+		return nil
+	}
+	return &api.Throughput{Mbps: t.Mbps, Bytes: t.Bytes, Streams: int32(t.Streams), Direction: t.Direction, Duration: int64(t.Duration), Ok: t.Ok}
+}
+
+func throughputFromProto(t *api.Throughput) *Throughput {
+	if t == nil {
+		return nil
+	}
+	return &Throughput{Mbps: t.Mbps, Bytes: t.Bytes, Streams: int(t.Streams), Direction: t.Direction, Duration: time.Duration(t.Duration), Ok: t.Ok}
+}
+
+func clockToProto(c *Clock) *api.Clock {
+	if c == nil {
+		return nil
+	}
+	return &api.Clock{Offset: int64(c.Offset), Bound: int64(c.Bound), Forward: int64(c.Forward), Backward: int64(c.Backward), Samples: int32(c.Samples), Ok: c.Ok}
+}
+
+func clockFromProto(c *api.Clock) *Clock {
+	if c == nil {
+		return nil
+	}
+	return &Clock{Offset: time.Duration(c.Offset), Bound: time.Duration(c.Bound), Forward: time.Duration(c.Forward), Backward: time.Duration(c.Backward), Samples: int(c.Samples), Ok: c.Ok}
+}
+
+func latencyToProto(l Latency) *api.Latency {
+	return &api.Latency{
+		Destination: l.Destination,
+		Ip:          l.IP,
+		Host:        l.Host,
+		Duration:    int64(l.Duration),
+		Ok:          l.Ok,
+		Prober:      l.Prober,
+		Labels:      l.Labels,
+		Stats:       statsToProto(l.Stats),
+		Age:         int64(l.Age),
+		Anomaly:     l.Anomaly,
+		Node:        l.Node,
+		Details:     detailsToProto(l.Details),
+		Wireguard:   wireGuardToProto(l.WireGuard),
+		Overlay:     pathToProto(l.Overlay),
+		Underlay:    pathToProto(l.Underlay),
+		Overhead:    int64(l.Overhead),
+		SourceIp:    l.SourceIP,
+		Mtu:         mtuToProto(l.MTU),
+		Throughput:  throughputToProto(l.Throughput),
+		Clock:       clockToProto(l.Clock),
+	}
+}
+
+func latencyFromProto(l *api.Latency) Latency {
+	return Latency{
+		Destination: l.Destination,
+		IP:          l.Ip,
+		Host:        l.Host,
+		Duration:    time.Duration(l.Duration),
+		Ok:          l.Ok,
+		Prober:      l.Prober,
+		Labels:      l.Labels,
+		Stats:       statsFromProto(l.Stats),
+		Age:         time.Duration(l.Age),
+		Anomaly:     l.Anomaly,
+		Node:        l.Node,
+		Details:     detailsFromProto(l.Details),
+		WireGuard:   wireGuardFromProto(l.Wireguard),
+		Overlay:     pathFromProto(l.Overlay),
+		Underlay:    pathFromProto(l.Underlay),
+		Overhead:    time.Duration(l.Overhead),
+		SourceIP:    l.SourceIp,
+		MTU:         mtuFromProto(l.Mtu),
+		Throughput:  throughputFromProto(l.Throughput),
+		Clock:       clockFromProto(l.Clock),
+	}
+}
+
+func vectorToProto(v Vector) *api.Vector {
+	pv := &api.Vector{
+		Source: v.Source,
+		Ip:     v.IP,
+		Host:   v.Host,
+		Labels: v.Labels,
+		Ok:     v.Ok,
+
+		Node:         v.Node,
+		Version:      v.Version,
+		ApiVersion:   int32(v.APIVersion),
+		Capabilities: v.Capabilities,
+	}
+	if v.Coordinate != nil {
+		pv.Coordinate = &api.Coordinate{
+			Vec:    v.Coordinate.Vec[:],
+			Height: v.Coordinate.Height,
+			Error:  v.Coordinate.Error,
+		}
+	}
+	for _, l := range v.Latencies {
+		pv.Latencies = append(pv.Latencies, latencyToProto(l))
+	}
+	return pv
+}
+
+func vectorFromProto(pv *api.Vector) Vector {
+	v := Vector{
+		Source: pv.Source,
+		IP:     pv.Ip,
+		Host:   pv.Host,
+		Labels: pv.Labels,
+		Ok:     pv.Ok,
+
+		Node:         pv.Node,
+		Version:      pv.Version,
+		APIVersion:   int(pv.ApiVersion),
+		Capabilities: pv.Capabilities,
+	}
+	if pc := pv.Coordinate; pc != nil {
+		c := Coordinate{Height: pc.Height, Error: pc.Error}
+		copy(c.Vec[:], pc.Vec)
+		v.Coordinate = &c
+	}
+	for _, l := range pv.Latencies {
+		v.Latencies = append(v.Latencies, latencyFromProto(l))
+	}
+	return v
+}
+
+func matrixToProto(m matrix) *api.Matrix {
+	pm := &api.Matrix{}
+	for _, v := range m {
+		pm.Vectors = append(pm.Vectors, vectorToProto(v))
+	}
+	return pm
+}
+
+func matrixFromProto(pm *api.Matrix) matrix {
+	var m matrix
+	for _, v := range pm.Vectors {
+		m = append(m, vectorFromProto(v))
+	}
+	return m
+}
+
+// grpcServer implements the gRPC API with the same logic as the HTTP endpoints.
+type grpcServer struct {
+	api.UnimplementedAdjacencyServer
+	vr      *vectorer
+	timeout time.Duration
+	// tpTimeout is added to the timeout of matrices of the throughput stat.
+	tpTimeout time.Duration
+	ss        *snapshotStore
+}
+
+func (g *grpcServer) GetVector(ctx context.Context, req *api.GetVectorRequest) (*api.Vector, error) {
+	q := url.Values{}
+	if req.Srv != "" {
+		q.Set("srv", req.Srv)
+	}
+	q.Set("destinations", req.Destinations)
+	srv, dsts, err := vectorQueryFromValues(q, g.vr.srv)
+	if err != nil {
+		errorCounter.Inc()
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	source, err := newSource(req.SourceAddress, req.SourceInterface)
+	if err != nil {
+		errorCounter.Inc()
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	stat, err := parseStat(req.Stat)
+	if err != nil {
+		errorCounter.Inc()
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	o := vectorOptions{dual: req.Dual, mtu: stat == statMTU, clock: stat == statClock, throughput: stat == statThroughput}
+	if o.throughput {
+		o.tp = prober.ThroughputOptions{Bytes: req.ThroughputBytes, Streams: int(req.ThroughputStreams), Direction: req.ThroughputDirection}
+		if err := checkThroughputOptions(o.tp); err != nil {
+			errorCounter.Inc()
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	v, err := g.vr.Vector(prober.WithSource(ctx, source), srv, dsts, o)
+	if err != nil {
+		errorCounter.Inc()
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	// The gRPC API always carries the identity of the node, like the envelope of /vector.
+	v.Node = g.vr.node
+	v.Version = version
+	v.APIVersion = apiVersion
+	v.Capabilities = g.vr.capabilities
+	return vectorToProto(*v), nil
+}
+
+func (g *grpcServer) GetMatrix(ctx context.Context, req *api.GetMatrixRequest) (*api.Matrix, error) {
+	q := url.Values{}
+	if req.Srv != "" {
+		q.Set("srv", req.Srv)
+	}
+	q.Set("sources", req.Sources)
+	q.Set("destinations", req.Destinations)
+	if req.Dual {
+		q.Set("dual", "true")
+	}
+	q.Set("sourceAddress", req.SourceAddress)
+	q.Set("sourceInterface", req.SourceInterface)
+	q.Set("stat", req.Stat)
+	setThroughputValues(q, prober.ThroughputOptions{Bytes: req.ThroughputBytes, Streams: int(req.ThroughputStreams), Direction: req.ThroughputDirection})
+	mq, err := matrixQueryFromValues(q, g.vr.srv)
+	if err != nil {
+		errorCounter.Inc()
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	timeout := g.timeout
+	if mq.stat == statThroughput {
+		timeout += g.tpTimeout
+	}
+	m, err := collectMatrix(ctx, g.vr.srv, mq, timeout)
+	if err != nil {
+		errorCounter.Inc()
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return matrixToProto(m), nil
+}
+
+func (g *grpcServer) WatchMatrix(req *api.WatchMatrixRequest, stream api.Adjacency_WatchMatrixServer) error {
+	// Only the latest snapshot is buffered,
+	// so slow clients skip snapshots instead of blocking the others.
+	ch := make(chan Snapshot, 1)
+	unsubscribe := g.ss.Subscribe(func(s Snapshot) {
+		select {
+		case ch <- s:
+		default:
+		}
+	})
+	defer unsubscribe()
+	last := 0
+	send := func(s Snapshot) error {
+		if s.ID <= last {
+			return nil
+		}
+		last = s.ID
+		return stream.Send(&api.Snapshot{
+			Id:     int64(s.ID),
+			Time:   timestamppb.New(s.Time),
+			Matrix: matrixToProto(s.Matrix),
+		})
+	}
+	if s, err := g.ss.Get("latest"); err == nil {
+		if err := send(s); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case s := <-ch:
+			if err := send(s); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func grpcUnaryMetrics(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	requestCounter.With(prometheus.Labels{"method": "grpc", "handler": info.FullMethod}).Inc()
+	return handler(ctx, req)
+}
+
+func grpcStreamMetrics(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	requestCounter.With(prometheus.Labels{"method": "grpc", "handler": info.FullMethod}).Inc()
+	return handler(srv, ss)
+}
+
+const (
+	// fanoutDialTimeout is the time to wait for a connection to the gRPC API of a node.
+	fanoutDialTimeout = time.Second
+	// fanoutRetry is the time after which gRPC is tried again
+	// for a node that did not serve it.
+	fanoutRetry = 5 * time.Minute
+)
+
+// errNoGRPC is returned if a node does not serve the gRPC API.
+var errNoGRPC = errors.New("node does not serve the gRPC API")
+
+// grpcFanout requests the vectors of other nodes via their gRPC API.
+// It assumes that all nodes serve the gRPC API on the same port.
+// It is safe to use concurrently.
+type grpcFanout struct {
+	port string
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+	// fallback maps nodes that did not serve the gRPC API
+	// to the time until which they are not tried again.
+	fallback map[string]time.Time
+}
+
+// fanout is used by getVectorFrom if it is set.
+var fanout *grpcFanout
+
+func newGRPCFanout(port string) *grpcFanout {
+	return &grpcFanout{
+		port:     port,
+		conns:    make(map[string]*grpc.ClientConn),
+		fallback: make(map[string]time.Time),
+	}
+}
+
+func (f *grpcFanout) conn(ctx context.Context, host string) (*grpc.ClientConn, error) {
+	f.mu.Lock()
+	if t, ok := f.fallback[host]; ok && time.Now().Before(t) {
+		f.mu.Unlock()
+		return nil, errNoGRPC
+	}
+	if c, ok := f.conns[host]; ok {
+		f.mu.Unlock()
+		return c, nil
+	}
+	f.mu.Unlock()
+	// Do not hold the lock while dialing, so that unreachable nodes do not delay the others.
+	ctxT, cancel := context.WithTimeout(ctx, fanoutDialTimeout)
+	defer cancel()
+	c, err := grpc.DialContext(ctxT, net.JoinHostPort(host, f.port), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err != nil {
+		f.fallback[host] = time.Now().Add(fanoutRetry)
+		return nil, fmt.Errorf("%w: %v", errNoGRPC, err)
+	}
+	if old, ok := f.conns[host]; ok {
+		// Another request connected in the meantime.
+		c.Close()
+		return old, nil
+	}
+	delete(f.fallback, host)
+	f.conns[host] = c
+	return c, nil
+}
+
+// forget closes the connection to the node and uses JSON for it until fanoutRetry passed.
+func (f *grpcFanout) forget(host string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok := f.conns[host]; ok {
+		c.Close()
+		delete(f.conns, host)
+	}
+	f.fallback[host] = time.Now().Add(fanoutRetry)
+}
+
+// GetVector requests the vector from the node of the given URL of its /vector endpoint.
+// It returns errNoGRPC if the node does not serve the gRPC API,
+// so that the caller can fall back to JSON.
+func (f *grpcFanout) GetVector(ctx context.Context, u *url.URL) (*Vector, error) {
+	host := u.Hostname()
+	c, err := f.conn(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	// The query was built by collectMatrix, so it is valid.
+	dual, _ := dualFromValues(u.Query())
+	tp, _ := throughputOptionsFromValues(u.Query())
+	pv, err := api.NewAdjacencyClient(c).GetVector(ctx, &api.GetVectorRequest{
+		Srv:             u.Query().Get("srv"),
+		Destinations:    u.Query().Get("destinations"),
+		Dual:            dual,
+		SourceAddress:   u.Query().Get("sourceAddress"),
+		SourceInterface: u.Query().Get("sourceInterface"),
+		Stat:            u.Query().Get("stat"),
+
+		ThroughputBytes:     tp.Bytes,
+		ThroughputStreams:   int32(tp.Streams),
+		ThroughputDirection: tp.Direction,
+	})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Unimplemented:
+			f.forget(host)
+			return nil, fmt.Errorf("%w: %v", errNoGRPC, err)
+		case codes.Unavailable:
+			// The node might have been restarted without the gRPC API.
+			if ctx.Err() == nil {
+				f.forget(host)
+				return nil, fmt.Errorf("%w: %v", errNoGRPC, err)
+			}
+		}
+		return nil, err
+	}
+	v := vectorFromProto(pv)
+	return &v, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kilo-io/adjacency_service/pkg/api"
+	"github.com/kilo-io/adjacency_service/pkg/prober"
+
+	"github.com/kylelemons/godebug/pretty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestProtoConversion(t *testing.T) {
+	m := squareMatrix([][]int{
+		{0, 3},
+		{-1, 0},
+	})
+	m[0].Labels = map[string]string{"zone": "a"}
+	m[0].Coordinate = &Coordinate{Vec: [2]float64{0.001, 0.002}, Height: 0.0001, Error: 0.5}
+	m[0].Latencies[1].Stats = &Stats{Median: time.Millisecond, P95: 2 * time.Millisecond, Max: 3 * time.Millisecond, FailureRatio: 0.5, Samples: 4}
+	m[0].Latencies[1].Age = time.Second
+	m[0].Latencies[1].Anomaly = 4.2
+	m[0].Latencies[1].Node = "n1"
+	m[0].Latencies[1].Details = &prober.Details{TLS: &prober.TLSDetails{
+		Connect:     time.Millisecond,
+		Handshake:   2 * time.Millisecond,
+		Version:     "TLS 1.3",
+		CipherSuite: "TLS_AES_128_GCM_SHA256",
+		ALPN:        "h2",
+		NotAfter:    time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		NameMatch:   true,
+	}}
+	m[1].Latencies[0].Details = &prober.Details{DNS: &prober.DNSDetails{Transport: "udp", Name: "example.com.", Type: "A", Rcode: "NOERROR", Answers: 1}}
+	m[1].Latencies[0].WireGuard = &WireGuardPeer{PublicKey: "key", Endpoint: "192.0.2.1:51820", LastHandshake: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), HandshakeAge: time.Minute, RxBytes: 1, TxBytes: 2}
+	m[0].Latencies[0].Overlay = &PathLatency{IP: "10.4.0.1", Duration: 3 * time.Millisecond, Ok: true, Prober: "http-ping-prober"}
+	m[0].Latencies[0].Underlay = &PathLatency{IP: "192.0.2.1", Duration: 2 * time.Millisecond, Ok: true, Prober: "http-ping-prober"}
+	m[0].Latencies[0].Overhead = time.Millisecond
+	m[0].Latencies[0].SourceIP = "10.4.0.2"
+	m[0].Latencies[1].MTU = &MTU{Size: 1380, Method: prober.MTUICMP, Ok: true, Expected: 1420, Below: true}
+	m[0].Latencies[1].Clock = &Clock{Offset: -3 * time.Millisecond, Bound: 200 * time.Microsecond, Forward: 2 * time.Millisecond, Backward: time.Millisecond, Samples: 5, Ok: true}
+	m[1].Latencies[0].Throughput = &Throughput{Mbps: 941.5, Bytes: 10 << 20, Streams: 4, Direction: prober.ThroughputUpload, Duration: 89 * time.Millisecond, Ok: true}
+	m[1].Latencies[1].Details = &prober.Details{GRPC: &prober.GRPCDetails{Connect: time.Millisecond, Check: time.Millisecond, Service: "ready", Status: "SERVING"}}
+	m[0].Node, m[0].Version, m[0].APIVersion, m[0].Capabilities = "n0", "1.2.0", apiEnvelope, []string{capGRPC}
+	if diff := pretty.Compare(matrixFromProto(matrixToProto(m)), m); diff != "" {
+		t.Errorf("matrix changed in conversion:\n%s", diff)
+	}
+}
+
+// vectorServer serves a fixed vector via gRPC.
+type vectorServer struct {
+	api.UnimplementedAdjacencyServer
+	v Vector
+}
+
+func (s *vectorServer) GetVector(ctx context.Context, req *api.GetVectorRequest) (*api.Vector, error) {
+	return vectorToProto(s.v), nil
+}
+
+func serveGRPC(t *testing.T, s api.AdjacencyServer) (string, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	gs := grpc.NewServer()
+	api.RegisterAdjacencyServer(gs, s)
+	go gs.Serve(lis)
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+	return port, gs.Stop
+}
+
+func TestGetVectorFromFanout(t *testing.T) {
+	jsonVector := Vector{Latencies: []Latency{{Destination: "http://json:3000", Ok: true, Duration: time.Millisecond}}}
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jsonVector)
+	}))
+	defer hs.Close()
+	u, err := url.Parse(hs.URL + "/vector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		fanout = nil
+	}()
+
+	// The node does not serve gRPC, so the collector falls back to JSON.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, closed, _ := net.SplitHostPort(lis.Addr().String())
+	lis.Close()
+	fanout = newGRPCFanout(closed)
+	v, err := getVectorFrom(context.Background(), u)
+	if err != nil {
+		t.Fatalf("failed to get vector: %v", err)
+	}
+	if diff := pretty.Compare(v.Latencies, jsonVector.Latencies); diff != "" {
+		t.Errorf("expected JSON vector:\n%s", diff)
+	}
+
+	// The node serves gRPC.
+	grpcVector := Vector{Latencies: []Latency{{Destination: "http://grpc:3000", Ok: true, Duration: 2 * time.Millisecond}}}
+	port, stop := serveGRPC(t, &vectorServer{v: grpcVector})
+	defer stop()
+	fanout = newGRPCFanout(port)
+	v, err = getVectorFrom(context.Background(), u)
+	if err != nil {
+		t.Fatalf("failed to get vector: %v", err)
+	}
+	if diff := pretty.Compare(v.Latencies, grpcVector.Latencies); diff != "" {
+		t.Errorf("expected gRPC vector:\n%s", diff)
+	}
+}
+
+func TestWatchMatrix(t *testing.T) {
+	ss := newSnapshotStore(2)
+	ss.Add(squareMatrix([][]int{{0}}), time.Now())
+	port, stop := serveGRPC(t, &grpcServer{ss: ss})
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := grpc.DialContext(ctx, net.JoinHostPort("127.0.0.1", port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+	stream, err := api.NewAdjacencyClient(c).WatchMatrix(ctx, &api.WatchMatrixRequest{})
+	if err != nil {
+		t.Fatalf("failed to watch matrix: %v", err)
+	}
+	s, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("failed to receive snapshot: %v", err)
+	}
+	if s.Id != 1 {
+		t.Errorf("expected the latest snapshot first, got %d", s.Id)
+	}
+	// The subscription might not be registered yet when the snapshot is added,
+	// so keep adding snapshots until one arrives.
+	go func() {
+		for ctx.Err() == nil {
+			ss.Add(squareMatrix([][]int{{0, 1}, {1, 0}}), time.Now())
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	s, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("failed to receive snapshot: %v", err)
+	}
+	if s.Id <= 1 || len(s.Matrix.Vectors) != 2 {
+		t.Errorf("expected a new snapshot with two vectors, got %v", s)
+	}
+}
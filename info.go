@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// started is the time the service was started.
+var started = time.Now()
+
+// discoverySRV is the discovery backend that resolves the nodes from an SRV record.
+const discoverySRV = "dns-srv"
+
+// Discovery describes how a node finds the other nodes.
+type Discovery struct {
+	Backend string `json:"backend"`
+	SRV     string `json:"srv,omitempty"`
+}
+
+// NodeInfo describes a node of the adjacency service.
+type NodeInfo struct {
+	// Node is the stable identity of the node,
+	// i.e. the name of the Kubernetes node or the hostname.
+	Node      string   `json:"node"`
+	Hostnames []string `json:"hostnames"`
+	Addresses []string `json:"addresses"`
+	// Overlay and Underlay are the addresses of the node in the overlay network, e.g. WireGuard,
+	// and in the underlying network. They are only set if they are configured.
+	Overlay      string            `json:"overlay,omitempty"`
+	Underlay     string            `json:"underlay,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Version      string            `json:"version"`
+	APIVersion   int               `json:"apiVersion"`
+	Capabilities []string          `json:"capabilities"`
+	Probers      []string          `json:"probers"`
+	Discovery    Discovery         `json:"discovery"`
+	Started      time.Time         `json:"started"`
+	Uptime       time.Duration     `json:"uptime"`
+}
+
+// id returns the stable identity of the source of the vector.
+// Nodes that do not report their name are identified by the host and port of their URL.
+func (v Vector) id() string {
+	if v.Node != "" {
+		return v.Node
+	}
+	return hostPort(v.Source)
+}
+
+// name returns the name of the source of the vector for humans.
+func (v Vector) name() string {
+	if v.Node != "" {
+		return v.Node
+	}
+	return ipOrHost(v.IP, v.Host)
+}
+
+// id returns the stable identity of the destination of the latency, see Vector.id.
+func (l Latency) id() string {
+	if l.Node != "" {
+		return l.Node
+	}
+	return hostPort(l.Destination)
+}
+
+// name returns the name of the destination of the latency for humans.
+func (l Latency) name() string {
+	if l.Node != "" {
+		return l.Node
+	}
+	return ipOrHost(l.IP, l.Host)
+}
+
+// IdentifyDestinations sets the node names of all destinations that are also sources of the matrix,
+// so that rows and columns of the same node have the same identity.
+func (m matrix) IdentifyDestinations() {
+	nodes := make(map[string]string, len(m))
+	for _, v := range m {
+		if v.Node != "" {
+			nodes[hostPort(v.Source)] = v.Node
+		}
+	}
+	for i := range m {
+		for j := range m[i].Latencies {
+			if n, ok := nodes[hostPort(m[i].Latencies[j].Destination)]; ok && m[i].Latencies[j].Node == "" {
+				m[i].Latencies[j].Node = n
+			}
+		}
+	}
+}
+
+// interfaceAddresses returns the IP addresses of all network interfaces.
+func interfaceAddresses() ([]string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interface addresses: %w", err)
+	}
+	ips := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok {
+			ips = append(ips, n.IP.String())
+			continue
+		}
+		ips = append(ips, a.String())
+	}
+	return ips, nil
+}
+
+// hostnames returns the hostname and the names that the given addresses resolve to.
+// Loopback addresses are ignored.
+func hostnames(ctx context.Context, addrs []string) []string {
+	names := make(map[string]bool)
+	if h, err := os.Hostname(); err == nil {
+		names[h] = true
+	}
+	for _, a := range addrs {
+		if ip := net.ParseIP(a); ip == nil || ip.IsLoopback() {
+			continue
+		}
+		ns, err := net.DefaultResolver.LookupAddr(ctx, a)
+		if err != nil {
+			continue
+		}
+		for _, n := range ns {
+			names[strings.TrimSuffix(n, ".")] = true
+		}
+	}
+	hs := make([]string, 0, len(names))
+	for n := range names {
+		hs = append(hs, n)
+	}
+	sort.Strings(hs)
+	return hs
+}
+
+// Info returns the description of this node.
+func (vr *vectorer) Info(ctx context.Context) NodeInfo {
+	addrs, err := interfaceAddresses()
+	if err != nil {
+		log.Println(err)
+		errorCounter.Inc()
+	}
+	probers := make([]string, 0, len(vr.probers))
+	for _, p := range vr.probers {
+		probers = append(probers, p.String())
+	}
+	now := time.Now()
+	return NodeInfo{
+		Node:         vr.node,
+		Hostnames:    hostnames(ctx, addrs),
+		Addresses:    addrs,
+		Overlay:      vr.overlay,
+		Underlay:     vr.underlay,
+		Labels:       vr.l.Labels(ctx),
+		Version:      version,
+		APIVersion:   apiVersion,
+		Capabilities: vr.capabilities,
+		Probers:      probers,
+		Discovery:    Discovery{Backend: discoverySRV, SRV: vr.srv},
+		Started:      started,
+		Uptime:       now.Sub(started),
+	}
+}
+
+func infoHandler(vr *vectorer) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, vr.Info(r.Context()))
+	}
+}
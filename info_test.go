@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kilo-io/adjacency_service/pkg/prober"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+// identify sets the node names of the sources of the matrix
+// and moves the nodes to new addresses, as if they were restarted.
+func identify(m matrix, addr string) matrix {
+	for i := range m {
+		m[i].Node = fmt.Sprintf("node-%d", i)
+		m[i].Source = fmt.Sprintf("http://%s%d:3000/vector", addr, i)
+		for j := range m[i].Latencies {
+			m[i].Latencies[j].Destination = fmt.Sprintf("http://%s%d:3000", addr, j)
+		}
+	}
+	m.IdentifyDestinations()
+	return m
+}
+
+func TestIdentifyDestinations(t *testing.T) {
+	m := squareMatrix([][]int{{0, 1}, {1, 0}})
+	m[0].Node = "node-0"
+	m.IdentifyDestinations()
+	for i, id := range []string{"node-0", hostPort(m[1].Source)} {
+		if m[i].id() != id {
+			t.Errorf("expected row %d to be %q, got %q", i, id, m[i].id())
+		}
+		if l := m[0].Latencies[i]; l.id() != id {
+			t.Errorf("expected column %d to be %q, got %q", i, id, l.id())
+		}
+	}
+	if n := m[0].name(); n != "node-0" {
+		t.Errorf("expected name node-0, got %q", n)
+	}
+	if n := m[1].name(); n != "n1" {
+		t.Errorf("expected name n1, got %q", n)
+	}
+}
+
+func TestNamesInTable(t *testing.T) {
+	m := identify(squareMatrix([][]int{{0, 1}, {1, 0}}), "10.0.0.")
+	// The first node failed, so its row only has dummies.
+	m[0].Ok = false
+	for j := range m[0].Latencies {
+		m[0].Latencies[j] = Latency{Destination: dummy}
+	}
+	s := m.String(fancy)
+	for _, n := range []string{"NODE-0", "NODE-1", "| node-0", "| node-1"} {
+		if !strings.Contains(s, n) {
+			t.Errorf("expected %q in the table:\n%s", n, s)
+		}
+	}
+}
+
+func TestDiffStableIdentity(t *testing.T) {
+	from := identify(squareMatrix([][]int{{0, 1}, {1, 0}}), "10.0.0.")
+	to := identify(squareMatrix([][]int{{0, 20}, {1, 0}}), "10.0.1.")
+	d := from.Diff(to, 5*time.Millisecond)
+	if len(d.AddedSources)+len(d.RemovedSources)+len(d.AddedDestinations)+len(d.RemovedDestinations) != 0 {
+		t.Errorf("expected nodes with new addresses to keep their rows, got %v", d)
+	}
+	if len(d.Changes) != 1 || d.Changes[0].Source != "node-0" || d.Changes[0].Destination != "node-1" {
+		t.Errorf("expected a single change from node-0 to node-1, got %v", d.Changes)
+	}
+}
+
+func TestInfoHandler(t *testing.T) {
+	vr := &vectorer{
+		srv:          "_adjacency._tcp.example.com",
+		probers:      []prober.Prober{&prober.NoProber{}},
+		l:            &labeler{static: labelsFlag{"zone": "a"}},
+		node:         "node-0",
+		capabilities: []string{capDestinations},
+	}
+	w := httptest.NewRecorder()
+	infoHandler(vr)(w, httptest.NewRequest("GET", "/info", nil))
+	var i NodeInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &i); err != nil {
+		t.Fatalf("failed to parse info: %v", err)
+	}
+	if len(i.Hostnames) == 0 {
+		t.Error("expected at least the hostname")
+	}
+	if len(i.Addresses) == 0 {
+		t.Error("expected at least one address")
+	}
+	if i.Uptime <= 0 || i.Started.IsZero() {
+		t.Errorf("expected uptime and start time, got %v and %v", i.Uptime, i.Started)
+	}
+	i.Hostnames, i.Addresses, i.Uptime, i.Started = nil, nil, 0, time.Time{}
+	expected := NodeInfo{
+		Node:         "node-0",
+		Labels:       map[string]string{"zone": "a"},
+		Version:      version,
+		APIVersion:   apiVersion,
+		Capabilities: []string{capDestinations},
+		Probers:      []string{(&prober.NoProber{}).String()},
+		Discovery:    Discovery{Backend: discoverySRV, SRV: "_adjacency._tcp.example.com"},
+	}
+	if diff := pretty.Compare(i, expected); diff != "" {
+		t.Errorf("got diff:\n%s", diff)
+	}
+}
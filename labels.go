@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// labelAliases maps short label names that can be used in query parameters
+// to the well-known label keys used by Kubernetes and Kilo.
+var labelAliases = map[string]string{
+	"zone":     "topology.kubernetes.io/zone",
+	"region":   "topology.kubernetes.io/region",
+	"location": "kilo.squat.ai/location",
+}
+
+// labelValue returns the value of the label with the given key.
+// If the key is not set, but it is an alias for a well-known label,
+// the value of the well-known label is returned.
+func labelValue(labels map[string]string, key string) string {
+	if v, ok := labels[key]; ok {
+		return v
+	}
+	return labels[labelAliases[key]]
+}
+
+// labelsFlag implements the flag.Value interface
+// so that labels can be given as repeated key=value flags.
+type labelsFlag map[string]string
+
+func (l labelsFlag) String() string {
+	var s []string
+	for k, v := range l {
+		s = append(s, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(s)
+	return strings.Join(s, ",")
+}
+
+func (l labelsFlag) Set(s string) error {
+	for _, kv := range strings.Split(s, ",") {
+		p := strings.SplitN(kv, "=", 2)
+		if len(p) != 2 || p[0] == "" {
+			return fmt.Errorf("label %q is not of the form key=value", kv)
+		}
+		l[strings.TrimSpace(p[0])] = strings.TrimSpace(p[1])
+	}
+	return nil
+}
+
+// parseLabelsFile parses labels in the format that the Kubernetes downward API
+// uses for files, i.e. one key="value" pair per line.
+func parseLabelsFile(data []byte) (map[string]string, error) {
+	labels := make(map[string]string)
+	s := bufio.NewScanner(strings.NewReader(string(data)))
+	for i := 1; s.Scan(); i++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p := strings.SplitN(line, "=", 2)
+		if len(p) != 2 || p[0] == "" {
+			return nil, fmt.Errorf("line %d is not of the form key=value", i)
+		}
+		v := p[1]
+		if strings.HasPrefix(v, `"`) {
+			var err error
+			if v, err = strconv.Unquote(v); err != nil {
+				return nil, fmt.Errorf("failed to unquote value in line %d: %w", i, err)
+			}
+		}
+		labels[p[0]] = v
+	}
+	return labels, s.Err()
+}
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	// nodeLabelsBackoff is the time after a failed lookup of the labels of the node
+	// before they are looked up again.
+	nodeLabelsBackoff = time.Minute
+)
+
+// nodeLabels looks up the labels of a Kubernetes node
+// using the credentials of the pod's service account.
+func nodeLabels(ctx context.Context, node string) (map[string]string, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running inside of a Kubernetes cluster")
+	}
+	token, err := ioutil.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+	ca, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("failed to parse service account CA")
+	}
+	c := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}
+	u := url.URL{
+		Scheme: "https",
+		Host:   net.JoinHostPort(host, port),
+		Path:   "/api/v1/nodes/" + url.PathEscape(node),
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node %s: %w", node, err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get node %s: got status %d: %s", node, resp.StatusCode, body)
+	}
+	var n struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(body, &n); err != nil {
+		return nil, fmt.Errorf("failed to parse node %s: %w", node, err)
+	}
+	return n.Metadata.Labels, nil
+}
+
+// labeler assembles the labels a node advertises.
+// Labels from the Kubernetes node are overwritten by labels from the file,
+// which in turn are overwritten by labels given as flags.
+// It is safe to use concurrently.
+type labeler struct {
+	static labelsFlag
+	file   string
+	node   string
+	// lookup looks up the labels of the Kubernetes node; it defaults to nodeLabels.
+	lookup func(context.Context, string) (map[string]string, error)
+
+	mu sync.Mutex
+	// nodeLabels caches the labels of the Kubernetes node,
+	// which is only looked up until it succeeds once.
+	nodeLabels map[string]string
+	// looking is true while the labels of the node are looked up
+	// and retry is the earliest time of the next lookup after a failure.
+	looking bool
+	retry   time.Time
+}
+
+// Labels returns the current labels of the node.
+// Failing to read a source of labels is logged but not fatal,
+// so that a node can always answer requests.
+func (l *labeler) Labels(ctx context.Context) map[string]string {
+	return l.labels(ctx, time.Now())
+}
+
+func (l *labeler) labels(ctx context.Context, now time.Time) map[string]string {
+	labels := make(map[string]string)
+	if l.node != "" {
+		// The lock is not held during the lookup, so that a slow API server
+		// only delays the request that looks up the labels.
+		l.mu.Lock()
+		lookup := l.nodeLabels == nil && !l.looking && !now.Before(l.retry)
+		l.looking = lookup
+		for k, v := range l.nodeLabels {
+			labels[k] = v
+		}
+		l.mu.Unlock()
+		if lookup {
+			f := l.lookup
+			if f == nil {
+				f = nodeLabels
+			}
+			nl, err := f(ctx, l.node)
+			l.mu.Lock()
+			l.looking = false
+			if err != nil {
+				log.Printf("failed to look up labels of node %s: %v\n", l.node, err)
+				errorCounter.Inc()
+				l.retry = now.Add(nodeLabelsBackoff)
+			} else {
+				l.nodeLabels = nl
+			}
+			l.mu.Unlock()
+			for k, v := range nl {
+				labels[k] = v
+			}
+		}
+	}
+	if l.file != "" {
+		data, err := ioutil.ReadFile(l.file)
+		if err != nil {
+			log.Printf("failed to read labels file: %v\n", err)
+			errorCounter.Inc()
+		} else if fl, err := parseLabelsFile(data); err != nil {
+			log.Printf("failed to parse labels file: %v\n", err)
+			errorCounter.Inc()
+		} else {
+			for k, v := range fl {
+				labels[k] = v
+			}
+		}
+	}
+	for k, v := range l.static {
+		labels[k] = v
+	}
+	return labels
+}
+
+func labelsHandler(l *labeler) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := json.Marshal(l.Labels(r.Context()))
+		if err != nil {
+			log.Printf("failed to marshal data: %v\n", err)
+			errorCounter.Inc()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(data)
+	}
+}
+
+// hostPort returns the host and port of the given URL.
+// It is used to identify the same node in the sources and the destinations of a matrix.
+func hostPort(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Host
+}
+
+// LabelDestinations copies the labels of the sources of the matrix
+// to the latencies whose destination is the same node.
+// This way destinations are labeled, if the matrix is square.
+func (m matrix) LabelDestinations() {
+	labels := make(map[string]map[string]string, len(m))
+	for _, v := range m {
+		if v.Labels != nil {
+			labels[hostPort(v.Source)] = v.Labels
+		}
+	}
+	for i := range m {
+		for j := range m[i].Latencies {
+			if l, ok := labels[hostPort(m[i].Latencies[j].Destination)]; ok && m[i].Latencies[j].Labels == nil {
+				m[i].Latencies[j].Labels = l
+			}
+		}
+	}
+}
+
+// SortByLabel sorts the rows of a padded matrix by the value of the given label
+// and reorders the columns of every row by the labels of the destinations.
+// The order of rows and columns with the same label value is preserved.
+func (m matrix) SortByLabel(key string) {
+	sort.SliceStable(m, func(i, j int) bool {
+		return labelValue(m[i].Labels, key) < labelValue(m[j].Labels, key)
+	})
+	if len(m) == 0 {
+		return
+	}
+	// All rows of a padded matrix have the same destinations,
+	// so the order of the columns can be computed once.
+	idx := make([]int, len(m[0].Latencies))
+	for i := range idx {
+		idx[i] = i
+	}
+	// Dummies do not have labels, so the labels of a column are taken from any row that has them.
+	labels := make([]map[string]string, len(idx))
+	for _, v := range m {
+		for j, l := range v.Latencies {
+			if labels[j] == nil && l.Labels != nil {
+				labels[j] = l.Labels
+			}
+		}
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return labelValue(labels[idx[i]], key) < labelValue(labels[idx[j]], key)
+	})
+	for i := range m {
+		lats := make([]Latency, len(idx))
+		for j, k := range idx {
+			lats[j] = m[i].Latencies[k]
+		}
+		m[i].Latencies = lats
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestLabelValue(t *testing.T) {
+	labels := map[string]string{
+		"topology.kubernetes.io/zone": "a",
+		"region":                      "custom",
+	}
+	for i, tc := range []struct {
+		key string
+		v   string
+	}{
+		{key: "zone", v: "a"},
+		{key: "topology.kubernetes.io/zone", v: "a"},
+		{key: "region", v: "custom"},
+		{key: "location", v: ""},
+	} {
+		if v := labelValue(labels, tc.key); v != tc.v {
+			t.Errorf("%d: got %q, expected %q", i, v, tc.v)
+		}
+	}
+}
+
+func TestParseLabelsFile(t *testing.T) {
+	for i, tc := range []struct {
+		name   string
+		data   string
+		labels map[string]string
+		err    bool
+	}{
+		{
+			name:   "empty",
+			labels: map[string]string{},
+		},
+		{
+			name: "downward API",
+			data: "app=\"adjacency\"\ntopology.kubernetes.io/zone=\"eu-west-1a\"\n",
+			labels: map[string]string{
+				"app":                         "adjacency",
+				"topology.kubernetes.io/zone": "eu-west-1a",
+			},
+		},
+		{
+			name: "unquoted and comments",
+			data: "# comment\n\nzone=a\nempty=\n",
+			labels: map[string]string{
+				"zone":  "a",
+				"empty": "",
+			},
+		},
+		{
+			name: "missing value",
+			data: "zone\n",
+			err:  true,
+		},
+		{
+			name: "broken quotes",
+			data: "zone=\"a\n",
+			err:  true,
+		},
+	} {
+		labels, err := parseLabelsFile([]byte(tc.data))
+		if (err != nil) != tc.err {
+			t.Errorf("%d (%s): got error %v, expected error = %t", i, tc.name, err, tc.err)
+			continue
+		}
+		if diff := pretty.Compare(labels, tc.labels); !tc.err && diff != "" {
+			t.Errorf("%d (%s): unexpected labels:\n%s", i, tc.name, diff)
+		}
+	}
+}
+
+func TestLabelsFlag(t *testing.T) {
+	l := make(labelsFlag)
+	for _, s := range []string{"zone=a", "region=eu,location=x"} {
+		if err := l.Set(s); err != nil {
+			t.Fatalf("failed to set %q: %v", s, err)
+		}
+	}
+	if s := l.String(); s != "location=x,region=eu,zone=a" {
+		t.Errorf("got %q", s)
+	}
+	if err := l.Set("zone"); err == nil {
+		t.Error("expected an error for a label without value")
+	}
+}
+
+func TestLabelerNodeLabels(t *testing.T) {
+	calls := 0
+	block := make(chan struct{})
+	var fail bool
+	l := &labeler{
+		static: labelsFlag{"zone": "a"},
+		node:   "n0",
+		lookup: func(ctx context.Context, node string) (map[string]string, error) {
+			calls++
+			<-block
+			if fail {
+				return nil, errors.New("API server unavailable")
+			}
+			return map[string]string{"kubernetes.io/hostname": node}, nil
+		},
+	}
+	start := time.Now()
+	// Other requests do not wait for the lookup in progress.
+	done := make(chan map[string]string)
+	fail = true
+	go func() { done <- l.labels(context.Background(), start) }()
+	for {
+		l.mu.Lock()
+		looking := l.looking
+		l.mu.Unlock()
+		if looking {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if diff := pretty.Compare(l.labels(context.Background(), start), map[string]string{"zone": "a"}); diff != "" {
+		t.Errorf("expected the labels without the node, got diff:\n%s", diff)
+	}
+	close(block)
+	<-done
+	for _, tc := range []struct {
+		at       time.Duration
+		expected map[string]string
+		calls    int
+	}{
+		// The failure is cached until the backoff passed.
+		{at: nodeLabelsBackoff / 2, expected: map[string]string{"zone": "a"}, calls: 1},
+		{at: nodeLabelsBackoff, expected: map[string]string{"zone": "a", "kubernetes.io/hostname": "n0"}, calls: 2},
+		// The labels are only looked up until the lookup succeeds.
+		{at: 3 * nodeLabelsBackoff, expected: map[string]string{"zone": "a", "kubernetes.io/hostname": "n0"}, calls: 2},
+	} {
+		fail = false
+		if diff := pretty.Compare(l.labels(context.Background(), start.Add(tc.at)), tc.expected); diff != "" {
+			t.Errorf("at %v: got diff:\n%s", tc.at, diff)
+		}
+		if calls != tc.calls {
+			t.Errorf("at %v: expected %d lookups, got %d", tc.at, tc.calls, calls)
+		}
+	}
+}
+
+func TestLabelDestinationsAndSort(t *testing.T) {
+	la := map[string]string{"topology.kubernetes.io/zone": "b"}
+	lb := map[string]string{"topology.kubernetes.io/zone": "a"}
+	m := matrix{
+		{
+			Source: "http://1.example.com:3000/vector?srv=_http._tcp.example.com",
+			Labels: la,
+			Latencies: []Latency{
+				{Destination: "http://1.example.com:3000", Ok: true, Duration: time.Millisecond},
+				{Destination: "http://2.example.com:3000", Ok: true, Duration: 2 * time.Millisecond},
+			},
+		},
+		{
+			Source: "http://2.example.com:3000/vector?srv=_http._tcp.example.com",
+			Labels: lb,
+			Latencies: []Latency{
+				{Destination: "http://1.example.com:3000", Ok: true, Duration: 3 * time.Millisecond},
+				{Destination: "http://2.example.com:3000", Ok: true, Duration: 4 * time.Millisecond},
+			},
+		},
+	}
+	m.LabelDestinations()
+	m.SortByLabel("zone")
+	em := matrix{
+		{
+			Source: "http://2.example.com:3000/vector?srv=_http._tcp.example.com",
+			Labels: lb,
+			Latencies: []Latency{
+				{Destination: "http://2.example.com:3000", Ok: true, Duration: 4 * time.Millisecond, Labels: lb},
+				{Destination: "http://1.example.com:3000", Ok: true, Duration: 3 * time.Millisecond, Labels: la},
+			},
+		},
+		{
+			Source: "http://1.example.com:3000/vector?srv=_http._tcp.example.com",
+			Labels: la,
+			Latencies: []Latency{
+				{Destination: "http://2.example.com:3000", Ok: true, Duration: 2 * time.Millisecond, Labels: lb},
+				{Destination: "http://1.example.com:3000", Ok: true, Duration: time.Millisecond, Labels: la},
+			},
+		},
+	}
+	if diff := pretty.Compare(m, em); diff != "" {
+		t.Errorf("unexpected matrix:\n%s", diff)
+	}
+}
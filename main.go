@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -12,19 +14,21 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/kilo-io/adjacency_service/pkg/api"
 	"github.com/kilo-io/adjacency_service/pkg/prober"
 
-	"github.com/goccy/go-graphviz"
-	"github.com/goccy/go-graphviz/cgraph"
 	"github.com/olekukonko/tablewriter"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.zx2c4.com/wireguard/wgctrl"
+	"google.golang.org/grpc"
 )
 
 // possible values for the output that will be printed to the terminal
@@ -37,13 +41,63 @@ const (
 )
 
 var (
-	srv          *string        = flag.String("srv", "_service._proto.exmaple.com", "the srv record name to be used to look up IP addresses and port")
-	listenAddr   *string        = flag.String("listen-address", ":3000", "The service will be listening to that address with port\ne.g. 172.0.0.1:3000")
-	metricsAddr  *string        = flag.String("metrics-address", ":9090", "The metrics server will be listening to that address with port\ne.g. 172.0.0.1:9090")
-	timeout      *time.Duration = flag.Duration("timeout", 10*time.Second, "The time after a vector request to a node should be canceled.")
-	timeoutProbe *time.Duration = flag.Duration("timeout-probe", 0, "The time after a single probe should be canceled. If set, timeout will be ignored")
+	srv           *string        = flag.String("srv", "_service._proto.exmaple.com", "the srv record name to be used to look up IP addresses and port")
+	listenAddr    *string        = flag.String("listen-address", ":3000", "The service will be listening to that address with port\ne.g. 172.0.0.1:3000")
+	metricsAddr   *string        = flag.String("metrics-address", ":9090", "The metrics server will be listening to that address with port\ne.g. 172.0.0.1:9090")
+	timeout       *time.Duration = flag.Duration("timeout", 10*time.Second, "The time after a vector request to a node should be canceled.")
+	timeoutProbe  *time.Duration = flag.Duration("timeout-probe", 0, "The time after a single probe should be canceled. If set, timeout will be ignored; otherwise it is the timeout divided by one more than the number of probers that are tried for the scheme of the SRV record")
+	asymRatio     *float64       = flag.Float64("asymmetry-ratio", 2, "The ratio of the latencies in both directions between two nodes above which they are reported as asymmetric")
+	margin        *time.Duration = flag.Duration("triangle-margin", time.Millisecond, "The margin by which a path via a relay node must be faster than the direct path to be reported")
+	sampleSize    *int           = flag.Int("sample-size", 0, "If set, every node only probes this many random nodes of the adjacency service per round in the background and answers requests for the square matrix with the latest samples")
+	sampleIntv    *time.Duration = flag.Duration("sample-interval", 10*time.Second, "The time between two rounds of sampled probing")
+	sampleCov     *time.Duration = flag.Duration("sample-coverage", 0, "If set, the sample size is increased as needed, so that every pair of nodes is probed at least once within this time")
+	labelsFile    *string        = flag.String("labels-file", "", "A file with labels of the node, e.g. from the Kubernetes downward API, in the format key=\"value\", one per line")
+	k8sNode       *string        = flag.String("kubernetes-node", "", "The name of the Kubernetes node whose labels should be advertised; requires permission to get nodes")
+	snapshotIntv  *time.Duration = flag.Duration("snapshot-interval", 0, "If set, the square matrix is collected and stored as a snapshot in this interval")
+	snapshotCnt   *int           = flag.Int("snapshot-count", 10, "The number of snapshots that are kept in memory")
+	grpcAddr      *string        = flag.String("grpc-address", "", "If set, the gRPC API will be served on this address with port\ne.g. :3001")
+	useFanout     *bool          = flag.Bool("grpc-fanout", false, "If set, vectors are requested from the other nodes via gRPC on the port of grpc-address, falling back to JSON over HTTP for nodes that do not serve it")
+	tlsCAFile     *string        = flag.String("tls-ca-file", "", "A file with PEM-encoded CA certificates that the TLS prober trusts instead of the system roots")
+	dnsName       *string        = flag.String("dns-query-name", "kubernetes.default.svc.cluster.local", "The name that the DNS prober queries")
+	dnsType       *string        = flag.String("dns-query-type", "A", "The type of the records that the DNS prober queries, e.g. A or AAAA")
+	grpcService   *string        = flag.String("grpc-health-service", "", "The service that the gRPC health prober checks; the empty service checks the server as a whole")
+	wgDevice      *string        = flag.String("wireguard-device", "", "If set, the latencies are annotated with the state of the WireGuard peers of this device, e.g. kilo0")
+	wgStale       *time.Duration = flag.Duration("wireguard-stale-handshake", defaultStaleHandshake, "The age after which the handshake of a WireGuard peer is stale")
+	overlayAddr   *string        = flag.String("overlay-address", "", "The address of the node in the overlay network, e.g. WireGuard, or the interface to take it from, e.g. kilo0; it is advertised for dual matrices")
+	underlayAddr  *string        = flag.String("underlay-address", "", "The address of the node in the underlying network or the interface to take it from, e.g. eth0; it is advertised for dual matrices")
+	sourceAddr    *string        = flag.String("source-address", "", "The IP address that probes are sent from or the interface to take it from, e.g. eth0; by default the kernel picks it")
+	sourceIface   *string        = flag.String("source-interface", "", "The network interface that probes are bound to with SO_BINDTODEVICE, e.g. kilo0; only supported on Linux")
+	traceMethod   *string        = flag.String("traceroute-method", prober.TracerouteUDP, "The protocol of the probes of traceroutes: udp, tcp or icmp; traceroutes need the NET_RAW capability")
+	traceMaxHops  *int           = flag.Int("traceroute-max-hops", 30, "The maximum number of hops of traceroutes")
+	traceIntv     *time.Duration = flag.Duration("traceroute-interval", 0, "The interval in which the paths to all nodes are traced to detect changes; 0 only traces paths on request")
+	mtuMethod     *string        = flag.String("mtu-method", prober.MTUICMP, "The method of MTU probes: icmp or udp, which send packets with the DF bit and need Linux, or http, which sends large payloads to the echo endpoint of the nodes")
+	mtuMax        *int           = flag.Int("mtu-max", 1500, "The largest MTU that MTU probes try")
+	mtuExpected   *int           = flag.Int("mtu-expected", 1420, "The MTU that is expected between nodes, e.g. the MTU of the WireGuard interface; smaller MTUs are highlighted")
+	tpBytes       *int64         = flag.Int64("throughput-bytes", 10<<20, "The number of bytes that a throughput test transfers to every destination unless the request sets bytes")
+	tpStreams     *int           = flag.Int("throughput-streams", 1, "The number of parallel TCP streams of a throughput test unless the request sets streams")
+	tpDirection   *string        = flag.String("throughput-direction", prober.ThroughputUpload, "The direction of throughput tests unless the request sets direction: upload sends the data to the destinations and download fetches it from them")
+	tpTimeout     *time.Duration = flag.Duration("throughput-timeout", 10*time.Second, "The time after which the throughput test to a single destination fails")
+	clockSamples  *int           = flag.Int("clock-samples", 5, "The number of timestamp exchanges from which the clock of a destination is estimated")
+	clockSkew     *time.Duration = flag.Duration("clock-skew-threshold", 50*time.Millisecond, "The offset from the clocks of the other nodes above which the clock of a node is reported as skewed")
+	diffThresh    *time.Duration = flag.Duration("diff-threshold", 5*time.Millisecond, "The difference of latencies between two snapshots above which a pair of nodes is reported")
+	alertLatency  *time.Duration = flag.Duration("alert-latency", 0, "If set, an alert fires if the latency of a pair of nodes is above this value for alert-latency-rounds consecutive snapshots")
+	alertRounds   *int           = flag.Int("alert-latency-rounds", 3, "The number of consecutive snapshots the latency of a pair of nodes must be above alert-latency for an alert to fire")
+	alertUnreach  *int           = flag.Int("alert-unreachable-from", -1, "If not negative, an alert fires if a node is unreachable from more than this many peers")
+	alertPart     *bool          = flag.Bool("alert-partition", false, "If set, an alert fires if the network is partitioned")
+	baseAlpha     *float64       = flag.Float64("baseline-alpha", 0.1, "The weight of a new snapshot in the moving average and variance of the latency of a pair of nodes")
+	baseWarmup    *int           = flag.Int("baseline-warmup", 10, "The number of snapshots a pair of nodes needs before its latencies can be anomalous")
+	anomalyThr    *float64       = flag.Float64("anomaly-threshold", 3, "The number of standard deviations from the baseline above which a latency is anomalous")
+	staticLabels                 = make(labelsFlag)
+	webhooks      urlsFlag
+	alertmanagers urlsFlag
 )
 
+func init() {
+	flag.Var(staticLabels, "label", "A label of the node in the format key=value; can be repeated and overwrites labels from other sources")
+	flag.Var(&webhooks, "alert-webhook", "A URL that receives a POST request when an alert fires or resolves; can be repeated")
+	flag.Var(&alertmanagers, "alertmanager-url", "The URL of the alerts API of an Alertmanager, e.g. http://alertmanager:9093/api/v2/alerts; can be repeated")
+}
+
 const dummy = "dummy"
 
 var (
@@ -60,6 +114,27 @@ var (
 			Help: "The total number of errors",
 		},
 	)
+	anomalyGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "anomaly",
+			Help: "Whether the latency of a pair of nodes in the latest snapshot deviates from its baseline",
+		},
+		[]string{"source", "destination"},
+	)
+	clockOffsetGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "clock_offset_seconds",
+			Help: "The estimated offset of the clock of a node of the adjacency service from the clock of this node",
+		},
+		[]string{"destination"},
+	)
+	pathChangeCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "path_changes_total",
+			Help: "The number of changes of the paths from this node to its destinations",
+		},
+		[]string{"destination"},
+	)
 )
 
 type Latency struct {
@@ -69,24 +144,89 @@ type Latency struct {
 	Duration    time.Duration `json:"duration"`
 	Ok          bool          `json:"ok"`
 	Prober      string        `json:"prober"`
+	// Labels are only known if the destination is also a source of the matrix.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Stats are only set if the matrix is grouped by a label.
+	Stats *Stats `json:"stats,omitempty"`
+	// Age is the time since the latency was probed.
+	// It is only set if the source samples its destinations.
+	Age time.Duration `json:"age,omitempty"`
+	// Anomaly is the deviation of the latency from the baseline of the pair
+	// in standard deviations. It is only set if the latency is anomalous.
+	Anomaly float64 `json:"anomaly,omitempty"`
+	// Node is the name of the destination.
+	// It is only known if the destination is also a source of the matrix.
+	Node string `json:"node,omitempty"`
+	// Details are only set by probers that report more than the duration.
+	Details *prober.Details `json:"details,omitempty"`
+	// WireGuard is only set if the source reads the state of its WireGuard device
+	// and the destination is one of its peers.
+	WireGuard *WireGuardPeer `json:"wireguard,omitempty"`
+	// Overlay and Underlay are only set in dual matrices
+	// if the destination advertises its overlay and underlay addresses.
+	Overlay  *PathLatency `json:"overlay,omitempty"`
+	Underlay *PathLatency `json:"underlay,omitempty"`
+	// Overhead is the latency via the overlay minus the latency via the underlay.
+	// It is only set if both were probed successfully.
+	Overhead time.Duration `json:"overhead,omitempty"`
+	// SourceIP is the local IP address of the connection of the probe.
+	// It is only known if the probe connected to the destination.
+	SourceIP string `json:"sourceIP,omitempty"`
+	// MTU is only set if the path MTU was requested with stat=mtu.
+	MTU *MTU `json:"mtu,omitempty"`
+	// Throughput is only set if the throughput was requested via /throughput.
+	Throughput *Throughput `json:"throughput,omitempty"`
+	// Clock is only set if the clock was requested with stat=clock.
+	Clock *Clock `json:"clock,omitempty"`
 }
 
 func (l Latency) String() string {
 	if l.Destination == dummy {
 		return ""
 	}
-	if l.Ok {
-		return l.Duration.String()
+	if l.Stats != nil {
+		return l.Stats.String()
+	}
+	s := "-"
+	switch {
+	case l.MTU != nil:
+		s = l.MTU.String()
+	case l.Throughput != nil:
+		s = l.Throughput.String()
+	case l.Clock != nil:
+		s = l.Clock.String()
+	case l.Overlay != nil || l.Underlay != nil:
+		s = l.dualString()
+	case l.Ok:
+		s = l.Duration.String()
+	}
+	if l.Age != 0 {
+		s += fmt.Sprintf(" (%v)", l.Age.Round(time.Second))
+	}
+	if l.Anomaly != 0 {
+		s += " !"
 	}
-	return "-"
+	if l.WireGuard != nil && l.WireGuard.Stale {
+		s += " ~"
+	}
+	return s
 }
 
 type Vector struct {
-	Source    string    `json:"source"`
-	IP        string    `json:"ip,omitempty"`
-	Host      string    `json:"host,omitempty"`
-	Latencies []Latency `json:"latencies,omitempty"`
-	Ok        bool      `json:"ok"`
+	Source string            `json:"source"`
+	IP     string            `json:"ip,omitempty"`
+	Host   string            `json:"host,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// Coordinate is the Vivaldi coordinate of the source.
+	Coordinate *Coordinate `json:"coordinate,omitempty"`
+	Latencies  []Latency   `json:"latencies,omitempty"`
+	Ok         bool        `json:"ok"`
+	// Node, Version, APIVersion and Capabilities describe the node of the source.
+	// They are only known for nodes that respond with an envelope.
+	Node         string   `json:"node,omitempty"`
+	Version      string   `json:"version,omitempty"`
+	APIVersion   int      `json:"apiVersion,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
 }
 
 type matrix []Vector
@@ -96,45 +236,47 @@ type format int
 // In case some nodes get different
 // dns resolution, fill matrix with dummy entries, so entries
 // within a row or column still have the same source/destination.
+// Rows and columns are ordered by the identity of their nodes,
+// so that identified destinations are in the same order as their sources.
 func (m matrix) Pad() matrix {
-	for _, lats := range m {
-		sort.Slice(lats.Latencies, func(i, j int) bool {
-			return lats.Latencies[i].Destination < lats.Latencies[j].Destination
-		})
-	}
 	sort.Slice(m, func(i, j int) bool {
+		if a, b := m[i].id(), m[j].id(); a != b {
+			return a < b
+		}
 		return m[i].Source < m[j].Source
 	})
-	var urlsH, urlsV []string
-	urlsVM := make(map[string]struct{})
-	// Find all different urls in the rows.
+	// Find all different destinations in the rows.
+	ids := make(map[string]string)
+	var urlsH []string
 	for _, v := range m {
-		urlsV = append(urlsV, v.Source)
 		for _, l := range v.Latencies {
-			urlsVM[l.Destination] = struct{}{}
+			if _, ok := ids[l.Destination]; !ok {
+				ids[l.Destination] = l.id()
+				urlsH = append(urlsH, l.Destination)
+			}
 		}
 	}
-	// Create a slice to be able to order the urls.
-	for u := range urlsVM {
-		urlsH = append(urlsH, u)
-	}
 	sort.Slice(urlsH, func(i, j int) bool {
+		if a, b := ids[urlsH[i]], ids[urlsH[j]]; a != b {
+			return a < b
+		}
 		return urlsH[i] < urlsH[j]
 	})
 
-	nm := make(matrix, len(urlsV))
+	nm := make(matrix, len(m))
 	for k, v := range m {
+		lats := make(map[string]Latency, len(v.Latencies))
+		for _, l := range v.Latencies {
+			lats[l.Destination] = l
+		}
 		nV := v
 		nV.Latencies = make([]Latency, len(urlsH))
-		// Find the missing url in the row
-		// and insert dummies.
-		offset := 0
+		// Insert dummies for the destinations that are missing in the row.
 		for i, u := range urlsH {
-			if i < len(v.Latencies)+offset && v.Latencies[i-offset].Destination == u {
-				nV.Latencies[i] = v.Latencies[i-offset]
+			if l, ok := lats[u]; ok {
+				nV.Latencies[i] = l
 				continue
 			}
-			offset++
 			nV.Latencies[i].Destination = dummy
 			nV.Latencies[i].Ok = false
 		}
@@ -143,6 +285,25 @@ func (m matrix) Pad() matrix {
 	return nm
 }
 
+// columns returns a latency of every column of the padded matrix that is not a dummy, if there is one,
+// so that the destinations of the columns are known even if some rows failed.
+func (m matrix) columns() []Latency {
+	if len(m) == 0 {
+		return nil
+	}
+	cols := make([]Latency, len(m[0].Latencies))
+	for j := range cols {
+		cols[j] = m[0].Latencies[j]
+		for i := range m {
+			if m[i].Latencies[j].Destination != dummy {
+				cols[j] = m[i].Latencies[j]
+				break
+			}
+		}
+	}
+	return cols
+}
+
 func ipOrHost(ip, host string) string {
 	if ip != naIP {
 		return ip
@@ -159,14 +320,33 @@ func (m matrix) String(f format) string {
 	var data [][]string
 	switch f {
 	case fancy:
+		// The version column is only shown if any node reported its version,
+		// e.g. to spot nodes that were not upgraded yet.
+		versions := false
+		for _, v := range m {
+			if v.Version != "" {
+				versions = true
+				break
+			}
+		}
 		line := []string{"Source\\Dest"}
-		for _, l := range m[0].Latencies {
-			line = append(line, ipOrHost(l.IP, l.Host))
+		if versions {
+			line = append(line, "Version")
+		}
+		for _, l := range m.columns() {
+			line = append(line, l.name())
 		}
 		table.SetHeader(line)
 		line = []string{}
 		for _, v := range m {
-			line = []string{ipOrHost(v.IP, v.Host)}
+			line = []string{v.name()}
+			if versions {
+				version := v.Version
+				if version == "" {
+					version = "-"
+				}
+				line = append(line, version)
+			}
 			for _, l := range v.Latencies {
 				line = append(line, l.String())
 			}
@@ -209,17 +389,27 @@ func (m matrix) String(f format) string {
 }
 
 func timeHTTPRequest(ctx context.Context, probers []prober.Prober, u *url.URL, timeout time.Duration) *Latency {
+	ctx, used := prober.WithUsedSource(ctx)
 	var dur time.Duration
-	var err error
-	var p prober.Prober
-	for _, p = range probers {
+	var details *prober.Details
+	err := fmt.Errorf("%w: %s", prober.ErrUnsupportedScheme, u.Scheme)
+	var name string
+	// The chain ends with the first prober that is final for the scheme,
+	// so that its failure is not hidden by the probers after it.
+	for _, p := range prober.Chain(probers, u.Scheme) {
 		ctxT, cancelT := context.WithTimeout(ctx, timeout)
 		defer cancelT()
-		if dur, err = p.Probe(ctxT, *u); err == nil {
-			break
+		name = p.String()
+		if dp, ok := p.(prober.DetailedProber); ok {
+			dur, details, err = dp.ProbeDetails(ctxT, *u)
 		} else {
-			log.Printf("prober %s failed: %v", p.String(), err)
+			details = nil
+			dur, err = p.Probe(ctxT, *u)
+		}
+		if err == nil {
+			break
 		}
+		log.Printf("prober %s failed: %v", name, err)
 	}
 	if err != nil {
 		log.Printf("failed to successfully determine any latency: %v\n", err)
@@ -237,9 +427,11 @@ func timeHTTPRequest(ctx context.Context, probers []prober.Prober, u *url.URL, t
 		Destination: u.String(),
 		Duration:    dur,
 		Host:        u.Hostname(),
-		Prober:      p.String(),
+		Prober:      name,
 		IP:          ip,
 		Ok:          err == nil,
+		Details:     details,
+		SourceIP:    used(),
 	}
 }
 
@@ -257,15 +449,42 @@ func getLatencies(ctx context.Context, probers []prober.Prober, urls []*url.URL,
 	return lats
 }
 
+// srvSchemes maps the services and protocols of SRV records to the schemes of the URLs of their targets.
+// Targets of other services are reached via HTTP.
+var srvSchemes = map[string]string{
+	"_https._tcp":    "https",
+	"_dns._udp":      prober.SchemeDNS,
+	"_dns._tcp":      prober.SchemeDNSTCP,
+	"_domain._udp":   prober.SchemeDNS,
+	"_domain._tcp":   prober.SchemeDNSTCP,
+	"_domain-s._tcp": prober.SchemeDNSTLS,
+	"_grpc._tcp":     prober.SchemeGRPC,
+	"_grpcs._tcp":    prober.SchemeGRPCS,
+}
+
+// srvScheme returns the scheme of the URLs of the targets of the SRV record,
+// e.g. https for _https._tcp.example.com.
+func srvScheme(srv string) string {
+	parts := strings.SplitN(srv, ".", 3)
+	if len(parts) < 2 {
+		return "http"
+	}
+	if s, ok := srvSchemes[parts[0]+"."+parts[1]]; ok {
+		return s
+	}
+	return "http"
+}
+
 func resolveSRV(srv, path, query string) ([]*url.URL, error) {
 	_, addrs, err := net.LookupSRV("", "", srv)
 	if err != nil {
 		return nil, err
 	}
+	scheme := srvScheme(srv)
 	urls := make([]*url.URL, 0, len(addrs))
 	for _, addr := range addrs {
 		urls = append(urls, &url.URL{
-			Scheme:   "http",
+			Scheme:   scheme,
 			Host:     fmt.Sprintf("%s:%d", strings.TrimRight(addr.Target, "."), addr.Port),
 			Path:     path,
 			RawQuery: query,
@@ -274,28 +493,193 @@ func resolveSRV(srv, path, query string) ([]*url.URL, error) {
 	return urls, nil
 }
 
-func vectorHandler(defaultSRV string, probers []prober.Prober, timeout time.Duration) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		srv := defaultSRV
-		var err error
-		if r.URL.Query()["srv"] != nil {
-			srv, err = srvFromRequest(r)
-			if err != nil {
-				log.Printf("failed to parse SRV record from request: %v\n", err)
-				errorCounter.Inc()
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
+// vectorer computes the vector of this node.
+type vectorer struct {
+	// srv is the SRV record of the adjacency service itself.
+	srv     string
+	probers []prober.Prober
+	timeout time.Duration
+	l       *labeler
+	c       *vivaldi
+	s       *sampler
+	// mtu probes the path MTU to the destinations, which is expected to be at least mtuExpected.
+	// The search to a destination takes up to mtuTimeout.
+	mtu         *prober.MTUProber
+	mtuExpected int
+	mtuTimeout  time.Duration
+	// tp tests the throughput to the destinations within tpTimeout with the defaults of tpDefaults.
+	tp         *prober.ThroughputProber
+	tpDefaults prober.ThroughputOptions
+	tpTimeout  time.Duration
+	// clock estimates the clocks of the destinations.
+	clock *prober.ClockProber
+	// offsets exports the offsets of the clocks of the nodes of srv.
+	offsets *clockOffsets
+	// source is the default source of probes.
+	source prober.Source
+	// wg is only set if the latencies are annotated with WireGuard peers.
+	wg *wireguard
+	// overlay and underlay are the advertised addresses of this node.
+	overlay, underlay string
+	ab                *addressBook
+	// dualTimeout bounds the probes of the overlay and underlay addresses of the destinations.
+	dualTimeout time.Duration
+	// node is the name of this node.
+	node string
+	// capabilities are advertised in the envelope of /vector.
+	capabilities []string
+}
+
+// Vector probes the nodes of the given SRV record that are selected by dsts
+// and returns the latencies to them.
+// The options select further measurements, e.g. of the overlay and underlay addresses of the nodes.
+// The probes are sent from the source of the context or else from the default source.
+// It only fails if the SRV record cannot be resolved.
+func (vr *vectorer) Vector(ctx context.Context, srv string, dsts *selector, o vectorOptions) (*Vector, error) {
+	if prober.SourceFromContext(ctx).IsZero() {
+		ctx = prober.WithSource(ctx, vr.source)
+	}
+	urls, err := resolveSRV(srv, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve SRV record: %w", err)
+	}
+	urls = filterURLs(ctx, urls, dsts)
+	// The MTU is probed alongside the latencies, so that the vector does not take longer.
+	var mtus map[string]*MTU
+	mtuDone := make(chan struct{})
+	go func() {
+		defer close(mtuDone)
+		if o.mtu {
+			mtus = vr.probeMTU(ctx, urls)
+		}
+	}()
+	// The overlay and underlay addresses are probed alongside the latencies as well.
+	var paths map[string]dualPaths
+	dualDone := make(chan struct{})
+	go func() {
+		defer close(dualDone)
+		if o.dual {
+			paths = vr.probeDual(ctx, urls)
+		}
+	}()
+	var lats []*Latency
+	switch {
+	case srv == vr.srv && vr.s != nil:
+		// The sampler probes the nodes of the adjacency service in the background.
+		lats = vr.s.Latencies(urls, time.Now())
+	case srv == vr.srv:
+		lats = getLatencies(ctx, vr.probers, urls, vr.timeout)
+		// Only the nodes of the adjacency service itself have coordinates.
+		// They are fetched in the background, so that the vector does not wait for them.
+		vr.c.UpdateInBackground(lats, vr.timeout)
+	default:
+		lats = getLatencies(ctx, vr.probers, urls, vr.timeout)
+	}
+	<-mtuDone
+	<-dualDone
+	// The throughput is tested after the latencies, so that the transfers do not delay the probes.
+	var tps map[string]*Throughput
+	if o.throughput {
+		tps = vr.probeThroughput(ctx, urls, o.tp)
+	}
+	// The clocks are estimated after the latencies as well, so that the probes do not delay the exchanges.
+	var clocks map[string]*Clock
+	if o.clock {
+		clocks = vr.probeClock(ctx, urls)
+		// Only the nodes of the adjacency service itself are exported,
+		// so that the metrics do not grow with every requested SRV record.
+		if srv == vr.srv && vr.offsets != nil {
+			vr.offsets.Record(clocks, time.Now())
+		}
+	}
+	for _, l := range lats {
+		l.MTU = mtus[l.Destination]
+		l.Throughput = tps[l.Destination]
+		l.Clock = clocks[l.Destination]
+		if p, ok := paths[l.Destination]; ok {
+			l.Overlay, l.Underlay = p.overlay, p.underlay
+			if o, ok := l.overhead(); ok {
+				l.Overhead = o
 			}
 		}
-		urls, err := resolveSRV(srv, "", "")
+	}
+	if vr.wg != nil {
+		if err := vr.wg.Annotate(lats, time.Now()); err != nil {
+			log.Println(err)
+			errorCounter.Inc()
+		}
+	}
+	coord := vr.c.Coordinate()
+	v := &Vector{
+		Labels:     vr.l.Labels(ctx),
+		Coordinate: &coord,
+		Ok:         true,
+	}
+	for _, lat := range lats {
+		v.Latencies = append(v.Latencies, *lat)
+	}
+	return v, nil
+}
+
+// vectorQueryFromValues returns the SRV record and the selector of the destinations
+// of a vector from the given query.
+func vectorQueryFromValues(q url.Values, defaultSRV string) (string, *selector, error) {
+	srv := defaultSRV
+	var err error
+	if q["srv"] != nil {
+		if srv, err = srvFromValues(q); err != nil {
+			return "", nil, fmt.Errorf("failed to parse SRV record from request: %w", err)
+		}
+	}
+	dsts, err := selectorFromValues(q, "destinations")
+	if err != nil {
+		return "", nil, err
+	}
+	return srv, dsts, nil
+}
+
+func vectorHandler(vr *vectorer) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		srv, dsts, err := vectorQueryFromValues(r.URL.Query(), vr.srv)
+		if err != nil {
+			log.Println(err)
+			errorCounter.Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		o, err := vectorOptionsFromValues(r.URL.Query())
 		if err != nil {
-			log.Printf("failed to resolve SRV record: %v\n", err)
+			log.Println(err)
+			errorCounter.Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		source, err := sourceFromValues(r.URL.Query())
+		if err != nil {
+			log.Println(err)
+			errorCounter.Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		v, err := vr.Vector(prober.WithSource(r.Context(), source), srv, dsts, o)
+		if err != nil {
+			log.Println(err)
 			errorCounter.Inc()
 			http.Error(w, err.Error(), http.StatusServiceUnavailable)
 			return
 		}
-		lats := getLatencies(r.Context(), probers, urls, timeout)
-		data, err := json.Marshal(lats)
+		var resp interface{} = v
+		if av := acceptedAPIVersion(r.Header.Get("Accept")); av >= apiEnvelope {
+			resp = vectorEnvelope{
+				APIVersion:   av,
+				Node:         vr.node,
+				Version:      version,
+				Capabilities: vr.capabilities,
+				Vector:       *v,
+			}
+			w.Header().Set("Content-Type", vectorMediaType(av))
+		}
+		data, err := json.Marshal(resp)
 		if err != nil {
 			log.Printf("failed to marshal data: %v\n", err)
 			errorCounter.Inc()
@@ -310,8 +694,8 @@ func pingHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("pong"))
 }
 
-func srvFromRequest(r *http.Request) (string, error) {
-	srv := r.URL.Query()["srv"][0]
+func srvFromValues(q url.Values) (string, error) {
+	srv := q.Get("srv")
 	if len(strings.SplitN(srv, ".", 3)) != 3 {
 		return "", errors.New("the given SRV record name does not have a valid format; it should look something like _foo._tcp.example.com")
 	}
@@ -329,10 +713,29 @@ func getVectorFrom(ctx context.Context, url *url.URL) (*Vector, error) {
 		Host:   url.Hostname(),
 		IP:     ip,
 	}
+	if fanout != nil {
+		rv, err := fanout.GetVector(ctx, url)
+		if err == nil {
+			v.Labels = rv.Labels
+			v.Coordinate = rv.Coordinate
+			v.Latencies = rv.Latencies
+			v.Node = rv.Node
+			v.Version = rv.Version
+			v.APIVersion = rv.APIVersion
+			v.Capabilities = rv.Capabilities
+			v.Ok = true
+			return v, nil
+		}
+		if !errors.Is(err, errNoGRPC) {
+			return v, err
+		}
+		// Fall back to JSON for nodes that do not serve the gRPC API.
+	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
 	if err != nil {
 		return v, err
 	}
+	req.Header.Set("Accept", vectorAccept)
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return v, fmt.Errorf("failed to make GET request: %w", err)
@@ -348,60 +751,213 @@ func getVectorFrom(ctx context.Context, url *url.URL) (*Vector, error) {
 	if err != nil {
 		return v, fmt.Errorf("failed to read body: %w", err)
 	}
-	if err = json.Unmarshal((body), &v.Latencies); err != nil {
-		return v, fmt.Errorf("response from node has wrong format: maybe it is not running this service?: %w", err)
+	if err := parseVectorResponse(resp.Header.Get("Content-Type"), body, v); err != nil {
+		return v, err
 	}
 	v.Ok = true
 	return v, nil
 }
 
-func collectAllHandler(srv string, timeout time.Duration) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		target := srv
-		// The srv target will be over written, if it is specified in the url query.
-		if r.URL.Query()["srv"] != nil {
-			var err error
-			target, err = srvFromRequest(r)
+// matrixQuery describes which part of the matrix should be collected.
+type matrixQuery struct {
+	// target is the SRV record of the destinations.
+	target string
+	// sources selects the nodes that are asked for their vectors.
+	sources *selector
+	// destinations is passed on to the nodes, which only probe the selected destinations.
+	destinations string
+	// dual is passed on to the nodes, which then also probe the overlay and underlay addresses.
+	dual bool
+	// sourceAddress and sourceInterface are passed on to the nodes, which send their probes from them.
+	// They are not checked, because they are resolved by every node.
+	sourceAddress, sourceInterface string
+	// stat is passed on to the nodes, which then also measure it, e.g. the path MTU.
+	stat string
+	// tp is passed on to the nodes with the throughput stat.
+	tp prober.ThroughputOptions
+}
+
+func matrixQueryFromValues(v url.Values, srv string) (matrixQuery, error) {
+	q := matrixQuery{target: srv}
+	var err error
+	// The srv target will be over written, if it is specified in the url query.
+	if v["srv"] != nil {
+		if q.target, err = srvFromValues(v); err != nil {
+			return q, err
+		}
+	}
+	if q.sources, err = selectorFromValues(v, "sources"); err != nil {
+		return q, err
+	}
+	if q.destinations = v.Get("destinations"); q.destinations != "" {
+		if _, err := parseSelector(q.destinations); err != nil {
+			return q, fmt.Errorf("failed to parse destinations: %w", err)
+		}
+	}
+	if q.dual, err = dualFromValues(v); err != nil {
+		return q, err
+	}
+	q.sourceAddress, q.sourceInterface = v.Get("sourceAddress"), v.Get("sourceInterface")
+	if q.stat, err = parseStat(v.Get("stat")); err != nil {
+		return q, err
+	}
+	if q.stat == statThroughput {
+		if q.tp, err = throughputOptionsFromValues(v); err != nil {
+			return q, err
+		}
+	}
+	return q, nil
+}
+
+// collectMatrix asks all nodes of the adjacency service for their vectors
+// and returns the padded matrix.
+// With the throughput stat, the nodes are asked one after the other
+// and the timeout applies to every destination of a node.
+func collectMatrix(ctx context.Context, srv string, q matrixQuery, timeout time.Duration) (matrix, error) {
+	query := url.Values{"srv": []string{q.target}}
+	// The destinations are filtered by the nodes themselves,
+	// so that they do not probe nodes that are not selected.
+	if q.destinations != "" {
+		query.Set("destinations", q.destinations)
+	}
+	if q.dual {
+		query.Set("dual", "true")
+	}
+	if q.sourceAddress != "" {
+		query.Set("sourceAddress", q.sourceAddress)
+	}
+	if q.sourceInterface != "" {
+		query.Set("sourceInterface", q.sourceInterface)
+	}
+	if q.stat != "" {
+		query.Set("stat", q.stat)
+	}
+	setThroughputValues(query, q.tp)
+	urls, err := resolveSRV(srv, "/vector", query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	urls = filterURLs(ctx, urls, q.sources)
+	// sem limits the number of nodes that are asked for their vectors at once.
+	sem := make(chan struct{}, len(urls)+1)
+	if q.stat == statThroughput {
+		// The nodes take turns and test one destination after the other,
+		// so that the tests do not compete for the links of the destinations.
+		sem = make(chan struct{}, 1)
+		dsts, err := resolveSRV(q.target, "", "")
+		if err != nil {
+			return nil, err
+		}
+		timeout *= time.Duration(len(dsts) + 1)
+	}
+	//getting target urls: in case some nodes are down, we can still return a complete matrix with error entries
+	m := make(matrix, len(urls))
+	var wg sync.WaitGroup
+	for i := range urls {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			ctxT, cancelT := context.WithTimeout(ctx, timeout)
+			defer cancelT()
+			vec, err := getVectorFrom(ctxT, urls[i])
 			if err != nil {
 				errorCounter.Inc()
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
+				log.Printf("failed to get Vector from %s: %v\n", vec.Source, err)
 			}
-		}
-		urls, err := resolveSRV(srv, "/vector", "srv="+target)
+			m[i] = *vec
+		}(i)
+	}
+	wg.Wait()
+	if q.destinations != "" {
+		// Older nodes ignore the destinations and return all of them.
+		dsts, err := parseSelector(q.destinations)
 		if err != nil {
-			errorCounter.Inc()
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, err
+		}
+		m.filterDestinations(dsts)
+	}
+	// Destinations are identified before padding, which orders them by their identity.
+	m.LabelDestinations()
+	m.IdentifyDestinations()
+	// Pad matrix with dummies.
+	m = m.Pad()
+	return m, nil
+}
+
+// collectFromRequest collects the matrix that is described by the request.
+// If it fails, an error is written to the response and false is returned.
+func collectFromRequest(w http.ResponseWriter, r *http.Request, srv string, timeout time.Duration) (matrix, matrixQuery, bool) {
+	return collectStatFromRequest(w, r, srv, timeout, "")
+}
+
+// collectStatFromRequest is like collectFromRequest, but the given stat replaces the stat of the request if it is set.
+// The throughput stat must be given, so that the tests only run if a handler asks for them.
+func collectStatFromRequest(w http.ResponseWriter, r *http.Request, srv string, timeout time.Duration, stat string) (matrix, matrixQuery, bool) {
+	v := r.URL.Query()
+	if stat != "" {
+		v.Set("stat", stat)
+	} else if v.Get("stat") == statThroughput {
+		errorCounter.Inc()
+		http.Error(w, "the throughput is only tested via /throughput", http.StatusBadRequest)
+		return nil, matrixQuery{}, false
+	}
+	q, err := matrixQueryFromValues(v, srv)
+	if err != nil {
+		errorCounter.Inc()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, q, false
+	}
+	m, err := collectMatrix(r.Context(), srv, q, timeout)
+	if err != nil {
+		errorCounter.Inc()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, q, false
+	}
+	return m, q, true
+}
+
+func collectAllHandler(srv string, timeout time.Duration, asymmetryRatio float64, b *baseline) func(http.ResponseWriter, *http.Request) {
+	return matrixHandler(srv, timeout, asymmetryRatio, b, "")
+}
+
+// matrixHandler collects the matrix and writes it in the requested format.
+// If stat is set, it replaces the stat of the request.
+// If b is set, it learns from the square matrix.
+func matrixHandler(srv string, timeout time.Duration, asymmetryRatio float64, b *baseline, stat string) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m, q, ok := collectStatFromRequest(w, r, srv, timeout, stat)
+		if !ok {
 			return
 		}
-		//getting target urls: in case some nodes are down, we can still return a complete matrix with error entries
-		m := make(matrix, len(urls))
-		var wg sync.WaitGroup
-		for i := range urls {
-			wg.Add(1)
-			go func(i int) {
-				defer wg.Done()
-				ctxT, cancelT := context.WithTimeout(r.Context(), timeout)
-				defer cancelT()
-				vec, err := getVectorFrom(ctxT, urls[i])
-				if err != nil {
-					errorCounter.Inc()
-					log.Printf("failed to get Vector from %s: %v\n", vec.Source, err)
-				}
-				m[i] = *vec
-			}(i)
+		// The baseline is learned from snapshots of the square matrix.
+		if b != nil && q.target == srv {
+			b.Mark(m)
+		}
+		if key := r.URL.Query().Get("groupBy"); key != "" {
+			m = m.GroupBy(key)
+		}
+		if key := r.URL.Query().Get("sort"); key != "" {
+			m.SortByLabel(key)
 		}
-		wg.Wait()
-		// Pad matrix with dummies.
-		m = m.Pad()
 		s := ""
-		if q := r.URL.Query()["format"]; q != nil {
-			var f format
-			switch q[0] {
+		var f format
+		if fq := r.URL.Query()["format"]; fq != nil {
+			switch fq[0] {
 			case "fancy":
 				f = fancy
 			case "simple":
 				f = simple
+			case "asymmetry":
+				ratio, err := ratioFromRequest(r, asymmetryRatio)
+				if err != nil {
+					errorCounter.Inc()
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				w.Write([]byte(asymmetries(m.Asymmetries(ratio)).String()))
+				return
 			case "json":
 				j, err := json.Marshal(m)
 				if err != nil {
@@ -412,75 +968,14 @@ func collectAllHandler(srv string, timeout time.Duration) func(http.ResponseWrit
 				w.Write([]byte(j))
 				return
 			case "svg":
-				err := func() error {
-					g := graphviz.New()
-					graph, err := g.Graph()
-					if err != nil {
-						return err
-					}
-					defer func() {
-						if err := graph.Close(); err != nil {
-							log.Println(err)
-							return
-						}
-						g.Close()
-					}()
-					nodes := make([]*cgraph.Node, len(m))
-					for i, v := range m {
-						var err error
-						nodes[i], err = graph.CreateNode(ipOrHost(v.IP, v.Host))
-						if err != nil {
-							return err
-						}
-					}
-					var targetNodes []*cgraph.Node
-					// Only draw one set of nodes because the srv record references the adjacency service,
-					// not some other service.
-					if target == srv {
-						targetNodes = nodes
-					} else if len(m) > 0 {
-						targetNodes = make([]*cgraph.Node, len(m[0].Latencies))
-						for i, l := range m[0].Latencies {
-							targetNodes[i], err = graph.CreateNode(ipOrHost(l.IP, l.Host))
-							if err != nil {
-								return err
-							}
-							targetNodes[i] = targetNodes[i].SetStyle(cgraph.DashedNodeStyle)
-						}
-					} else {
-						return err
-					}
-					for i, n := range nodes {
-						for j, tn := range targetNodes {
-							e, err := graph.CreateEdge(fmt.Sprintf("%d:%d", i, j), n, tn)
-							if err != nil {
-								return err
-							}
-							e.SetLabel(fmt.Sprint(m[i].Latencies[j].Duration))
-							var es cgraph.EdgeStyle
-							switch d := m[i].Latencies[j].Duration; {
-							case d > 10000000000: // > 10s
-								es = cgraph.DottedEdgeStyle
-							case d > 100000000: // > 100ms
-								es = cgraph.DashedEdgeStyle
-							case d > 10000000: // > 10ms
-								es = cgraph.SolidEdgeStyle
-							default: // <= 10ms
-								es = cgraph.BoldEdgeStyle
-							}
-							e.SetStyle(es)
-						}
-					}
-					w.Header().Add("content-type", "image/svg+xml")
-					if err := g.Render(graph, "svg", w); err != nil {
-						return err
-					}
-					return nil
-				}()
+				o, err := svgOptionsFromRequest(r)
 				if err != nil {
-					log.Println(err)
-					http.Error(w, err.Error(), http.StatusInternalServerError)
+					errorCounter.Inc()
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
 				}
+				o.square = q.target == srv
+				writeSVG(w, m, o)
 				return
 			default:
 				f = standard
@@ -488,12 +983,35 @@ func collectAllHandler(srv string, timeout time.Duration) func(http.ResponseWrit
 
 			s = m.String(f)
 		} else {
+			f = standard
 			s = m.String(standard)
 		}
+		// The reachability of nodes is only meaningful if the matrix is square
+		// and not aggregated.
+		if f != simple && q.target == srv && r.URL.Query().Get("groupBy") == "" && len(m) > 0 {
+			s = m.Reachability().String() + "\n" + s
+		}
 		w.Write([]byte(s))
 	}
 }
 
+// tlsConfigFromFile returns a TLS config that trusts the CA certificates in the given file.
+// If the path is empty, the config is nil, so that the system roots are used.
+func tlsConfigFromFile(path string) (*tls.Config, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("failed to parse CA file %q: no certificates found", path)
+	}
+	return &tls.Config{RootCAs: roots}, nil
+}
+
 func metricsMiddleWare(path string, next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		requestCounter.With(prometheus.Labels{"method": r.Method, "handler": path}).Inc()
@@ -502,6 +1020,15 @@ func metricsMiddleWare(path string, next func(http.ResponseWriter, *http.Request
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		if err := diffCommand(os.Args[2:], os.Stdout); err != nil {
+			if err != flag.ErrHelp {
+				log.Println(err)
+			}
+			os.Exit(1)
+		}
+		return
+	}
 	flag.Parse()
 	if len(strings.SplitN(*srv, ".", 3)) != 3 {
 		log.Printf("%q is not a valid srv record name\n", *srv)
@@ -511,25 +1038,229 @@ func main() {
 	r.MustRegister(
 		errorCounter,
 		requestCounter,
+		anomalyGauge,
+		pathChangeCounter,
+		clockOffsetGauge,
 		collectors.NewGoCollector(),
 		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 	)
 
-	probers := []prober.Prober{prober.NewHTTPPingProber(http.DefaultClient), prober.NewHTTPProber(http.DefaultClient), prober.NewTCPProber(), &prober.NoProber{}}
+	tc, err := tlsConfigFromFile(*tlsCAFile)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	dp, err := prober.NewDNSProber(*dnsName, *dnsType, tc)
+	if err != nil {
+		log.Printf("failed to create DNS prober: %v\n", err)
+		return
+	}
+	source, err := newSource(*sourceAddr, *sourceIface)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	hc := prober.NewSourceClient()
+	probers := []prober.Prober{prober.NewTLSProber(tc), dp, prober.NewGRPCHealthProber(*grpcService, tc), prober.NewHTTPPingProber(hc), prober.NewHTTPProber(hc), prober.NewTCPProber(), &prober.NoProber{}}
+	// The probers are tried one after the other, but probers for other schemes are skipped,
+	// so the timeout is split between the chain of the scheme of the SRV record and one more probe.
+	n := len(prober.Chain(probers, srvScheme(*srv)))
 	if *timeoutProbe != time.Duration(0) {
-		*timeout = time.Duration(len(probers)+1) * *timeoutProbe
+		*timeout = time.Duration(n+1) * *timeoutProbe
 	} else {
-		*timeoutProbe = *timeout / time.Duration(len(probers)+1)
+		*timeoutProbe = *timeout / time.Duration(n+1)
 	}
 
 	log.Printf("using timeout %v, using probe timeout %v\n", *timeout, timeoutProbe)
 
+	l := &labeler{
+		static: staticLabels,
+		file:   *labelsFile,
+		node:   *k8sNode,
+	}
+	c := newVivaldi()
+	var s *sampler
+	if *sampleSize > 0 {
+		if *sampleIntv <= 0 {
+			log.Printf("the sample interval must be positive, got %v\n", *sampleIntv)
+			return
+		}
+		s = newSampler(*srv, probers, *timeoutProbe, *sampleSize, *sampleIntv, *sampleCov, c)
+		// Sampled probes are always sent from the default source.
+		go s.Run(prober.WithSource(context.Background(), source))
+		log.Printf("probing %d nodes every %v\n", *sampleSize, *sampleIntv)
+	}
+	if *snapshotCnt < 1 {
+		log.Printf("the snapshot count must be positive, got %d\n", *snapshotCnt)
+		return
+	}
+	ss := newSnapshotStore(*snapshotCnt)
+	collect := func(ctx context.Context) (matrix, error) {
+		return collectMatrix(ctx, *srv, matrixQuery{target: *srv}, *timeout)
+	}
+	a := newAlerter(alertRules{
+		latency:         *alertLatency,
+		latencyRounds:   *alertRounds,
+		unreachableFrom: *alertUnreach,
+		partition:       *alertPart,
+	}, webhooks, alertmanagers)
+	if a.rules.enabled() {
+		if *snapshotIntv <= 0 {
+			log.Println("alert rules are only evaluated for snapshots; set --snapshot-interval to evaluate them periodically")
+		}
+		ss.Subscribe(a.Evaluate)
+	}
+	if *baseAlpha <= 0 || *baseAlpha > 1 {
+		log.Printf("the baseline alpha must be in (0, 1], got %v\n", *baseAlpha)
+		return
+	}
+	b := newBaseline(*baseAlpha, *anomalyThr, *baseWarmup, anomalyGauge)
+	ss.Subscribe(b.Update)
+	if *snapshotIntv <= 0 {
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "baseline-alpha", "baseline-warmup", "anomaly-threshold":
+				log.Printf("the baseline is only learned from snapshots; set --snapshot-interval for --%s to take effect without snapshots on demand\n", f.Name)
+			}
+		})
+	}
+	if *snapshotIntv > 0 {
+		go ss.Run(context.Background(), *snapshotIntv, collect)
+		log.Printf("taking a snapshot every %v\n", *snapshotIntv)
+	}
+	node := *k8sNode
+	if node == "" {
+		var err error
+		if node, err = os.Hostname(); err != nil {
+			log.Printf("failed to get hostname: %v\n", err)
+		}
+	}
+	caps := []string{capDestinations, capLabels, capCoordinates}
+	if s != nil {
+		caps = append(caps, capSampling)
+	}
+	if *grpcAddr != "" {
+		caps = append(caps, capGRPC)
+	}
+	overlay, err := addressFromFlag(*overlayAddr)
+	if err != nil {
+		log.Printf("failed to get overlay address: %v\n", err)
+		return
+	}
+	underlay, err := addressFromFlag(*underlayAddr)
+	if err != nil {
+		log.Printf("failed to get underlay address: %v\n", err)
+		return
+	}
+	if overlay != "" || underlay != "" {
+		caps = append(caps, capDual)
+	}
+	vr := &vectorer{
+		srv:          *srv,
+		probers:      probers,
+		timeout:      *timeoutProbe,
+		l:            l,
+		c:            c,
+		s:            s,
+		node:         node,
+		capabilities: caps,
+		source:       source,
+		overlay:      overlay,
+		underlay:     underlay,
+		ab:           newAddressBook(),
+	}
+	if *wgDevice != "" {
+		wc, err := wgctrl.New()
+		if err != nil {
+			log.Printf("failed to open WireGuard client: %v\n", err)
+			return
+		}
+		defer wc.Close()
+		vr.wg = newWireGuard(wc, *wgDevice, *wgStale)
+		vr.capabilities = append(vr.capabilities, capWireGuard)
+	}
+	mp, err := prober.NewMTUProber(*mtuMethod, *mtuMax, hc)
+	if err != nil {
+		log.Printf("failed to create MTU prober: %v\n", err)
+		return
+	}
+	// The MTU search takes many probes and runs alongside the latency probes,
+	// so it gets the timeout of the vector request minus one probe timeout to answer in time.
+	vr.mtu, vr.mtuExpected, vr.mtuTimeout = mp, *mtuExpected, *timeout-*timeoutProbe
+	vr.capabilities = append(vr.capabilities, capMTU)
+	// The overlay and underlay addresses are probed alongside the latencies as well.
+	vr.dualTimeout = *timeout - *timeoutProbe
+	tpDefaults := prober.ThroughputOptions{Bytes: *tpBytes, Streams: *tpStreams, Direction: *tpDirection}
+	if *tpBytes < 1 || *tpStreams < 1 || *tpDirection == "" {
+		log.Printf("the throughput bytes and streams must be positive and the direction must be set, got %d, %d and %q\n", *tpBytes, *tpStreams, *tpDirection)
+		return
+	}
+	if err := checkThroughputOptions(tpDefaults); err != nil {
+		log.Printf("invalid throughput flags: %v\n", err)
+		return
+	}
+	vr.tp, vr.tpDefaults, vr.tpTimeout = prober.NewThroughputProber(hc), tpDefaults, *tpTimeout
+	vr.capabilities = append(vr.capabilities, capThroughput)
+	if vr.clock, err = prober.NewClockProber(*clockSamples, hc); err != nil {
+		log.Printf("failed to create clock prober: %v\n", err)
+		return
+	}
+	vr.offsets = newClockOffsets(clockOffsetGauge)
+	vr.capabilities = append(vr.capabilities, capClock)
+	tp, err := prober.NewTracerouteProber(*traceMethod, *traceMaxHops)
+	if err != nil {
+		log.Printf("failed to create traceroute prober: %v\n", err)
+		return
+	}
+	tr := newTracer(tp, node, *timeout)
+	if *traceIntv > 0 {
+		go tr.Run(prober.WithSource(context.Background(), source), *srv, *traceIntv)
+		log.Printf("tracing the paths to all nodes every %v\n", *traceIntv)
+	}
+
 	m := http.NewServeMux()
 	mm := http.NewServeMux()
 	mm.Handle("/metrics", promhttp.HandlerFor(r, promhttp.HandlerOpts{}))
-	m.HandleFunc("/vector", metricsMiddleWare("/vector", vectorHandler(*srv, probers, *timeoutProbe)))
+	m.HandleFunc("/vector", metricsMiddleWare("/vector", vectorHandler(vr)))
+	m.HandleFunc("/coordinate", metricsMiddleWare("/coordinate", coordinateHandler(c)))
+	m.HandleFunc("/coordinates", metricsMiddleWare("/coordinates", coordinatesHandler(*srv, *timeout)))
+	m.HandleFunc("/labels", metricsMiddleWare("/labels", labelsHandler(l)))
 	m.HandleFunc("/ping", metricsMiddleWare("/ping", pingHandler))
-	m.HandleFunc("/", metricsMiddleWare("/", collectAllHandler(*srv, *timeout)))
+	m.HandleFunc("/info", metricsMiddleWare("/info", infoHandler(vr)))
+	m.HandleFunc("/echo", metricsMiddleWare("/echo", echoHandler))
+	m.HandleFunc("/throughput", metricsMiddleWare("/throughput", throughputHandler(*srv, *timeout+*tpTimeout, *asymRatio)))
+	m.HandleFunc(prober.ThroughputPath, metricsMiddleWare(prober.ThroughputPath, throughputDataHandler))
+	m.HandleFunc(prober.TimePath, metricsMiddleWare(prober.TimePath, timeHandler))
+	m.HandleFunc("/path", metricsMiddleWare("/path", pathHandler(vr, tr, *timeout)))
+	m.HandleFunc("/", metricsMiddleWare("/", collectAllHandler(*srv, *timeout, *asymRatio, b)))
+	m.HandleFunc("/analysis/reachability", metricsMiddleWare("/analysis/reachability", reachabilityHandler(*srv, *timeout)))
+	m.HandleFunc("/analysis/paths", metricsMiddleWare("/analysis/paths", pathsHandler(*srv, *timeout, *margin)))
+	m.HandleFunc("/analysis/asymmetry", metricsMiddleWare("/analysis/asymmetry", asymmetryHandler(*srv, *timeout, *asymRatio)))
+	m.HandleFunc("/analysis/clocks", metricsMiddleWare("/analysis/clocks", clocksHandler(*srv, *timeout, *clockSkew)))
+	m.HandleFunc("/snapshots", metricsMiddleWare("/snapshots", snapshotsHandler(ss, collect)))
+	m.HandleFunc("/snapshots/", metricsMiddleWare("/snapshots/", snapshotsHandler(ss, collect)))
+	m.HandleFunc("/anomalies", metricsMiddleWare("/anomalies", anomaliesHandler(b)))
+	m.HandleFunc("/alerts", metricsMiddleWare("/alerts", alertsHandler(a)))
+	m.HandleFunc("/diff", metricsMiddleWare("/diff", diffHandler(ss, *diffThresh)))
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Printf("failed to listen on gRPC address: %v\n", err)
+			return
+		}
+		gs := grpc.NewServer(grpc.UnaryInterceptor(grpcUnaryMetrics), grpc.StreamInterceptor(grpcStreamMetrics))
+		api.RegisterAdjacencyServer(gs, &grpcServer{vr: vr, timeout: *timeout, tpTimeout: *tpTimeout, ss: ss})
+		go gs.Serve(lis)
+		log.Printf("serving gRPC on %s\n", *grpcAddr)
+	}
+	if *useFanout {
+		_, port, err := net.SplitHostPort(*grpcAddr)
+		if err != nil {
+			log.Printf("grpc-fanout requires a valid grpc-address: %v\n", err)
+			return
+		}
+		fanout = newGRPCFanout(port)
+	}
 	go http.ListenAndServe(*metricsAddr, mm)
 	log.Printf("listening on %s\n", *listenAddr)
 	log.Fatal(http.ListenAndServe(*listenAddr, m))
@@ -1,10 +1,21 @@
 package main
 
 import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
 	"github.com/kylelemons/godebug/pretty"
+	"golang.org/x/net/dns/dnsmessage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/kilo-io/adjacency_service/pkg/prober"
 )
 
 func TestPad(t *testing.T) {
@@ -165,6 +176,44 @@ func TestPad(t *testing.T) {
 				},
 			},
 		},
+		{
+			// Named nodes are ordered by their names rather than their addresses.
+			m: matrix{
+				Vector{
+					Source: "http://10.0.0.1:3000/vector",
+					Node:   "b",
+					Latencies: []Latency{
+						{Destination: "http://10.0.0.1:3000", Node: "b", Ok: true},
+						{Destination: "http://10.0.0.2:3000", Node: "a", Ok: true},
+					},
+				},
+				Vector{
+					Source: "http://10.0.0.2:3000/vector",
+					Node:   "a",
+					Latencies: []Latency{
+						{Destination: "http://10.0.0.1:3000", Node: "b", Ok: true},
+					},
+				},
+			},
+			em: matrix{
+				Vector{
+					Source: "http://10.0.0.2:3000/vector",
+					Node:   "a",
+					Latencies: []Latency{
+						{Destination: "dummy"},
+						{Destination: "http://10.0.0.1:3000", Node: "b", Ok: true},
+					},
+				},
+				Vector{
+					Source: "http://10.0.0.1:3000/vector",
+					Node:   "b",
+					Latencies: []Latency{
+						{Destination: "http://10.0.0.2:3000", Node: "a", Ok: true},
+						{Destination: "http://10.0.0.1:3000", Node: "b", Ok: true},
+					},
+				},
+			},
+		},
 	} {
 
 		if diff := pretty.Compare(m.m.Pad(), m.em); diff != "" {
@@ -172,3 +221,116 @@ func TestPad(t *testing.T) {
 		}
 	}
 }
+
+func TestSRVScheme(t *testing.T) {
+	for i, tc := range []struct {
+		srv    string
+		scheme string
+	}{
+		{srv: "_service._tcp.example.com", scheme: "http"},
+		{srv: "_http._tcp.example.com", scheme: "http"},
+		{srv: "_https._tcp.example.com", scheme: "https"},
+		{srv: "_dns._udp.kube-dns.kube-system.svc.cluster.local", scheme: "dns"},
+		{srv: "_dns._tcp.kube-dns.kube-system.svc.cluster.local", scheme: "dns+tcp"},
+		{srv: "_domain-s._tcp.example.com", scheme: "dns+tls"},
+		{srv: "_grpc._tcp.example.com", scheme: "grpc"},
+		{srv: "example.com", scheme: "http"},
+	} {
+		if s := srvScheme(tc.srv); s != tc.scheme {
+			t.Errorf("test case %d: expected scheme %q, got %q", i, tc.scheme, s)
+		}
+	}
+}
+
+// serveServFail answers all DNS queries over UDP with SERVFAIL
+// and accepts TCP connections on the same port, like a broken DNS server.
+// It returns the address.
+func serveServFail(t *testing.T) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	pc, err := net.ListenPacket("udp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var p dnsmessage.Parser
+			h, err := p.Start(buf[:n])
+			if err != nil {
+				continue
+			}
+			b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: h.ID, Response: true, RCode: dnsmessage.RCodeServerFailure})
+			if resp, err := b.Finish(); err == nil {
+				pc.WriteTo(resp, addr)
+			}
+		}
+	}()
+	return l.Addr().String(), func() {
+		l.Close()
+		pc.Close()
+	}
+}
+
+func TestTimeHTTPRequestChain(t *testing.T) {
+	dp, err := prober.NewDNSProber("example.com", "A", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hc := prober.NewSourceClient()
+	probers := []prober.Prober{prober.NewTLSProber(nil), dp, prober.NewGRPCHealthProber("", nil), prober.NewHTTPPingProber(hc), prober.NewHTTPProber(hc), prober.NewTCPProber(), &prober.NoProber{}}
+	// This server does not serve /ping, so the HTTP prober after the HTTP ping prober succeeds.
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+	// The certificate of this server is not trusted, but the TCP prober could still connect to it.
+	untrusted := httptest.NewTLSServer(http.NotFoundHandler())
+	defer untrusted.Close()
+	dns, stopDNS := serveServFail(t)
+	defer stopDNS()
+	// This gRPC server is draining.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := health.NewServer()
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	gs := grpc.NewServer()
+	healthpb.RegisterHealthServer(gs, hs)
+	go gs.Serve(lis)
+	defer gs.Stop()
+	for _, tc := range []struct {
+		name   string
+		url    string
+		ok     bool
+		prober string
+	}{
+		{name: "http fallback", url: plain.URL, ok: true, prober: "http-prober"},
+		{name: "bad certificate", url: untrusted.URL, prober: "tls-prober"},
+		{name: "SERVFAIL", url: prober.SchemeDNS + "://" + dns, prober: "dns-prober"},
+		{name: "NOT_SERVING", url: prober.SchemeGRPC + "://" + lis.Addr().String(), prober: "grpc-health-prober"},
+	} {
+		u, err := url.Parse(tc.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := timeHTTPRequest(context.Background(), probers, u, time.Second)
+		if l.Ok != tc.ok || l.Prober != tc.prober {
+			t.Errorf("test case %q: expected ok %t with %s, got %t with %s", tc.name, tc.ok, tc.prober, l.Ok, l.Prober)
+		}
+	}
+}
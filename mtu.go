@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/kilo-io/adjacency_service/pkg/prober"
+)
+
+const (
+	// statMTU is the value of the stat query parameter that makes the nodes probe the path MTU.
+	statMTU = "mtu"
+	// maxEchoSize is the size of the largest body that the echo endpoint returns.
+	maxEchoSize = 64 << 10
+	// belowMTUColor is the color of edges of pairs whose MTU is below the expected MTU in the SVG output.
+	belowMTUColor = "red"
+)
+
+// MTU is the path MTU from a node to a destination.
+type MTU struct {
+	// Size is the path MTU or, with the http method, the size of the largest body that was echoed.
+	Size   int    `json:"size"`
+	Method string `json:"method"`
+	Ok     bool   `json:"ok"`
+	// Expected is the MTU that the source expects and Below is true if the size is smaller.
+	Expected int  `json:"expected,omitempty"`
+	Below    bool `json:"below,omitempty"`
+}
+
+func (m *MTU) String() string {
+	if !m.Ok {
+		return "-"
+	}
+	if m.Below {
+		return fmt.Sprintf("%d (<%d)", m.Size, m.Expected)
+	}
+	return strconv.Itoa(m.Size)
+}
+
+// parseStat checks the value of the stat query parameter.
+// The empty stat means latencies.
+func parseStat(s string) (string, error) {
+	switch s {
+	case "", statMTU, statThroughput, statClock:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown stat %q", s)
+}
+
+// vectorOptions are the optional measurements of a vector.
+type vectorOptions struct {
+	// dual probes the overlay and underlay addresses of the destinations.
+	dual bool
+	// mtu probes the path MTU to the destinations.
+	mtu bool
+	// clock estimates the clocks of the destinations and the one-way delays to them.
+	clock bool
+	// throughput tests the throughput to the destinations with the options of tp.
+	throughput bool
+	tp         prober.ThroughputOptions
+}
+
+func vectorOptionsFromValues(q url.Values) (vectorOptions, error) {
+	var o vectorOptions
+	var err error
+	if o.dual, err = dualFromValues(q); err != nil {
+		return o, err
+	}
+	stat, err := parseStat(q.Get("stat"))
+	if err != nil {
+		return o, err
+	}
+	o.mtu = stat == statMTU
+	o.clock = stat == statClock
+	if o.throughput = stat == statThroughput; o.throughput {
+		if o.tp, err = throughputOptionsFromValues(q); err != nil {
+			return o, err
+		}
+	}
+	return o, nil
+}
+
+// probeMTU probes the path MTU to the given URLs concurrently and returns the MTUs by destination.
+func (vr *vectorer) probeMTU(ctx context.Context, urls []*url.URL) map[string]*MTU {
+	mtus := make(map[string]*MTU, len(urls))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, u := range urls {
+		wg.Add(1)
+		go func(u *url.URL) {
+			defer wg.Done()
+			ctxT, cancelT := context.WithTimeout(ctx, vr.mtuTimeout)
+			defer cancelT()
+			m := &MTU{Method: vr.mtu.Method(), Expected: vr.mtuExpected}
+			size, err := vr.mtu.MTU(ctxT, *u)
+			if err != nil {
+				log.Printf("failed to probe MTU to %s: %v\n", u.Host, err)
+				errorCounter.Inc()
+			} else {
+				m.Size, m.Ok, m.Below = size, true, size < vr.mtuExpected
+			}
+			mu.Lock()
+			mtus[u.String()] = m
+			mu.Unlock()
+		}(u)
+	}
+	wg.Wait()
+	return mtus
+}
+
+// echoHandler returns the body of the request, so that nodes can probe the path with large payloads.
+func echoHandler(w http.ResponseWriter, r *http.Request) {
+	// HTTP/1.x handlers must read the body before they write the response.
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxEchoSize))
+	if err != nil {
+		errorCounter.Inc()
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(body)
+}
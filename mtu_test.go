@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+
+	"github.com/kilo-io/adjacency_service/pkg/prober"
+)
+
+func TestMTUString(t *testing.T) {
+	for _, tc := range []struct {
+		m        MTU
+		expected string
+	}{
+		{m: MTU{Size: 1420, Ok: true, Expected: 1420}, expected: "1420"},
+		{m: MTU{Size: 1380, Ok: true, Expected: 1420, Below: true}, expected: "1380 (<1420)"},
+		{m: MTU{Expected: 1420}, expected: "-"},
+	} {
+		if s := tc.m.String(); s != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, s)
+		}
+		if s := (Latency{MTU: &tc.m, Ok: true, Duration: time.Millisecond}).String(); s != tc.expected {
+			t.Errorf("expected cell %q, got %q", tc.expected, s)
+		}
+	}
+}
+
+func TestVectorOptionsFromValues(t *testing.T) {
+	for _, tc := range []struct {
+		q        string
+		expected vectorOptions
+		err      bool
+	}{
+		{q: ""},
+		{q: "stat=mtu", expected: vectorOptions{mtu: true}},
+		{q: "stat=mtu&dual=true", expected: vectorOptions{dual: true, mtu: true}},
+		{q: "stat=jitter", err: true},
+		{q: "dual=maybe", err: true},
+	} {
+		q, err := url.ParseQuery(tc.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		o, err := vectorOptionsFromValues(q)
+		if (err != nil) != tc.err {
+			t.Errorf("query %q: expected error %t, got %v", tc.q, tc.err, err)
+		}
+		if o != tc.expected {
+			t.Errorf("query %q: expected %+v, got %+v", tc.q, tc.expected, o)
+		}
+	}
+	if _, err := matrixQueryFromValues(url.Values{"stat": []string{"jitter"}}, "_service._tcp.example.com"); err == nil {
+		t.Error("expected an error for an unknown stat")
+	}
+}
+
+func TestEchoHandler(t *testing.T) {
+	body := strings.Repeat("a", 2000)
+	w := httptest.NewRecorder()
+	echoHandler(w, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(body)))
+	if w.Code != http.StatusOK || w.Body.String() != body {
+		t.Errorf("expected the body to be echoed, got status %d and %d bytes", w.Code, w.Body.Len())
+	}
+	w = httptest.NewRecorder()
+	echoHandler(w, httptest.NewRequest(http.MethodPost, "/echo", bytes.NewReader(make([]byte, maxEchoSize+1))))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d for a large body, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
+
+func TestProbeMTU(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(echoHandler))
+	defer s.Close()
+	// This server drops bodies larger than 1000 bytes, like a path with a broken MTU.
+	broken := httptest.NewServer(http.MaxBytesHandler(http.HandlerFunc(echoHandler), 1000))
+	defer broken.Close()
+	// This server does not run the adjacency service.
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+
+	mp, err := prober.NewMTUProber(prober.MTUHTTP, 1500, prober.NewSourceClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	vr := &vectorer{mtu: mp, mtuExpected: 1420, mtuTimeout: 5 * time.Second}
+	var urls []*url.URL
+	for _, s := range []*httptest.Server{s, broken, plain} {
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls = append(urls, u)
+	}
+	mtus := vr.probeMTU(context.Background(), urls)
+	expected := map[string]*MTU{
+		s.URL:      {Size: 1500, Method: prober.MTUHTTP, Ok: true, Expected: 1420},
+		broken.URL: {Size: 1000, Method: prober.MTUHTTP, Ok: true, Expected: 1420, Below: true},
+		plain.URL:  {Method: prober.MTUHTTP, Expected: 1420},
+	}
+	if diff := pretty.Compare(mtus, expected); diff != "" {
+		t.Errorf("got diff:\n%s", diff)
+	}
+}
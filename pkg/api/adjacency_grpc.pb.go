@@ -0,0 +1,217 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: adjacency.proto
+
+package api
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Adjacency_GetVector_FullMethodName   = "/adjacency.v1.Adjacency/GetVector"
+	Adjacency_GetMatrix_FullMethodName   = "/adjacency.v1.Adjacency/GetMatrix"
+	Adjacency_WatchMatrix_FullMethodName = "/adjacency.v1.Adjacency/WatchMatrix"
+)
+
+// AdjacencyClient is the client API for Adjacency service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AdjacencyClient interface {
+	// GetVector probes the destinations and returns the latencies to them.
+	GetVector(ctx context.Context, in *GetVectorRequest, opts ...grpc.CallOption) (*Vector, error)
+	// GetMatrix collects the vectors of all nodes.
+	GetMatrix(ctx context.Context, in *GetMatrixRequest, opts ...grpc.CallOption) (*Matrix, error)
+	// WatchMatrix streams every snapshot of the square matrix, starting with the latest one.
+	WatchMatrix(ctx context.Context, in *WatchMatrixRequest, opts ...grpc.CallOption) (Adjacency_WatchMatrixClient, error)
+}
+
+type adjacencyClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdjacencyClient(cc grpc.ClientConnInterface) AdjacencyClient {
+	return &adjacencyClient{cc}
+}
+
+func (c *adjacencyClient) GetVector(ctx context.Context, in *GetVectorRequest, opts ...grpc.CallOption) (*Vector, error) {
+	out := new(Vector)
+	err := c.cc.Invoke(ctx, Adjacency_GetVector_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adjacencyClient) GetMatrix(ctx context.Context, in *GetMatrixRequest, opts ...grpc.CallOption) (*Matrix, error) {
+	out := new(Matrix)
+	err := c.cc.Invoke(ctx, Adjacency_GetMatrix_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adjacencyClient) WatchMatrix(ctx context.Context, in *WatchMatrixRequest, opts ...grpc.CallOption) (Adjacency_WatchMatrixClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Adjacency_ServiceDesc.Streams[0], Adjacency_WatchMatrix_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &adjacencyWatchMatrixClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Adjacency_WatchMatrixClient interface {
+	Recv() (*Snapshot, error)
+	grpc.ClientStream
+}
+
+type adjacencyWatchMatrixClient struct {
+	grpc.ClientStream
+}
+
+func (x *adjacencyWatchMatrixClient) Recv() (*Snapshot, error) {
+	m := new(Snapshot)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AdjacencyServer is the server API for Adjacency service.
+// All implementations must embed UnimplementedAdjacencyServer
+// for forward compatibility
+type AdjacencyServer interface {
+	// GetVector probes the destinations and returns the latencies to them.
+	GetVector(context.Context, *GetVectorRequest) (*Vector, error)
+	// GetMatrix collects the vectors of all nodes.
+	GetMatrix(context.Context, *GetMatrixRequest) (*Matrix, error)
+	// WatchMatrix streams every snapshot of the square matrix, starting with the latest one.
+	WatchMatrix(*WatchMatrixRequest, Adjacency_WatchMatrixServer) error
+	mustEmbedUnimplementedAdjacencyServer()
+}
+
+// UnimplementedAdjacencyServer must be embedded to have forward compatible implementations.
+type UnimplementedAdjacencyServer struct {
+}
+
+func (UnimplementedAdjacencyServer) GetVector(context.Context, *GetVectorRequest) (*Vector, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVector not implemented")
+}
+func (UnimplementedAdjacencyServer) GetMatrix(context.Context, *GetMatrixRequest) (*Matrix, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMatrix not implemented")
+}
+func (UnimplementedAdjacencyServer) WatchMatrix(*WatchMatrixRequest, Adjacency_WatchMatrixServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchMatrix not implemented")
+}
+func (UnimplementedAdjacencyServer) mustEmbedUnimplementedAdjacencyServer() {}
+
+// UnsafeAdjacencyServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdjacencyServer will
+// result in compilation errors.
+type UnsafeAdjacencyServer interface {
+	mustEmbedUnimplementedAdjacencyServer()
+}
+
+func RegisterAdjacencyServer(s grpc.ServiceRegistrar, srv AdjacencyServer) {
+	s.RegisterService(&Adjacency_ServiceDesc, srv)
+}
+
+func _Adjacency_GetVector_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVectorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdjacencyServer).GetVector(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Adjacency_GetVector_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdjacencyServer).GetVector(ctx, req.(*GetVectorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Adjacency_GetMatrix_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMatrixRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdjacencyServer).GetMatrix(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Adjacency_GetMatrix_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdjacencyServer).GetMatrix(ctx, req.(*GetMatrixRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Adjacency_WatchMatrix_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchMatrixRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AdjacencyServer).WatchMatrix(m, &adjacencyWatchMatrixServer{stream})
+}
+
+type Adjacency_WatchMatrixServer interface {
+	Send(*Snapshot) error
+	grpc.ServerStream
+}
+
+type adjacencyWatchMatrixServer struct {
+	grpc.ServerStream
+}
+
+func (x *adjacencyWatchMatrixServer) Send(m *Snapshot) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Adjacency_ServiceDesc is the grpc.ServiceDesc for Adjacency service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Adjacency_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "adjacency.v1.Adjacency",
+	HandlerType: (*AdjacencyServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetVector",
+			Handler:    _Adjacency_GetVector_Handler,
+		},
+		{
+			MethodName: "GetMatrix",
+			Handler:    _Adjacency_GetMatrix_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchMatrix",
+			Handler:       _Adjacency_WatchMatrix_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "adjacency.proto",
+}
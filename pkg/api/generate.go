@@ -0,0 +1,4 @@
+// Package api contains the protobuf schema and the gRPC service of the adjacency service.
+package api
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative adjacency.proto
@@ -0,0 +1,178 @@
+package prober
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// TimePath is the path of the endpoint that answers with the times at which it received and answered the request.
+const TimePath = "/time"
+
+// TimeResponse is the response of the time endpoint.
+// The times are given in nanoseconds since the Unix epoch.
+type TimeResponse struct {
+	Receive  int64 `json:"receive"`
+	Transmit int64 `json:"transmit"`
+}
+
+// An Exchange is an NTP-style exchange of timestamps with another node.
+// Send and Arrive are read from the clock of the prober, Receive and Transmit from the clock of the other node.
+type Exchange struct {
+	Send, Receive, Transmit, Arrive time.Time
+}
+
+// Offset returns the offset of the clock of the other node from the clock of the prober,
+// assuming that the request and the response took equally long.
+func (e Exchange) Offset() time.Duration {
+	return (e.Receive.Sub(e.Send) + e.Transmit.Sub(e.Arrive)) / 2
+}
+
+// Delay returns the round-trip time of the exchange without the time the other node took to answer.
+func (e Exchange) Delay() time.Duration {
+	return e.Arrive.Sub(e.Send) - e.Transmit.Sub(e.Receive)
+}
+
+// ClockResult is the estimate of the clock of another node.
+type ClockResult struct {
+	// Offset is the offset of the clock of the other node from the clock of the prober.
+	// It is within ±Bound of the true offset.
+	Offset time.Duration
+	Bound  time.Duration
+	// Delay is the smallest round-trip time of the exchanges.
+	Delay time.Duration
+	// Forward and Backward are the median one-way delays to and from the other node,
+	// corrected by the offset, so they are also within ±Bound.
+	Forward, Backward time.Duration
+	Samples           int
+}
+
+// estimateClock estimates the clock of another node from the given exchanges.
+// Like NTP, it takes the offset from the exchange with the smallest delay,
+// because that exchange was delayed least by queues, which are rarely symmetric.
+// The one-way delays of all exchanges are corrected by that offset,
+// so that asymmetric delays show in the medians.
+func estimateClock(es []Exchange) (ClockResult, error) {
+	if len(es) == 0 {
+		return ClockResult{}, errors.New("no exchanges")
+	}
+	best := es[0]
+	for _, e := range es[1:] {
+		if e.Delay() < best.Delay() {
+			best = e
+		}
+	}
+	r := ClockResult{
+		Offset:  best.Offset(),
+		Bound:   best.Delay() / 2,
+		Delay:   best.Delay(),
+		Samples: len(es),
+	}
+	forward := make([]time.Duration, len(es))
+	backward := make([]time.Duration, len(es))
+	for i, e := range es {
+		forward[i] = e.Receive.Sub(e.Send) - r.Offset
+		backward[i] = e.Arrive.Sub(e.Transmit) + r.Offset
+	}
+	r.Forward, r.Backward = medianDuration(forward), medianDuration(backward)
+	return r, nil
+}
+
+// medianDuration returns the median of the durations; it sorts them.
+func medianDuration(ds []time.Duration) time.Duration {
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+	if len(ds)%2 == 1 {
+		return ds[len(ds)/2]
+	}
+	return (ds[len(ds)/2-1] + ds[len(ds)/2]) / 2
+}
+
+// ClockProber estimates the offset of the clock of another node and the one-way delays to it
+// with exchanges of timestamps with the time endpoint of its URL.
+// It does not implement the Prober interface, because it measures more than a duration.
+type ClockProber struct {
+	samples int
+	c       Client
+}
+
+// NewClockProber returns a new ClockProber that makes the given number of exchanges with the given client.
+// It is safe to use concurrently.
+func NewClockProber(samples int, c Client) (*ClockProber, error) {
+	if samples < 1 {
+		return nil, fmt.Errorf("the number of samples must be positive, got %d", samples)
+	}
+	return &ClockProber{samples: samples, c: c}, nil
+}
+
+// Clock estimates the clock of the host of the URL from exchanges one after the other.
+// Failed exchanges are skipped; it only fails if all exchanges failed.
+// The requests are sent from the source of the context.
+func (p *ClockProber) Clock(ctx context.Context, u url.URL) (ClockResult, error) {
+	u.Path = TimePath
+	u.RawQuery = ""
+	var es []Exchange
+	var err error
+	for i := 0; i < p.samples && ctx.Err() == nil; i++ {
+		var e Exchange
+		if e, err = p.exchange(ctx, u); err == nil {
+			es = append(es, e)
+		}
+	}
+	if len(es) == 0 {
+		if err == nil {
+			err = ctx.Err()
+		}
+		return ClockResult{}, fmt.Errorf("failed to exchange timestamps: %w", err)
+	}
+	return estimateClock(es)
+}
+
+// exchange requests the timestamps of the URL.
+// The send and arrive times are taken when the request was written and the response started to arrive,
+// so that connecting to the node does not count.
+func (p *ClockProber) exchange(ctx context.Context, u url.URL) (Exchange, error) {
+	var e Exchange
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			e.Send = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			e.Arrive = time.Now()
+		},
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return e, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := p.c.Do(req)
+	if err != nil {
+		return e, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return e, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return e, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	var tr TimeResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return e, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if tr.Receive == 0 || tr.Transmit < tr.Receive {
+		return e, fmt.Errorf("invalid timestamps %d and %d", tr.Receive, tr.Transmit)
+	}
+	if e.Send.IsZero() || e.Arrive.IsZero() {
+		return e, errors.New("failed to trace the request")
+	}
+	e.Receive, e.Transmit = time.Unix(0, tr.Receive), time.Unix(0, tr.Transmit)
+	return e, nil
+}
@@ -0,0 +1,113 @@
+package prober
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestEstimateClock(t *testing.T) {
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	// exchange returns an exchange with a node whose clock is ahead by offset,
+	// which takes forward and backward for the request and the response and 1ms to answer.
+	exchange := func(at, offset, forward, backward time.Duration) Exchange {
+		send := start.Add(at)
+		receive := send.Add(forward).Add(offset)
+		transmit := receive.Add(time.Millisecond)
+		return Exchange{Send: send, Receive: receive, Transmit: transmit, Arrive: transmit.Add(-offset).Add(backward)}
+	}
+	for _, tc := range []struct {
+		name     string
+		es       []Exchange
+		expected ClockResult
+		err      bool
+	}{
+		{
+			name:     "symmetric",
+			es:       []Exchange{exchange(0, 10*time.Millisecond, 2*time.Millisecond, 2*time.Millisecond)},
+			expected: ClockResult{Offset: 10 * time.Millisecond, Bound: 2 * time.Millisecond, Delay: 4 * time.Millisecond, Forward: 2 * time.Millisecond, Backward: 2 * time.Millisecond, Samples: 1},
+		},
+		{
+			name: "node behind",
+			es:   []Exchange{exchange(0, -time.Second, time.Millisecond, time.Millisecond)},
+			expected: ClockResult{
+				Offset: -time.Second, Bound: time.Millisecond, Delay: 2 * time.Millisecond, Forward: time.Millisecond, Backward: time.Millisecond, Samples: 1,
+			},
+		},
+		{
+			// The exchange with the smallest delay gives the offset
+			// and the queue on the way to the node shows in the forward delay.
+			name: "queued requests",
+			es: []Exchange{
+				exchange(0, 10*time.Millisecond, 7*time.Millisecond, time.Millisecond),
+				exchange(time.Second, 10*time.Millisecond, time.Millisecond, time.Millisecond),
+				exchange(2*time.Second, 10*time.Millisecond, 9*time.Millisecond, time.Millisecond),
+			},
+			expected: ClockResult{Offset: 10 * time.Millisecond, Bound: time.Millisecond, Delay: 2 * time.Millisecond, Forward: 7 * time.Millisecond, Backward: time.Millisecond, Samples: 3},
+		},
+		{
+			name: "no exchanges",
+			err:  true,
+		},
+	} {
+		r, err := estimateClock(tc.es)
+		if (err != nil) != tc.err {
+			t.Errorf("test case %q: expected error %t, got %v", tc.name, tc.err, err)
+		}
+		if diff := pretty.Compare(r, tc.expected); diff != "" {
+			t.Errorf("test case %q: got diff:\n%s", tc.name, diff)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	// The clock of this server is an hour ahead.
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != TimePath {
+			http.NotFound(w, r)
+			return
+		}
+		receive := time.Now().Add(time.Hour).UnixNano()
+		json.NewEncoder(w).Encode(TimeResponse{Receive: receive, Transmit: time.Now().Add(time.Hour).UnixNano()})
+	}))
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewClockProber(5, NewSourceClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r, err := p.Clock(ctx, *u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := r.Offset - time.Hour; d < -r.Bound || d > r.Bound {
+		t.Errorf("expected an offset of 1h±%v, got %v", r.Bound, r.Offset)
+	}
+	if r.Samples != 5 || r.Delay < 0 {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	// This server does not serve the time endpoint.
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+	if u, err = url.Parse(plain.URL); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Clock(ctx, *u); err == nil {
+		t.Error("expected an error for a node without the time endpoint")
+	}
+	if _, err := NewClockProber(0, nil); err == nil {
+		t.Error("expected an error for no samples")
+	}
+}
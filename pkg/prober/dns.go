@@ -0,0 +1,238 @@
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// The schemes of the URLs of DNS servers by transport.
+const (
+	SchemeDNS    = "dns"
+	SchemeDNSTCP = "dns+tcp"
+	SchemeDNSTLS = "dns+tls"
+)
+
+// DNSDetails describe the response of a DNS server.
+type DNSDetails struct {
+	Transport string `json:"transport"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Rcode     string `json:"rcode"`
+	Answers   int    `json:"answers"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+var dnsTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"NS":    dnsmessage.TypeNS,
+	"PTR":   dnsmessage.TypePTR,
+	"SOA":   dnsmessage.TypeSOA,
+	"SRV":   dnsmessage.TypeSRV,
+	"TXT":   dnsmessage.TypeTXT,
+}
+
+var dnsRcodes = map[dnsmessage.RCode]string{
+	dnsmessage.RCodeSuccess:        "NOERROR",
+	dnsmessage.RCodeFormatError:    "FORMERR",
+	dnsmessage.RCodeServerFailure:  "SERVFAIL",
+	dnsmessage.RCodeNameError:      "NXDOMAIN",
+	dnsmessage.RCodeNotImplemented: "NOTIMP",
+	dnsmessage.RCodeRefused:        "REFUSED",
+}
+
+func rcodeName(r dnsmessage.RCode) string {
+	if n, ok := dnsRcodes[r]; ok {
+		return n
+	}
+	return fmt.Sprintf("RCODE%d", r)
+}
+
+// DNSProber implements the Prober interface.
+// It queries the DNS server at the host and port of the URL for a name
+// over UDP, TCP or TLS, depending on the scheme of the URL.
+// Responses with any rcode but NOERROR and NXDOMAIN fail the probe,
+// because the server could not answer the query.
+type DNSProber struct {
+	name   dnsmessage.Name
+	qtype  dnsmessage.Type
+	config *tls.Config
+}
+
+// NewDNSProber returns a new DNSProber that queries the given name and type, e.g. A or AAAA.
+// The config, which may be nil, is used for DNS over TLS.
+// It is safe to use concurrently.
+func NewDNSProber(name, qtype string, config *tls.Config) (*DNSProber, error) {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	n, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse name %q: %w", name, err)
+	}
+	t, ok := dnsTypes[strings.ToUpper(qtype)]
+	if !ok {
+		return nil, fmt.Errorf("unknown DNS type %q", qtype)
+	}
+	c := &tls.Config{}
+	if config != nil {
+		c = config.Clone()
+	}
+	return &DNSProber{name: n, qtype: t, config: c}, nil
+}
+
+func (p *DNSProber) Probe(ctx context.Context, u url.URL) (time.Duration, error) {
+	dur, _, err := p.ProbeDetails(ctx, u)
+	return dur, err
+}
+
+func (p *DNSProber) ProbeDetails(ctx context.Context, u url.URL) (time.Duration, *Details, error) {
+	var transport, port string
+	switch u.Scheme {
+	case SchemeDNS:
+		transport, port = "udp", "53"
+	case SchemeDNSTCP:
+		transport, port = "tcp", "53"
+	case SchemeDNSTLS:
+		transport, port = "tls", "853"
+	default:
+		return 0, nil, fmt.Errorf("%w: %s", ErrUnsupportedScheme, u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	addr := net.JoinHostPort(u.Hostname(), port)
+	id := uint16(time.Now().UnixNano())
+	q, err := p.query(id)
+	if err != nil {
+		return 0, nil, err
+	}
+	start := time.Now()
+	resp, err := p.exchange(ctx, transport, addr, u.Hostname(), q)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to query %s over %s: %w", addr, transport, err)
+	}
+	dur := time.Now().Sub(start)
+	var parser dnsmessage.Parser
+	h, err := parser.Start(resp)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to parse response from %s: %w", addr, err)
+	}
+	if h.ID != id || !h.Response {
+		return 0, nil, fmt.Errorf("received unexpected message from %s", addr)
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return 0, nil, fmt.Errorf("failed to parse response from %s: %w", addr, err)
+	}
+	answers, err := parser.AllAnswers()
+	if err != nil && !h.Truncated {
+		return 0, nil, fmt.Errorf("failed to parse answers from %s: %w", addr, err)
+	}
+	if h.RCode != dnsmessage.RCodeSuccess && h.RCode != dnsmessage.RCodeNameError {
+		return 0, nil, fmt.Errorf("%s responded with %s", addr, rcodeName(h.RCode))
+	}
+	return dur, &Details{DNS: &DNSDetails{
+		Transport: transport,
+		Name:      p.name.String(),
+		Type:      strings.TrimPrefix(p.qtype.String(), "Type"),
+		Rcode:     rcodeName(h.RCode),
+		Answers:   len(answers),
+		Truncated: h.Truncated,
+	}}, nil
+}
+
+func (p *DNSProber) query(id uint16) ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	if err := b.StartQuestions(); err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+	if err := b.Question(dnsmessage.Question{Name: p.name, Type: p.qtype, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+	q, err := b.Finish()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+	return q, nil
+}
+
+// exchange sends the query and returns the response.
+// Messages over TCP and TLS are prefixed with their length.
+func (p *DNSProber) exchange(ctx context.Context, transport, addr, host string, q []byte) ([]byte, error) {
+	network := transport
+	if network == "tls" {
+		network = "tcp"
+	}
+	conn, err := dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if d, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(d)
+	}
+	if transport == "tls" {
+		c := p.config.Clone()
+		if c.ServerName == "" {
+			c.ServerName = host
+		}
+		tc := tls.Client(conn, c)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			return nil, err
+		}
+		conn = tc
+	}
+	if transport == "udp" {
+		if _, err := conn.Write(q); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+	msg := make([]byte, 2+len(q))
+	binary.BigEndian.PutUint16(msg, uint16(len(q)))
+	copy(msg[2:], q)
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	var l [2]byte
+	if _, err := io.ReadFull(conn, l[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(l[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	if len(resp) == 0 {
+		return nil, errors.New("received empty response")
+	}
+	return resp, nil
+}
+
+func (p *DNSProber) Supports(scheme string) bool {
+	switch scheme {
+	case SchemeDNS, SchemeDNSTCP, SchemeDNSTLS:
+		return true
+	}
+	return false
+}
+
+func (p *DNSProber) String() string {
+	return "dns-prober"
+}
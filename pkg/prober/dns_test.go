@@ -0,0 +1,186 @@
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsAnswer answers queries for example.com. with an A record,
+// fails queries for fail.example.com. and does not know any other names.
+func dnsAnswer(t *testing.T, q []byte) []byte {
+	var p dnsmessage.Parser
+	h, err := p.Start(q)
+	if err != nil {
+		t.Errorf("failed to parse query: %v", err)
+		return nil
+	}
+	question, err := p.Question()
+	if err != nil {
+		t.Errorf("failed to parse question: %v", err)
+		return nil
+	}
+	rh := dnsmessage.Header{ID: h.ID, Response: true, RecursionDesired: h.RecursionDesired}
+	switch question.Name.String() {
+	case "example.com.":
+	case "fail.example.com.":
+		rh.RCode = dnsmessage.RCodeServerFailure
+	default:
+		rh.RCode = dnsmessage.RCodeNameError
+	}
+	b := dnsmessage.NewBuilder(nil, rh)
+	b.StartQuestions()
+	b.Question(question)
+	b.StartAnswers()
+	if rh.RCode == dnsmessage.RCodeSuccess {
+		b.AResource(dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 60}, dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}})
+	}
+	resp, err := b.Finish()
+	if err != nil {
+		t.Errorf("failed to build response: %v", err)
+	}
+	return resp
+}
+
+// serveDNS serves dnsAnswer over UDP and over TCP or TLS on the same port.
+// It returns the port.
+func serveDNS(t *testing.T, config *tls.Config) (string, func()) {
+	var l net.Listener
+	var err error
+	if config != nil {
+		l, err = tls.Listen("tcp", "127.0.0.1:0", config)
+	} else {
+		l, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	pc, err := net.ListenPacket("udp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			pc.WriteTo(dnsAnswer(t, buf[:n]), addr)
+		}
+	}()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				var lb [2]byte
+				if _, err := io.ReadFull(c, lb[:]); err != nil {
+					return
+				}
+				q := make([]byte, binary.BigEndian.Uint16(lb[:]))
+				if _, err := io.ReadFull(c, q); err != nil {
+					return
+				}
+				resp := dnsAnswer(t, q)
+				binary.BigEndian.PutUint16(lb[:], uint16(len(resp)))
+				c.Write(append(lb[:], resp...))
+			}()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	return port, func() {
+		l.Close()
+		pc.Close()
+	}
+}
+
+func TestDNSProber(t *testing.T) {
+	port, stop := serveDNS(t, nil)
+	defer stop()
+	// Borrow the certificate of a TLS test server.
+	hs := httptest.NewTLSServer(http.NotFoundHandler())
+	defer hs.Close()
+	tlsPort, stopTLS := serveDNS(t, hs.TLS)
+	defer stopTLS()
+	roots := hs.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+	for i, tc := range []struct {
+		name    string
+		scheme  string
+		port    string
+		details *DNSDetails
+		err     bool
+	}{
+		{
+			name:    "example.com",
+			scheme:  SchemeDNS,
+			port:    port,
+			details: &DNSDetails{Transport: "udp", Name: "example.com.", Type: "A", Rcode: "NOERROR", Answers: 1},
+		},
+		{
+			name:    "example.com",
+			scheme:  SchemeDNSTCP,
+			port:    port,
+			details: &DNSDetails{Transport: "tcp", Name: "example.com.", Type: "A", Rcode: "NOERROR", Answers: 1},
+		},
+		{
+			name:    "example.com",
+			scheme:  SchemeDNSTLS,
+			port:    tlsPort,
+			details: &DNSDetails{Transport: "tls", Name: "example.com.", Type: "A", Rcode: "NOERROR", Answers: 1},
+		},
+		{
+			name:    "unknown.example.com",
+			scheme:  SchemeDNS,
+			port:    port,
+			details: &DNSDetails{Transport: "udp", Name: "unknown.example.com.", Type: "A", Rcode: "NXDOMAIN"},
+		},
+		{
+			name:   "fail.example.com",
+			scheme: SchemeDNS,
+			port:   port,
+			err:    true,
+		},
+	} {
+		p, err := NewDNSProber(tc.name, "a", &tls.Config{RootCAs: roots})
+		if err != nil {
+			t.Fatalf("%d: failed to create prober: %v", i, err)
+		}
+		_, d, err := p.ProbeDetails(context.Background(), url.URL{Scheme: tc.scheme, Host: net.JoinHostPort("127.0.0.1", tc.port)})
+		if (err != nil) != tc.err {
+			t.Errorf("%d (%s over %s): expected error %t, got %v", i, tc.name, tc.scheme, tc.err, err)
+			continue
+		}
+		if tc.err {
+			continue
+		}
+		if d == nil || d.DNS == nil || *d.DNS != *tc.details {
+			t.Errorf("%d (%s over %s): expected %+v, got %+v", i, tc.name, tc.scheme, tc.details, d)
+		}
+	}
+}
+
+func TestDNSProberScheme(t *testing.T) {
+	p, err := NewDNSProber("example.com", "AAAA", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Probe(context.Background(), url.URL{Scheme: "http", Host: "127.0.0.1:53"}); !errors.Is(err, ErrUnsupportedScheme) {
+		t.Errorf("expected unsupported scheme, got %v", err)
+	}
+	if _, err := NewDNSProber("example.com", "BOGUS", nil); err == nil {
+		t.Error("expected an error for an unknown type")
+	}
+}
@@ -0,0 +1,117 @@
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// The schemes of the URLs of gRPC servers.
+const (
+	SchemeGRPC  = "grpc"
+	SchemeGRPCS = "grpcs"
+)
+
+// GRPCDetails describe a gRPC health check.
+type GRPCDetails struct {
+	// Connect is the time it took to establish the connection, including the TLS handshake.
+	Connect time.Duration `json:"connect"`
+	// Check is the time the health check took after the connection was established.
+	Check   time.Duration `json:"check"`
+	Service string        `json:"service,omitempty"`
+	// Status is the serving status reported by the server, e.g. SERVING or NOT_SERVING.
+	Status string `json:"status"`
+}
+
+// GRPCHealthProber implements the Prober interface.
+// It calls grpc.health.v1.Health/Check on the host and port of grpc and grpcs URLs.
+// Only the SERVING status succeeds the probe;
+// the status is reported in the details either way.
+type GRPCHealthProber struct {
+	service string
+	config  *tls.Config
+}
+
+// NewGRPCHealthProber returns a new GRPCHealthProber that checks the given service;
+// the empty service checks the server as a whole.
+// The config, which may be nil, is used for grpcs URLs.
+// It is safe to use concurrently.
+func NewGRPCHealthProber(service string, config *tls.Config) *GRPCHealthProber {
+	c := &tls.Config{}
+	if config != nil {
+		c = config.Clone()
+	}
+	return &GRPCHealthProber{service: service, config: c}
+}
+
+func (p *GRPCHealthProber) Probe(ctx context.Context, u url.URL) (time.Duration, error) {
+	dur, _, err := p.ProbeDetails(ctx, u)
+	return dur, err
+}
+
+func (p *GRPCHealthProber) ProbeDetails(ctx context.Context, u url.URL) (time.Duration, *Details, error) {
+	var creds credentials.TransportCredentials
+	var port string
+	switch u.Scheme {
+	case SchemeGRPC:
+		creds, port = insecure.NewCredentials(), "80"
+	case SchemeGRPCS:
+		creds, port = credentials.NewTLS(p.config), "443"
+	default:
+		return 0, nil, fmt.Errorf("%w: %s", ErrUnsupportedScheme, u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	addr := net.JoinHostPort(u.Hostname(), port)
+	d, err := SourceFromContext(ctx).dialer("tcp")
+	if err != nil {
+		return 0, nil, err
+	}
+	// gRPC does not pass the values of ctx to the dialer.
+	dialer := func(dctx context.Context, a string) (net.Conn, error) {
+		c, err := d.DialContext(dctx, "tcp", a)
+		if err == nil {
+			recordSource(ctx, c.LocalAddr())
+		}
+		return c, err
+	}
+	start := time.Now()
+	conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(creds), grpc.WithBlock(), grpc.WithContextDialer(dialer))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+	connected := time.Now()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: p.service})
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to check health of %s: %w", addr, err)
+	}
+	end := time.Now()
+	details := &Details{GRPC: &GRPCDetails{
+		Connect: connected.Sub(start),
+		Check:   end.Sub(connected),
+		Service: p.service,
+		Status:  resp.Status.String(),
+	}}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return 0, details, fmt.Errorf("%s is %s", addr, resp.Status)
+	}
+	return end.Sub(start), details, nil
+}
+
+func (p *GRPCHealthProber) Supports(scheme string) bool {
+	return scheme == SchemeGRPC || scheme == SchemeGRPCS
+}
+
+func (p *GRPCHealthProber) String() string {
+	return "grpc-health-prober"
+}
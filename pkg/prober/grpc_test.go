@@ -0,0 +1,118 @@
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// serveHealth serves the gRPC health service, with TLS if the config is not nil.
+// It returns the port.
+func serveHealth(t *testing.T, hs *health.Server, config *tls.Config) (string, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	var opts []grpc.ServerOption
+	if config != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+	}
+	s := grpc.NewServer(opts...)
+	healthpb.RegisterHealthServer(s, hs)
+	go s.Serve(lis)
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+	return port, s.Stop
+}
+
+func TestGRPCHealthProber(t *testing.T) {
+	hs := health.NewServer()
+	hs.SetServingStatus("ready", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("draining", healthpb.HealthCheckResponse_NOT_SERVING)
+	port, stop := serveHealth(t, hs, nil)
+	defer stop()
+	// Borrow the certificate of a TLS test server.
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+	tlsPort, stopTLS := serveHealth(t, hs, &tls.Config{Certificates: ts.TLS.Certificates})
+	defer stopTLS()
+	roots := ts.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+	for i, tc := range []struct {
+		name    string
+		service string
+		scheme  string
+		port    string
+		status  string
+		err     bool
+	}{
+		{
+			name:   "server",
+			scheme: SchemeGRPC,
+			port:   port,
+			status: "SERVING",
+		},
+		{
+			name:    "serving service",
+			service: "ready",
+			scheme:  SchemeGRPC,
+			port:    port,
+			status:  "SERVING",
+		},
+		{
+			name:    "not serving service",
+			service: "draining",
+			scheme:  SchemeGRPC,
+			port:    port,
+			status:  "NOT_SERVING",
+			err:     true,
+		},
+		{
+			name:    "unknown service",
+			service: "unknown",
+			scheme:  SchemeGRPC,
+			port:    port,
+			err:     true,
+		},
+		{
+			name:    "tls",
+			service: "ready",
+			scheme:  SchemeGRPCS,
+			port:    tlsPort,
+			status:  "SERVING",
+		},
+	} {
+		p := NewGRPCHealthProber(tc.service, &tls.Config{RootCAs: roots})
+		dur, d, err := p.ProbeDetails(context.Background(), url.URL{Scheme: tc.scheme, Host: net.JoinHostPort("127.0.0.1", tc.port)})
+		if (err != nil) != tc.err {
+			t.Errorf("%d (%s): expected error %t, got %v", i, tc.name, tc.err, err)
+			continue
+		}
+		// Servers that report a status other than SERVING fail the probe, but still have details.
+		if tc.status == "" {
+			continue
+		}
+		if d == nil || d.GRPC == nil {
+			t.Errorf("%d (%s): expected gRPC details", i, tc.name)
+			continue
+		}
+		gd := d.GRPC
+		if gd.Status != tc.status || gd.Service != tc.service {
+			t.Errorf("%d (%s): expected status %s of %q, got %s of %q", i, tc.name, tc.status, tc.service, gd.Status, gd.Service)
+		}
+		if err == nil && dur != gd.Connect+gd.Check {
+			t.Errorf("%d (%s): expected duration %v to be the sum of connect and check, got %v and %v", i, tc.name, dur, gd.Connect, gd.Check)
+		}
+	}
+	if _, err := NewGRPCHealthProber("", nil).Probe(context.Background(), url.URL{Scheme: "http", Host: "127.0.0.1:1"}); !errors.Is(err, ErrUnsupportedScheme) {
+		t.Errorf("expected unsupported scheme, got %v", err)
+	}
+}
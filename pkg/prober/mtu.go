@@ -0,0 +1,333 @@
+package prober
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/bits"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// The methods of MTU probes.
+const (
+	MTUICMP = "icmp"
+	MTUUDP  = "udp"
+	MTUHTTP = "http"
+)
+
+const (
+	// minMTU is the smallest MTU of IPv4 links, so every destination must be reachable with it.
+	minMTU = 68
+	// defaultMTUStep is the time to wait for the answer to a single probe if the context has no deadline.
+	defaultMTUStep = time.Second
+	ipv4HeaderLen  = 20
+	icmpHeaderLen  = 8
+	udpHeaderLen   = 8
+	// EchoPath is the path of the endpoint that returns the body of requests.
+	EchoPath = "/echo"
+)
+
+// MTUProber determines the path MTU to the host of a URL with a binary search over the sizes of its probes.
+// With the icmp and udp methods, it sends IPv4 packets with the DF bit set, so that routers drop packets
+// that are too large for the next link instead of fragmenting them.
+// It finds the size of the largest packet that reached the destination,
+// which answers ICMP echo requests and UDP datagrams to closed ports.
+// ICMP probes need a raw ICMP socket, i.e. the NET_RAW capability,
+// and answers to UDP probes are subject to the ICMP rate limits of the destination.
+// Both are only supported on Linux.
+// With the http method, it finds the size of the largest request body that the echo endpoint of the URL returns.
+// TCP splits bodies into segments, so the size is not the path MTU;
+// a size below the maximum means that large segments are lost, e.g. because ICMP messages about the path MTU are blocked.
+// It does not implement the Prober interface, because it measures sizes and not durations.
+type MTUProber struct {
+	method string
+	max    int
+	c      Client
+}
+
+// NewMTUProber returns a new MTUProber that uses the given method and probes sizes up to max.
+// The client is only used by the http method.
+// It is safe to use concurrently.
+func NewMTUProber(method string, max int, c Client) (*MTUProber, error) {
+	switch method {
+	case MTUICMP, MTUUDP, MTUHTTP:
+	default:
+		return nil, fmt.Errorf("unknown MTU method %q", method)
+	}
+	if max < minMTU {
+		return nil, fmt.Errorf("the maximum MTU must be at least %d, got %d", minMTU, max)
+	}
+	return &MTUProber{method: method, max: max, c: c}, nil
+}
+
+// Method returns the method of the probes.
+func (p *MTUProber) Method() string {
+	return p.method
+}
+
+// errNoAnswer is returned by a fitsFunc if the probe was not answered in time.
+// Either the probe was too large and dropped silently or the probe or its answer was lost,
+// e.g. because of the ICMP rate limits of the destination.
+var errNoAnswer = errors.New("no answer")
+
+// fitsFunc probes the given size and returns true if it reached the destination.
+// If a router reported the MTU of the next link, it is returned as well.
+type fitsFunc func(ctx context.Context, size int) (bool, int, error)
+
+// search returns the largest size between lo and hi that fits, assuming that all smaller sizes fit.
+// The remaining time of the context is split evenly between the remaining probes.
+// Probes that are not answered are repeated once, so that a lost answer does not understate the size.
+func search(ctx context.Context, lo, hi int, fits fitsFunc) (int, error) {
+	good, bad := lo-1, hi+1
+	probe := func(size int) (bool, int, error) {
+		var ok bool
+		var next int
+		var err error
+		for try := 0; try < 2; try++ {
+			step := defaultMTUStep
+			if d, set := ctx.Deadline(); set {
+				step = time.Until(d) / time.Duration(bits.Len(uint(bad-good))+1)
+			}
+			ctxS, cancel := context.WithTimeout(ctx, step)
+			ok, next, err = fits(ctxS, size)
+			cancel()
+			if !errors.Is(err, errNoAnswer) {
+				return ok, next, err
+			}
+		}
+		return false, 0, err
+	}
+	ok, _, err := probe(lo)
+	if err != nil && !errors.Is(err, errNoAnswer) {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("no probe of %d bytes reached the destination", lo)
+	}
+	good = lo
+	// hint is the MTU that a router reported, which is probed next.
+	var hint int
+	for bad-good > 1 {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		mid := good + (bad-good)/2
+		if hint != 0 {
+			mid, hint = hint, 0
+		}
+		ok, next, err := probe(mid)
+		if err != nil && !errors.Is(err, errNoAnswer) {
+			return 0, err
+		}
+		switch {
+		case ok:
+			good = mid
+		case next > good && next < mid:
+			// The MTU of the next link is the largest size that can fit.
+			bad, hint = next+1, next
+		default:
+			// Probes that are not answered twice are most likely too large.
+			bad = mid
+		}
+	}
+	return good, nil
+}
+
+// MTU returns the path MTU to the host of the URL or, with the http method,
+// the size of the largest body that the echo endpoint of the URL returns.
+// The probes are sent from the source of the context.
+func (p *MTUProber) MTU(ctx context.Context, u url.URL) (int, error) {
+	if p.method == MTUHTTP {
+		u.Path = EchoPath
+		u.RawQuery = ""
+		return search(ctx, 1, p.max, func(ctx context.Context, size int) (bool, int, error) {
+			ok := p.echo(ctx, u, size)
+			if !ok && ctx.Err() != nil {
+				return false, 0, errNoAnswer
+			}
+			return ok, 0, nil
+		})
+	}
+	dst, err := lookupIPv4(ctx, u.Hostname())
+	if err != nil {
+		return 0, err
+	}
+	s := SourceFromContext(ctx)
+	control := func(network, address string, c syscall.RawConn) error {
+		if bind := s.control(); bind != nil {
+			if err := bind(network, address, c); err != nil {
+				return err
+			}
+		}
+		var err error
+		if cerr := c.Control(func(fd uintptr) {
+			err = setDontFragment(fd)
+		}); cerr != nil {
+			return cerr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to set DF bit: %w", err)
+		}
+		return nil
+	}
+	if p.method == MTUUDP {
+		d, err := s.dialer("udp4")
+		if err != nil {
+			return 0, err
+		}
+		d.Control = control
+		conn, err := d.DialContext(ctx, "udp4", net.JoinHostPort(dst.String(), strconv.Itoa(tracerouteBasePort)))
+		if err != nil {
+			return 0, fmt.Errorf("failed to create UDP socket: %w", err)
+		}
+		defer conn.Close()
+		return search(ctx, minMTU, p.max, func(ctx context.Context, size int) (bool, int, error) {
+			return fitsUDP(ctx, conn, size)
+		})
+	}
+	laddr := "0.0.0.0"
+	if s.Address != "" {
+		laddr = s.Address
+	}
+	lc := net.ListenConfig{Control: control}
+	c, err := lc.ListenPacket(ctx, "ip4:icmp", laddr)
+	if err != nil {
+		return 0, fmt.Errorf("failed to listen for ICMP messages: %w", err)
+	}
+	defer c.Close()
+	id := nextEchoID()
+	seq := 0
+	return search(ctx, minMTU, p.max, func(ctx context.Context, size int) (bool, int, error) {
+		seq++
+		return fitsICMP(ctx, c, dst, id, seq, size)
+	})
+}
+
+// fitsUDP sends a datagram of the given size and waits for the port unreachable message of the destination.
+func fitsUDP(ctx context.Context, conn net.Conn, size int) (bool, int, error) {
+	drainUDP(conn)
+	if _, err := conn.Write(make([]byte, size-ipv4HeaderLen-udpHeaderLen)); err != nil {
+		// EMSGSIZE means that the datagram does not fit the local link.
+		if errors.Is(err, syscall.EMSGSIZE) {
+			return false, 0, nil
+		}
+		return false, 0, errNoAnswer
+	}
+	d, ok := ctx.Deadline()
+	if !ok {
+		d = time.Now().Add(defaultMTUStep)
+	}
+	conn.SetReadDeadline(d)
+	_, err := conn.Read(make([]byte, 1))
+	// The port unreachable message of the destination is reported as ECONNREFUSED.
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true, 0, nil
+	}
+	return false, 0, errNoAnswer
+}
+
+// drainUDP discards the errors of the socket that port unreachable messages caused,
+// which answered earlier probes after their deadline,
+// because the socket reports them on the next read or write.
+func drainUDP(conn net.Conn) {
+	buf := make([]byte, 1)
+	for i := 0; i < 16; i++ {
+		// Reads fail without checking the socket if the deadline has passed.
+		conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+		if _, err := conn.Read(buf); !errors.Is(err, syscall.ECONNREFUSED) {
+			return
+		}
+	}
+}
+
+// fitsICMP sends an echo request of the given size and waits for the reply of the destination
+// or for the message of a router that the packet is too large.
+func fitsICMP(ctx context.Context, c net.PacketConn, dst net.IP, id, seq, size int) (bool, int, error) {
+	data := make([]byte, size-ipv4HeaderLen-icmpHeaderLen)
+	b, err := (&icmp.Message{Type: ipv4.ICMPTypeEcho, Body: &icmp.Echo{ID: id, Seq: seq, Data: data}}).Marshal(nil)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to build ICMP echo request: %w", err)
+	}
+	if _, err := c.WriteTo(b, &net.IPAddr{IP: dst}); err != nil {
+		// EMSGSIZE means that the packet does not fit the local link.
+		return false, 0, nil
+	}
+	d, ok := ctx.Deadline()
+	if !ok {
+		d = time.Now().Add(defaultMTUStep)
+	}
+	if err := c.SetReadDeadline(d); err != nil {
+		return false, 0, fmt.Errorf("failed to set read deadline: %w", err)
+	}
+	buf := make([]byte, size+ipv4HeaderLen)
+	for {
+		n, _, err := c.ReadFrom(buf)
+		if err != nil {
+			var nerr net.Error
+			if errors.As(err, &nerr) && nerr.Timeout() {
+				return false, 0, errNoAnswer
+			}
+			return false, 0, fmt.Errorf("failed to read ICMP message: %w", err)
+		}
+		if ok, next, match := matchMTU(buf[:n], dst, id, seq, len(data)); match {
+			return ok, next, nil
+		}
+	}
+}
+
+// matchMTU returns whether the ICMP message answers the echo request with the given ID and sequence number.
+// If it does, it returns whether the echo request reached the destination
+// and the MTU of the next link if the message is from a router that could not forward the request.
+func matchMTU(b []byte, dst net.IP, id, seq, size int) (bool, int, bool) {
+	m, err := icmp.ParseMessage(protocolICMP, b)
+	if err != nil {
+		return false, 0, false
+	}
+	switch body := m.Body.(type) {
+	case *icmp.Echo:
+		match := m.Type == ipv4.ICMPTypeEchoReply && body.ID == id && body.Seq == seq
+		return match && len(body.Data) == size, 0, match
+	case *icmp.DstUnreach:
+		h, err := ipv4.ParseHeader(body.Data)
+		if err != nil || !h.Dst.Equal(dst) || h.Protocol != protocolICMP || len(body.Data) < h.Len+8 {
+			return false, 0, false
+		}
+		probe := body.Data[h.Len:]
+		if int(binary.BigEndian.Uint16(probe[4:6])) != id || int(binary.BigEndian.Uint16(probe[6:8])) != seq {
+			return false, 0, false
+		}
+		// The code 4 means that fragmentation is needed; the MTU of the next link is in bytes 6 and 7.
+		if m.Code == 4 && len(b) >= 8 {
+			return false, int(binary.BigEndian.Uint16(b[6:8])), true
+		}
+		return false, 0, true
+	}
+	return false, 0, false
+}
+
+// echo posts a body of the given size to the URL and returns true if the same body is returned.
+func (p *MTUProber) echo(ctx context.Context, u url.URL, size int) bool {
+	body := bytes.Repeat([]byte("adjacency "), size/10+1)[:size]
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	resp, err := p.c.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	got, err := ioutil.ReadAll(resp.Body)
+	return err == nil && resp.StatusCode == http.StatusOK && bytes.Equal(got, body)
+}
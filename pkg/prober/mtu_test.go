@@ -0,0 +1,173 @@
+package prober
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"testing"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+func TestSearch(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		mtu  int
+		next int
+		// silent drops probes that are too large without an answer.
+		silent bool
+		// lost loses the first answer to every size.
+		lost     bool
+		expected int
+		err      bool
+	}{
+		{name: "maximum", mtu: 1500, expected: 1500},
+		{name: "wireguard", mtu: 1420, expected: 1420},
+		{name: "router reports next hop", mtu: 1280, next: 1280, expected: 1280},
+		{name: "minimum", mtu: minMTU, expected: minMTU},
+		{name: "unreachable", mtu: 0, err: true},
+		{name: "silently dropped", mtu: 1420, silent: true, expected: 1420},
+		{name: "lost answers", mtu: 1420, lost: true, expected: 1420},
+		{name: "unreachable without answers", mtu: 0, silent: true, err: true},
+	} {
+		var probes int
+		tried := make(map[int]bool)
+		mtu, err := search(context.Background(), minMTU, 1500, func(_ context.Context, size int) (bool, int, error) {
+			probes++
+			if tc.lost && !tried[size] {
+				tried[size] = true
+				return false, 0, errNoAnswer
+			}
+			if size <= tc.mtu {
+				return true, 0, nil
+			}
+			if tc.silent {
+				return false, 0, errNoAnswer
+			}
+			return false, tc.next, nil
+		})
+		if (err != nil) != tc.err {
+			t.Errorf("test case %q: expected error %t, got %v", tc.name, tc.err, err)
+		}
+		if mtu != tc.expected {
+			t.Errorf("test case %q: expected %d, got %d", tc.name, tc.expected, mtu)
+		}
+		if tc.next != 0 && probes > 6 {
+			t.Errorf("test case %q: expected the reported MTU to shorten the search, got %d probes", tc.name, probes)
+		}
+	}
+}
+
+func TestMatchMTU(t *testing.T) {
+	dst := net.IPv4(192, 0, 2, 1).To4()
+	marshal := func(m icmp.Message) []byte {
+		b, err := m.Marshal(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	request := marshal(icmp.Message{Type: ipv4.ICMPTypeEcho, Body: &icmp.Echo{ID: 7, Seq: 2, Data: make([]byte, 100)}})
+	fragNeeded := marshal(icmp.Message{Type: ipv4.ICMPTypeDestinationUnreachable, Code: 4, Body: &icmp.DstUnreach{Data: quote(t, dst, protocolICMP, request[:8])}})
+	binary.BigEndian.PutUint16(fragNeeded[6:8], 1420)
+	for _, tc := range []struct {
+		name  string
+		msg   []byte
+		ok    bool
+		next  int
+		match bool
+	}{
+		{
+			name:  "reply",
+			msg:   marshal(icmp.Message{Type: ipv4.ICMPTypeEchoReply, Body: &icmp.Echo{ID: 7, Seq: 2, Data: make([]byte, 100)}}),
+			ok:    true,
+			match: true,
+		},
+		{
+			name:  "truncated reply",
+			msg:   marshal(icmp.Message{Type: ipv4.ICMPTypeEchoReply, Body: &icmp.Echo{ID: 7, Seq: 2, Data: make([]byte, 50)}}),
+			match: true,
+		},
+		{
+			name: "reply to an earlier request",
+			msg:  marshal(icmp.Message{Type: ipv4.ICMPTypeEchoReply, Body: &icmp.Echo{ID: 7, Seq: 1, Data: make([]byte, 100)}}),
+		},
+		{
+			name: "own request",
+			msg:  request,
+		},
+		{
+			name:  "fragmentation needed",
+			msg:   fragNeeded,
+			next:  1420,
+			match: true,
+		},
+		{
+			name:  "host unreachable",
+			msg:   marshal(icmp.Message{Type: ipv4.ICMPTypeDestinationUnreachable, Code: 1, Body: &icmp.DstUnreach{Data: quote(t, dst, protocolICMP, request[:8])}}),
+			match: true,
+		},
+	} {
+		ok, next, match := matchMTU(tc.msg, dst, 7, 2, 100)
+		if ok != tc.ok || next != tc.next || match != tc.match {
+			t.Errorf("test case %q: expected %t, %d, %t, got %t, %d, %t", tc.name, tc.ok, tc.next, tc.match, ok, next, match)
+		}
+	}
+}
+
+func TestMTU(t *testing.T) {
+	// The handler only echoes bodies of up to 1000 bytes.
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != EchoPath {
+			http.NotFound(w, r)
+			return
+		}
+		// HTTP/1.x handlers must read the body before they write the response.
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil || len(body) > 1000 {
+			return
+		}
+		w.Write(body)
+	}))
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	methods := []string{MTUHTTP}
+	if c, err := net.ListenPacket("ip4:icmp", "127.0.0.1"); err == nil && runtime.GOOS == "linux" {
+		c.Close()
+		methods = append(methods, MTUICMP, MTUUDP)
+	}
+	for _, method := range methods {
+		p, err := NewMTUProber(method, 1500, NewSourceClient())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		mtu, err := p.MTU(ctx, *u)
+		cancel()
+		if err != nil {
+			t.Errorf("method %s: failed to probe MTU: %v", method, err)
+			continue
+		}
+		// The MTU of the loopback interface is larger than the maximum.
+		expected := 1500
+		if method == MTUHTTP {
+			expected = 1000
+		}
+		if mtu != expected {
+			t.Errorf("method %s: expected %d, got %d", method, expected, mtu)
+		}
+	}
+	if _, err := NewMTUProber("tcp", 1500, nil); err == nil {
+		t.Error("expected an error for an unknown method")
+	}
+}
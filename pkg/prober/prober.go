@@ -20,6 +20,75 @@ type Prober interface {
 	String() string
 }
 
+// ErrUnsupportedScheme is returned by probers that cannot probe URLs with the given scheme,
+// so that the next prober can be tried without reporting a failure.
+var ErrUnsupportedScheme = errors.New("unsupported scheme")
+
+// A SchemeProber is a Prober that only probes URLs with certain schemes
+// and returns ErrUnsupportedScheme for all others.
+type SchemeProber interface {
+	Prober
+	Supports(scheme string) bool
+}
+
+// Supports returns true if the prober probes URLs with the given scheme.
+// Probers that are not SchemeProbers probe URLs with any scheme.
+func Supports(p Prober, scheme string) bool {
+	if sp, ok := p.(SchemeProber); ok {
+		return sp.Supports(scheme)
+	}
+	return true
+}
+
+// Final returns true if a failure of the prober to probe a URL with the given scheme is the result of the probe.
+// Probers of other protocols than HTTP are final for their schemes,
+// because the probers after them would only connect to the port and hide the failure.
+// The HTTP probers are not, because the targets of HTTP URLs do not need to serve HTTP.
+func Final(p Prober, scheme string) bool {
+	return Supports(p, scheme) && !Supports(p, "http")
+}
+
+// Chain returns the probers that are tried in order to probe a URL with the given scheme:
+// the probers that support the scheme up to the first one that is final for it.
+func Chain(probers []Prober, scheme string) []Prober {
+	var c []Prober
+	for _, p := range probers {
+		if !Supports(p, scheme) {
+			continue
+		}
+		c = append(c, p)
+		if Final(p, scheme) {
+			break
+		}
+	}
+	return c
+}
+
+// Details describe a probe in more detail than its duration.
+// Only the field of the prober that made the probe is set.
+type Details struct {
+	TLS  *TLSDetails  `json:"tls,omitempty"`
+	DNS  *DNSDetails  `json:"dns,omitempty"`
+	GRPC *GRPCDetails `json:"grpc,omitempty"`
+}
+
+// A DetailedProber is a Prober that also reports details of its probes.
+// The details of a failed probe may be set to tell why it failed.
+type DetailedProber interface {
+	Prober
+	ProbeDetails(context.Context, url.URL) (time.Duration, *Details, error)
+}
+
+// checkHTTPScheme returns ErrUnsupportedScheme if the URL cannot be requested via HTTP.
+// URLs without a scheme are left to the client.
+func checkHTTPScheme(u url.URL) error {
+	switch u.Scheme {
+	case "", "http", "https":
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedScheme, u.Scheme)
+}
+
 // The Client interface's purpose is to enable easy testing of Probers by consuming a simplified interface
 // instead of a http.Client.
 type Client interface {
@@ -51,8 +120,11 @@ func NewHTTPPingProber(c Client) *HTTPPingProber {
 }
 
 func (p *HTTPPingProber) Probe(ctx context.Context, u url.URL) (time.Duration, error) {
+	if err := checkHTTPScheme(u); err != nil {
+		return 0, err
+	}
 	u.Path = "ping"
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	req, err := http.NewRequestWithContext(traceSource(ctx), http.MethodGet, u.String(), nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -75,6 +147,10 @@ func (p *HTTPPingProber) Probe(ctx context.Context, u url.URL) (time.Duration, e
 	return dur, nil
 }
 
+func (p *HTTPPingProber) Supports(scheme string) bool {
+	return checkHTTPScheme(url.URL{Scheme: scheme}) == nil
+}
+
 func (p *HTTPPingProber) String() string {
 	return "http-ping-prober"
 }
@@ -91,7 +167,10 @@ func NewHTTPProber(c Client) *HTTPProber {
 }
 
 func (p *HTTPProber) Probe(ctx context.Context, u url.URL) (time.Duration, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err := checkHTTPScheme(u); err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(traceSource(ctx), http.MethodGet, u.String(), nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -111,22 +190,22 @@ func (p *HTTPProber) Probe(ctx context.Context, u url.URL) (time.Duration, error
 	return dur, nil
 }
 
+func (p *HTTPProber) Supports(scheme string) bool {
+	return checkHTTPScheme(url.URL{Scheme: scheme}) == nil
+}
+
 func (p *HTTPProber) String() string {
 	return "http-prober"
 }
 
 // TCPProber implements the Prober interface.
-type TCPProber struct {
-	dialer net.Dialer
-}
+type TCPProber struct{}
 
 // NewTCPProber returns a new TCPProber.
 // It is safe to use it concurrently because
-// it only contains a net.Dialer.
+// it has no state.
 func NewTCPProber() *TCPProber {
-	return &TCPProber{
-		dialer: net.Dialer{},
-	}
+	return &TCPProber{}
 }
 
 // TCPProber tries to establish a TCP connection to the host and port of the given URL.
@@ -136,7 +215,7 @@ func (p TCPProber) Probe(ctx context.Context, u url.URL) (dur time.Duration, err
 	start := time.Now()
 	var conn net.Conn
 	sysErr := &os.SyscallError{}
-	if conn, err = p.dialer.DialContext(ctx, "tcp", fmt.Sprintf("%s:%s", u.Hostname(), u.Port())); err == nil {
+	if conn, err = dial(ctx, "tcp", fmt.Sprintf("%s:%s", u.Hostname(), u.Port())); err == nil {
 		defer conn.Close()
 	} else if errors.As(err, &sysErr) && sysErr.Err == syscall.ECONNRESET {
 		log.Printf("Received ECONNRESET from %s, continuing\n", u.String())
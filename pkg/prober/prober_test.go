@@ -8,6 +8,8 @@ import (
 	"net/url"
 	"testing"
 	"time"
+
+	"github.com/kylelemons/godebug/pretty"
 )
 
 type fakeReader struct{}
@@ -110,3 +112,57 @@ func TestHTTPClient(t *testing.T) {
 		}
 	}
 }
+
+func TestSupports(t *testing.T) {
+	dp, err := NewDNSProber("example.com", "A", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	probers := []Prober{NewTLSProber(nil), dp, NewGRPCHealthProber("", nil), NewHTTPPingProber(nil), NewHTTPProber(nil), NewTCPProber(), &NoProber{}}
+	for _, tc := range []struct {
+		scheme string
+		// expected are the probers that probe URLs with the scheme.
+		expected []string
+	}{
+		{scheme: "http", expected: []string{"http-ping-prober", "http-prober", "tcp-prober", "no-prober"}},
+		{scheme: "https", expected: []string{"tls-prober", "http-ping-prober", "http-prober", "tcp-prober", "no-prober"}},
+		{scheme: SchemeDNS, expected: []string{"dns-prober", "tcp-prober", "no-prober"}},
+		{scheme: SchemeGRPCS, expected: []string{"grpc-health-prober", "tcp-prober", "no-prober"}},
+	} {
+		var ps []string
+		for _, p := range probers {
+			if Supports(p, tc.scheme) {
+				ps = append(ps, p.String())
+			}
+		}
+		if diff := pretty.Compare(ps, tc.expected); diff != "" {
+			t.Errorf("scheme %q: got diff:\n%s", tc.scheme, diff)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	dp, err := NewDNSProber("example.com", "A", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	probers := []Prober{NewTLSProber(nil), dp, NewGRPCHealthProber("", nil), NewHTTPPingProber(nil), NewHTTPProber(nil), NewTCPProber(), &NoProber{}}
+	for _, tc := range []struct {
+		scheme string
+		// expected are the probers that are tried for the scheme.
+		expected []string
+	}{
+		{scheme: "http", expected: []string{"http-ping-prober", "http-prober", "tcp-prober", "no-prober"}},
+		{scheme: "https", expected: []string{"tls-prober"}},
+		{scheme: SchemeDNS, expected: []string{"dns-prober"}},
+		{scheme: SchemeGRPCS, expected: []string{"grpc-health-prober"}},
+	} {
+		var ps []string
+		for _, p := range Chain(probers, tc.scheme) {
+			ps = append(ps, p.String())
+		}
+		if diff := pretty.Compare(ps, tc.expected); diff != "" {
+			t.Errorf("scheme %q: got diff:\n%s", tc.scheme, diff)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+//go:build linux
+
+package prober
+
+import (
+	"net"
+	"syscall"
+)
+
+// bindToDevice binds the socket to the network interface with SO_BINDTODEVICE.
+func bindToDevice(fd uintptr, iface string) error {
+	return syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, iface)
+}
+
+// prepareTraceSocket sets the TTL of the IPv4 socket and binds it to the given address, which may be nil,
+// and an ephemeral port, which it returns, so that ICMP messages about the connection can be matched to it.
+func prepareTraceSocket(fd uintptr, ttl int, ip net.IP) (int, error) {
+	if err := syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TTL, ttl); err != nil {
+		return 0, err
+	}
+	sa := &syscall.SockaddrInet4{}
+	if ip != nil {
+		copy(sa.Addr[:], ip.To4())
+	}
+	if err := syscall.Bind(int(fd), sa); err != nil {
+		return 0, err
+	}
+	got, err := syscall.Getsockname(int(fd))
+	if err != nil {
+		return 0, err
+	}
+	return got.(*syscall.SockaddrInet4).Port, nil
+}
+
+// setDontFragment sets the DF bit of all packets of the IPv4 socket and ignores the cached path MTU,
+// so that packets that are larger than the path MTU are dropped or rejected instead of fragmented.
+func setDontFragment(fd uintptr) error {
+	return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_MTU_DISCOVER, syscall.IP_PMTUDISC_PROBE)
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package prober
+
+import (
+	"errors"
+	"net"
+)
+
+// bindToDevice fails, because SO_BINDTODEVICE only exists on Linux.
+func bindToDevice(fd uintptr, iface string) error {
+	return errors.New("binding to an interface is only supported on Linux")
+}
+
+// prepareTraceSocket fails, because TCP traceroutes are only supported on Linux.
+func prepareTraceSocket(fd uintptr, ttl int, ip net.IP) (int, error) {
+	return 0, errors.New("TCP traceroutes are only supported on Linux")
+}
+
+// setDontFragment fails, because MTU probes need IP_MTU_DISCOVER, which only exists on Linux.
+func setDontFragment(fd uintptr) error {
+	return errors.New("MTU probes with the DF bit are only supported on Linux")
+}
@@ -0,0 +1,220 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"syscall"
+)
+
+// Source is the local end of the connections of probes.
+// The zero Source lets the kernel pick the source address and the interface.
+type Source struct {
+	// Address is the IP address that probes are sent from.
+	Address string `json:"address,omitempty"`
+	// Interface is the network interface that probes are bound to with SO_BINDTODEVICE.
+	// It is only supported on Linux and may require the NET_RAW capability.
+	Interface string `json:"interface,omitempty"`
+}
+
+func (s Source) IsZero() bool {
+	return s == Source{}
+}
+
+func (s Source) String() string {
+	switch {
+	case s.Address != "" && s.Interface != "":
+		return s.Address + "%" + s.Interface
+	case s.Interface != "":
+		return "%" + s.Interface
+	}
+	return s.Address
+}
+
+// dialer returns a dialer that connects from the source via the given network.
+func (s Source) dialer(network string) (*net.Dialer, error) {
+	d := &net.Dialer{}
+	if s.Address != "" {
+		ip := net.ParseIP(s.Address)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source address %q", s.Address)
+		}
+		switch network {
+		case "udp", "udp4", "udp6":
+			d.LocalAddr = &net.UDPAddr{IP: ip}
+		default:
+			d.LocalAddr = &net.TCPAddr{IP: ip}
+		}
+	}
+	d.Control = s.control()
+	return d, nil
+}
+
+// control returns a function that binds sockets to the interface of the source
+// for net.Dialer and net.ListenConfig, or nil if the source has no interface.
+func (s Source) control() func(string, string, syscall.RawConn) error {
+	if s.Interface == "" {
+		return nil
+	}
+	iface := s.Interface
+	return func(_, _ string, c syscall.RawConn) error {
+		var err error
+		if cerr := c.Control(func(fd uintptr) {
+			err = bindToDevice(fd, iface)
+		}); cerr != nil {
+			return cerr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to bind to interface %s: %w", iface, err)
+		}
+		return nil
+	}
+}
+
+type sourceKey struct{}
+
+// WithSource returns a context in which probers send their probes from the given source.
+func WithSource(ctx context.Context, s Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, s)
+}
+
+// SourceFromContext returns the source of the probes made with the context.
+func SourceFromContext(ctx context.Context) Source {
+	s, _ := ctx.Value(sourceKey{}).(Source)
+	return s
+}
+
+type usedSourceKey struct{}
+
+// usedSource is the local IP address of the last connection of a probe.
+type usedSource struct {
+	mu sync.Mutex
+	ip string
+}
+
+// WithUsedSource returns a context in which probers record the local IP address of their connections
+// and a function that returns the address that was recorded last.
+func WithUsedSource(ctx context.Context) (context.Context, func() string) {
+	u := &usedSource{}
+	return context.WithValue(ctx, usedSourceKey{}, u), func() string {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		return u.ip
+	}
+}
+
+func recordSource(ctx context.Context, a net.Addr) {
+	u, ok := ctx.Value(usedSourceKey{}).(*usedSource)
+	if !ok || a == nil {
+		return
+	}
+	ip, _, err := net.SplitHostPort(a.String())
+	if err != nil {
+		ip = a.String()
+	}
+	u.mu.Lock()
+	u.ip = ip
+	u.mu.Unlock()
+}
+
+// dial connects to the address from the source of the context
+// and records the local address of the connection.
+func dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	d, err := SourceFromContext(ctx).dialer(network)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	recordSource(ctx, conn.LocalAddr())
+	return conn, nil
+}
+
+// traceSource returns a context that records the local address of the connection of an HTTP request,
+// including connections that are reused.
+func traceSource(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(i httptrace.GotConnInfo) {
+			recordSource(ctx, i.Conn.LocalAddr())
+		},
+	})
+}
+
+// maxSourceClients is the number of sources whose transports a SourceClient keeps.
+// Sources can be chosen per request, so the least recently used transports are dropped.
+const maxSourceClients = 16
+
+// SourceClient implements the Client interface.
+// It sends requests from the source of their contexts.
+// Requests without a source are sent with http.DefaultClient.
+// Every source has its own transport, so that connections are not shared between sources.
+type SourceClient struct {
+	mu sync.Mutex
+	cs map[Source]*http.Client
+	// lru are the sources of cs from the least to the most recently used.
+	lru []Source
+}
+
+// NewSourceClient returns a new SourceClient.
+// It is safe to use concurrently.
+func NewSourceClient() *SourceClient {
+	return &SourceClient{cs: make(map[Source]*http.Client)}
+}
+
+func (c *SourceClient) Do(req *http.Request) (*http.Response, error) {
+	hc, err := c.client(SourceFromContext(req.Context()))
+	if err != nil {
+		return nil, err
+	}
+	return hc.Do(req)
+}
+
+func (c *SourceClient) client(s Source) (*http.Client, error) {
+	if s.IsZero() {
+		return http.DefaultClient, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hc, ok := c.cs[s]; ok {
+		c.use(s)
+		return hc, nil
+	}
+	// Check the source once instead of failing every dial.
+	if _, err := s.dialer("tcp"); err != nil {
+		return nil, err
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		d, err := s.dialer(network)
+		if err != nil {
+			return nil, err
+		}
+		return d.DialContext(ctx, network, addr)
+	}
+	hc := &http.Client{Transport: t}
+	if len(c.lru) == maxSourceClients {
+		// Requests that still use the transport finish normally.
+		old := c.lru[0]
+		c.cs[old].CloseIdleConnections()
+		delete(c.cs, old)
+		c.lru = c.lru[1:]
+	}
+	c.cs[s] = hc
+	c.lru = append(c.lru, s)
+	return hc, nil
+}
+
+// use marks the source as the most recently used.
+func (c *SourceClient) use(s Source) {
+	for i, u := range c.lru {
+		if u == s {
+			c.lru = append(append(c.lru[:i:i], c.lru[i+1:]...), s)
+			return
+		}
+	}
+}
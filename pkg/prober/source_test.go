@@ -0,0 +1,120 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"testing"
+)
+
+func TestSourceString(t *testing.T) {
+	for _, tc := range []struct {
+		s        Source
+		expected string
+	}{
+		{s: Source{}, expected: ""},
+		{s: Source{Address: "10.4.0.1"}, expected: "10.4.0.1"},
+		{s: Source{Interface: "kilo0"}, expected: "%kilo0"},
+		{s: Source{Address: "10.4.0.1", Interface: "kilo0"}, expected: "10.4.0.1%kilo0"},
+	} {
+		if s := tc.s.String(); s != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, s)
+		}
+	}
+}
+
+func TestSourceProbers(t *testing.T) {
+	// Every address of 127.0.0.0/8 is local on Linux,
+	// so the server sees the source address if it was bound.
+	if runtime.GOOS != "linux" {
+		t.Skip("binding to 127.0.0.2 and to interfaces requires Linux")
+	}
+	remote := make(chan string, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		select {
+		case remote <- host:
+		default:
+		}
+	}))
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewSourceClient()
+	for _, tc := range []struct {
+		name     string
+		p        Prober
+		s        Source
+		expected string
+		err      bool
+	}{
+		{name: "tcp", p: NewTCPProber(), s: Source{Address: "127.0.0.2"}, expected: "127.0.0.2"},
+		{name: "http", p: NewHTTPProber(c), s: Source{Address: "127.0.0.2"}, expected: "127.0.0.2"},
+		{name: "http again", p: NewHTTPProber(c), s: Source{Address: "127.0.0.3"}, expected: "127.0.0.3"},
+		{name: "http default", p: NewHTTPProber(c), expected: "127.0.0.1"},
+		{name: "interface", p: NewTCPProber(), s: Source{Interface: "lo"}, expected: "127.0.0.1"},
+		{name: "address and interface", p: NewHTTPProber(c), s: Source{Address: "127.0.0.4", Interface: "lo"}, expected: "127.0.0.4"},
+		{name: "invalid address", p: NewTCPProber(), s: Source{Address: "kilo0"}, err: true},
+		{name: "missing interface", p: NewTCPProber(), s: Source{Interface: "does-not-exist0"}, err: true},
+		{name: "http invalid address", p: NewHTTPProber(c), s: Source{Address: "kilo0"}, err: true},
+	} {
+		ctx, used := WithUsedSource(WithSource(context.Background(), tc.s))
+		_, err := tc.p.Probe(ctx, *u)
+		if (err != nil) != tc.err {
+			t.Errorf("test case %q: expected error %t, got %v", tc.name, tc.err, err)
+			continue
+		}
+		if tc.err {
+			continue
+		}
+		if ip := used(); ip != tc.expected {
+			t.Errorf("test case %q: expected used source %q, got %q", tc.name, tc.expected, ip)
+		}
+		if _, ok := tc.p.(*HTTPProber); !ok {
+			continue
+		}
+		select {
+		case r := <-remote:
+			if r != tc.expected {
+				t.Errorf("test case %q: expected server to see %q, got %q", tc.name, tc.expected, r)
+			}
+		default:
+			t.Errorf("test case %q: expected a request", tc.name)
+		}
+	}
+}
+
+func TestSourceClientEviction(t *testing.T) {
+	c := NewSourceClient()
+	source := func(i int) Source {
+		return Source{Address: fmt.Sprintf("127.0.%d.%d", i/256, i%256)}
+	}
+	first, err := c.client(source(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < 2*maxSourceClients; i++ {
+		if _, err := c.client(source(i)); err != nil {
+			t.Fatal(err)
+		}
+		// The first source stays because it is used all the time.
+		if hc, err := c.client(source(0)); err != nil || hc != first {
+			t.Fatalf("expected the client of the first source to be kept, got %v", err)
+		}
+	}
+	if len(c.cs) != maxSourceClients || len(c.lru) != maxSourceClients {
+		t.Errorf("expected %d clients, got %d and %d sources", maxSourceClients, len(c.cs), len(c.lru))
+	}
+	if _, ok := c.cs[source(1)]; ok {
+		t.Error("expected the least recently used source to be dropped")
+	}
+	if _, err := c.client(Source{Address: "invalid"}); err == nil {
+		t.Error("expected an error for an invalid source")
+	}
+}
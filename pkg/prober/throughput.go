@@ -0,0 +1,179 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The directions of throughput tests.
+const (
+	// ThroughputUpload sends the data from the prober to the destination.
+	ThroughputUpload = "upload"
+	// ThroughputDownload sends the data from the destination to the prober.
+	ThroughputDownload = "download"
+)
+
+// ThroughputPath is the path of the endpoint that receives data with POST requests
+// and sends the number of bytes given by the bytes query parameter with GET requests.
+const ThroughputPath = "/throughput/data"
+
+// ThroughputOptions describe a throughput test.
+type ThroughputOptions struct {
+	// Bytes is the total number of bytes that are transferred.
+	Bytes int64
+	// Streams is the number of parallel streams, which share the bytes equally.
+	Streams int
+	// Direction is ThroughputUpload or ThroughputDownload.
+	Direction string
+}
+
+// ThroughputResult is the result of a throughput test.
+type ThroughputResult struct {
+	Bytes    int64
+	Duration time.Duration
+}
+
+// Mbps returns the throughput in Mbit/s.
+func (r ThroughputResult) Mbps() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) * 8 / r.Duration.Seconds() / 1e6
+}
+
+// ThroughputProber tests the throughput to the throughput endpoint of a URL.
+// Every stream is a request over its own TCP connection,
+// which HTTP/1.1 clients open for concurrent requests to the same host.
+// It does not implement the Prober interface, because it measures rates and not durations.
+type ThroughputProber struct {
+	c Client
+}
+
+// NewThroughputProber returns a new ThroughputProber that sends its requests with the given client.
+// It is safe to use concurrently.
+func NewThroughputProber(c Client) *ThroughputProber {
+	return &ThroughputProber{c: c}
+}
+
+// Throughput transfers the bytes of the options in the direction of the options
+// and returns the number of bytes and the time from the start of the first to the end of the last stream.
+// The requests are sent from the source of the context.
+func (p *ThroughputProber) Throughput(ctx context.Context, u url.URL, o ThroughputOptions) (ThroughputResult, error) {
+	if o.Streams < 1 {
+		return ThroughputResult{}, fmt.Errorf("the number of streams must be positive, got %d", o.Streams)
+	}
+	if o.Bytes < int64(o.Streams) {
+		return ThroughputResult{}, fmt.Errorf("the number of bytes must be at least the number of streams, got %d", o.Bytes)
+	}
+	var stream func(context.Context, url.URL, int64) error
+	switch o.Direction {
+	case ThroughputUpload:
+		stream = p.upload
+	case ThroughputDownload:
+		stream = p.download
+	default:
+		return ThroughputResult{}, fmt.Errorf("unknown throughput direction %q", o.Direction)
+	}
+	u.Path = ThroughputPath
+	u.RawQuery = ""
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make([]error, o.Streams)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < o.Streams; i++ {
+		// The first streams carry the remainder.
+		n := o.Bytes / int64(o.Streams)
+		if int64(i) < o.Bytes%int64(o.Streams) {
+			n++
+		}
+		wg.Add(1)
+		go func(i int, n int64) {
+			defer wg.Done()
+			if errs[i] = stream(ctx, u, n); errs[i] != nil {
+				// The result is void if any stream fails.
+				cancel()
+			}
+		}(i, n)
+	}
+	wg.Wait()
+	d := time.Since(start)
+	for _, err := range errs {
+		if err != nil {
+			return ThroughputResult{}, err
+		}
+	}
+	return ThroughputResult{Bytes: o.Bytes, Duration: d}, nil
+}
+
+// upload posts n bytes to the URL, which answers with the number of bytes it received.
+func (p *ThroughputProber) upload(ctx context.Context, u url.URL, n int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), io.LimitReader(zeros{}, n))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.ContentLength = n
+	resp, err := p.c.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	got, err := strconv.ParseInt(strings.TrimSpace(string(body)), 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse the number of received bytes: %w", err)
+	}
+	if got != n {
+		return fmt.Errorf("the destination received %d of %d bytes", got, n)
+	}
+	return nil
+}
+
+// download gets n bytes from the URL.
+func (p *ThroughputProber) download(ctx context.Context, u url.URL, n int64) error {
+	u.RawQuery = url.Values{"bytes": []string{strconv.FormatInt(n, 10)}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := p.c.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	got, err := io.Copy(ioutil.Discard, resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if got != n {
+		return fmt.Errorf("received %d of %d bytes", got, n)
+	}
+	return nil
+}
+
+// zeros is an endless reader of zero bytes.
+type zeros struct{}
+
+func (zeros) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = 0
+	}
+	return len(b), nil
+}
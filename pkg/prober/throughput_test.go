@@ -0,0 +1,98 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestThroughput(t *testing.T) {
+	var mu sync.Mutex
+	var received int64
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != ThroughputPath {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			n, _ := io.Copy(ioutil.Discard, r.Body)
+			mu.Lock()
+			received += n
+			mu.Unlock()
+			// This server loses a byte of every large upload.
+			if n > 1000 {
+				n--
+			}
+			fmt.Fprintln(w, n)
+		case http.MethodGet:
+			n, _ := strconv.ParseInt(r.URL.Query().Get("bytes"), 10, 64)
+			io.Copy(w, io.LimitReader(zeros{}, n))
+		}
+	}))
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewThroughputProber(NewSourceClient())
+	for _, tc := range []struct {
+		name     string
+		o        ThroughputOptions
+		received int64
+		err      bool
+	}{
+		{name: "upload", o: ThroughputOptions{Bytes: 1000, Streams: 1, Direction: ThroughputUpload}, received: 1000},
+		{name: "parallel upload", o: ThroughputOptions{Bytes: 1001, Streams: 4, Direction: ThroughputUpload}, received: 1001},
+		{name: "lost upload", o: ThroughputOptions{Bytes: 2000, Streams: 1, Direction: ThroughputUpload}, received: 2000, err: true},
+		{name: "download", o: ThroughputOptions{Bytes: 1 << 20, Streams: 1, Direction: ThroughputDownload}},
+		{name: "parallel download", o: ThroughputOptions{Bytes: 1 << 20, Streams: 3, Direction: ThroughputDownload}},
+		{name: "no streams", o: ThroughputOptions{Bytes: 1000, Direction: ThroughputUpload}, err: true},
+		{name: "fewer bytes than streams", o: ThroughputOptions{Bytes: 2, Streams: 3, Direction: ThroughputUpload}, err: true},
+		{name: "unknown direction", o: ThroughputOptions{Bytes: 1000, Streams: 1, Direction: "sideways"}, err: true},
+	} {
+		mu.Lock()
+		received = 0
+		mu.Unlock()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		r, err := p.Throughput(ctx, *u, tc.o)
+		cancel()
+		if (err != nil) != tc.err {
+			t.Errorf("test case %q: expected error %t, got %v", tc.name, tc.err, err)
+		}
+		mu.Lock()
+		if received != tc.received {
+			t.Errorf("test case %q: expected the server to receive %d bytes, got %d", tc.name, tc.received, received)
+		}
+		mu.Unlock()
+		if err != nil {
+			continue
+		}
+		if r.Bytes != tc.o.Bytes || r.Duration <= 0 || r.Mbps() <= 0 {
+			t.Errorf("test case %q: unexpected result %+v", tc.name, r)
+		}
+	}
+}
+
+func TestThroughputResultMbps(t *testing.T) {
+	for _, tc := range []struct {
+		r        ThroughputResult
+		expected float64
+	}{
+		{r: ThroughputResult{Bytes: 125000000, Duration: time.Second}, expected: 1000},
+		{r: ThroughputResult{Bytes: 1250000, Duration: 100 * time.Millisecond}, expected: 100},
+		{r: ThroughputResult{Bytes: 1000}},
+	} {
+		if m := tc.r.Mbps(); m != tc.expected {
+			t.Errorf("expected %v Mbit/s for %+v, got %v", tc.expected, tc.r, m)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// TLSDetails describe a TLS handshake.
+type TLSDetails struct {
+	// Connect is the time it took to establish the TCP connection.
+	Connect time.Duration `json:"connect"`
+	// Handshake is the time the TLS handshake took after the TCP connection was established.
+	Handshake   time.Duration `json:"handshake"`
+	Version     string        `json:"version"`
+	CipherSuite string        `json:"cipherSuite"`
+	ALPN        string        `json:"alpn,omitempty"`
+	// NotAfter is the expiry of the certificate of the server.
+	NotAfter time.Time `json:"notAfter"`
+	// NameMatch is true if the certificate of the server is valid for the host of the URL.
+	NameMatch bool `json:"nameMatch"`
+	// Verified is true if the certificate chain of the server is trusted.
+	Verified bool `json:"verified"`
+}
+
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+func tlsVersionName(v uint16) string {
+	if n, ok := tlsVersions[v]; ok {
+		return n
+	}
+	return fmt.Sprintf("0x%04x", v)
+}
+
+// TLSProber implements the Prober interface.
+// It measures the time to establish a TCP connection and to complete a TLS handshake
+// with the host and port of https URLs.
+// Invalid certificates fail the probe, but the handshake is still reported in the details.
+type TLSProber struct {
+	config *tls.Config
+}
+
+// NewTLSProber returns a new TLSProber.
+// The root CAs and the ALPN protocols are taken from the given config, which may be nil.
+// It is safe to use concurrently.
+func NewTLSProber(config *tls.Config) *TLSProber {
+	c := &tls.Config{}
+	if config != nil {
+		c = config.Clone()
+	}
+	if c.NextProtos == nil {
+		c.NextProtos = []string{"h2", "http/1.1"}
+	}
+	// The certificate is verified after the handshake,
+	// so that the details of the handshake are reported even if the certificate is invalid.
+	c.InsecureSkipVerify = true
+	return &TLSProber{config: c}
+}
+
+func (p *TLSProber) Probe(ctx context.Context, u url.URL) (time.Duration, error) {
+	dur, _, err := p.ProbeDetails(ctx, u)
+	return dur, err
+}
+
+func (p *TLSProber) ProbeDetails(ctx context.Context, u url.URL) (time.Duration, *Details, error) {
+	if !p.Supports(u.Scheme) {
+		return 0, nil, fmt.Errorf("%w: %s", ErrUnsupportedScheme, u.Scheme)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	addr := net.JoinHostPort(u.Hostname(), port)
+	start := time.Now()
+	conn, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to establish a TCP connection with %s: %w", addr, err)
+	}
+	defer conn.Close()
+	connected := time.Now()
+	c := p.config.Clone()
+	c.ServerName = u.Hostname()
+	tc := tls.Client(conn, c)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		return 0, nil, fmt.Errorf("failed to complete a TLS handshake with %s: %w", addr, err)
+	}
+	end := time.Now()
+	cs := tc.ConnectionState()
+	d := &TLSDetails{
+		Connect:     connected.Sub(start),
+		Handshake:   end.Sub(connected),
+		Version:     tlsVersionName(cs.Version),
+		CipherSuite: tls.CipherSuiteName(cs.CipherSuite),
+		ALPN:        cs.NegotiatedProtocol,
+	}
+	if len(cs.PeerCertificates) == 0 {
+		return 0, &Details{TLS: d}, fmt.Errorf("%s sent no certificate", addr)
+	}
+	leaf := cs.PeerCertificates[0]
+	d.NotAfter = leaf.NotAfter
+	d.NameMatch = leaf.VerifyHostname(u.Hostname()) == nil
+	opts := x509.VerifyOptions{
+		Roots:         p.config.RootCAs,
+		DNSName:       u.Hostname(),
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(opts); err != nil {
+		return 0, &Details{TLS: d}, fmt.Errorf("the certificate of %s is invalid: %w", addr, err)
+	}
+	d.Verified = true
+	return end.Sub(start), &Details{TLS: d}, nil
+}
+
+func (p *TLSProber) Supports(scheme string) bool {
+	return scheme == "https"
+}
+
+func (p *TLSProber) String() string {
+	return "tls-prober"
+}
@@ -0,0 +1,86 @@
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestTLSProber(t *testing.T) {
+	s := httptest.NewUnstartedServer(http.NotFoundHandler())
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := s.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+	for i, tc := range []struct {
+		name     string
+		config   *tls.Config
+		host     string
+		verified bool
+		match    bool
+	}{
+		{
+			name:     "trusted",
+			config:   &tls.Config{RootCAs: roots},
+			host:     "127.0.0.1",
+			verified: true,
+			match:    true,
+		},
+		{
+			name:   "untrusted",
+			host:   "127.0.0.1",
+			match:  true,
+			config: nil,
+		},
+		{
+			// The certificate of the test server is only valid for 127.0.0.1 and example.com.
+			name:   "other name",
+			config: &tls.Config{RootCAs: roots},
+			host:   "localhost",
+		},
+	} {
+		tu := *u
+		tu.Host = tc.host + ":" + u.Port()
+		dur, d, err := NewTLSProber(tc.config).ProbeDetails(context.Background(), tu)
+		// Only trusted certificates that are valid for the host succeed the probe.
+		if (err == nil) != tc.verified {
+			t.Errorf("%d (%s): expected error %t, got %v", i, tc.name, !tc.verified, err)
+		}
+		if d == nil || d.TLS == nil {
+			t.Errorf("%d (%s): expected TLS details", i, tc.name)
+			continue
+		}
+		td := d.TLS
+		if err == nil && dur != td.Connect+td.Handshake {
+			t.Errorf("%d (%s): expected duration %v to be the sum of connect and handshake, got %v and %v", i, tc.name, dur, td.Connect, td.Handshake)
+		}
+		if td.Version != "TLS 1.3" || td.CipherSuite == "" || td.ALPN != "h2" || td.NotAfter.IsZero() {
+			t.Errorf("%d (%s): got incomplete details %+v", i, tc.name, td)
+		}
+		if td.Verified != tc.verified {
+			t.Errorf("%d (%s): expected verified %t, got %t", i, tc.name, tc.verified, td.Verified)
+		}
+		if td.NameMatch != tc.match {
+			t.Errorf("%d (%s): expected name match %t, got %t", i, tc.name, tc.match, td.NameMatch)
+		}
+	}
+}
+
+func TestTLSProberScheme(t *testing.T) {
+	_, err := NewTLSProber(nil).Probe(context.Background(), url.URL{Scheme: "http", Host: "127.0.0.1:1"})
+	if !errors.Is(err, ErrUnsupportedScheme) {
+		t.Errorf("expected unsupported scheme, got %v", err)
+	}
+	_, err = NewHTTPProber(okClient).Probe(context.Background(), url.URL{Scheme: "dns", Host: "127.0.0.1:53"})
+	if !errors.Is(err, ErrUnsupportedScheme) {
+		t.Errorf("expected unsupported scheme, got %v", err)
+	}
+}
@@ -0,0 +1,435 @@
+package prober
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// The methods of traceroutes, i.e. the protocols of their probes.
+const (
+	TracerouteUDP  = "udp"
+	TracerouteTCP  = "tcp"
+	TracerouteICMP = "icmp"
+)
+
+const (
+	// tracerouteBasePort is the destination port of the UDP probe with the TTL 1, like in traceroute(8).
+	tracerouteBasePort = 33434
+	// defaultTraceTimeout is the time a trace waits for answers if the context has no deadline.
+	defaultTraceTimeout = 3 * time.Second
+	protocolICMP        = 1
+	protocolTCP         = 6
+	protocolUDP         = 17
+
+	// hopGrace is the time a trace waits for the answers of hops before the destination
+	// after the destination answered.
+	hopGrace = 100 * time.Millisecond
+)
+
+// echoID makes the IDs of the echo requests of concurrent probes unique.
+var echoID uint32
+
+func nextEchoID() int {
+	return int(uint32(os.Getpid())+atomic.AddUint32(&echoID, 1)) & 0xffff
+}
+
+// Hop is a router on the path to a destination or the destination itself.
+type Hop struct {
+	TTL int `json:"ttl"`
+	// IP is empty if no answer was received from the hop.
+	IP  string        `json:"ip,omitempty"`
+	RTT time.Duration `json:"rtt,omitempty"`
+}
+
+// TracerouteProber implements the Prober interface.
+// It sends probes with increasing TTLs to the host of the URL and listens for the ICMP
+// time exceeded messages of the routers on the path and for the answer of the destination.
+// All probes are sent at once, so that a trace takes as long as the slowest hop and not as long as all hops together.
+// It needs a raw ICMP socket, i.e. the NET_RAW capability, and only supports IPv4.
+// TCP traceroutes are only supported on Linux.
+type TracerouteProber struct {
+	method  string
+	maxHops int
+}
+
+// NewTracerouteProber returns a new TracerouteProber that uses the given method and sends at most maxHops probes.
+// It is safe to use concurrently.
+func NewTracerouteProber(method string, maxHops int) (*TracerouteProber, error) {
+	switch method {
+	case TracerouteUDP, TracerouteTCP, TracerouteICMP:
+	default:
+		return nil, fmt.Errorf("unknown traceroute method %q", method)
+	}
+	if maxHops < 1 || maxHops > 255 {
+		return nil, fmt.Errorf("the maximum number of hops must be between 1 and 255, got %d", maxHops)
+	}
+	return &TracerouteProber{method: method, maxHops: maxHops}, nil
+}
+
+// Method returns the method of the traceroutes.
+func (p *TracerouteProber) Method() string {
+	return p.method
+}
+
+// Probe returns the round-trip time to the destination as measured by a trace.
+func (p *TracerouteProber) Probe(ctx context.Context, u url.URL) (time.Duration, error) {
+	hops, reached, err := p.Trace(ctx, u)
+	if err != nil {
+		return 0, err
+	}
+	if !reached {
+		return 0, fmt.Errorf("%s was not reached within %d hops", u.Hostname(), p.maxHops)
+	}
+	return hops[len(hops)-1].RTT, nil
+}
+
+func (p *TracerouteProber) String() string {
+	return "traceroute-prober"
+}
+
+// trace is the state of a single traceroute.
+type trace struct {
+	mu   sync.Mutex
+	dst  net.IP
+	id   int
+	sent []time.Time
+	hops []Hop
+	// ports maps the local ports of UDP and TCP probes to their TTLs.
+	ports map[int]int
+	// reached is the lowest TTL at which the destination answered or 0.
+	reached int
+	// reachedAt is the time of the first answer of the destination.
+	reachedAt time.Time
+}
+
+func newTrace(dst net.IP, maxHops int) *trace {
+	t := &trace{
+		dst:   dst,
+		id:    nextEchoID(),
+		sent:  make([]time.Time, maxHops),
+		hops:  make([]Hop, maxHops),
+		ports: make(map[int]int),
+	}
+	for i := range t.hops {
+		t.hops[i].TTL = i + 1
+	}
+	return t
+}
+
+func (t *trace) send(ttl, port int, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sent[ttl-1] = now
+	if port != 0 {
+		t.ports[port] = ttl
+	}
+}
+
+func (t *trace) ttlOf(port int) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ttl, ok := t.ports[port]
+	return ttl, ok
+}
+
+// answer records the first answer to the probe with the given TTL.
+func (t *trace) answer(ttl int, ip net.IP, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ttl < 1 || ttl > len(t.hops) || t.hops[ttl-1].IP != "" || t.sent[ttl-1].IsZero() {
+		return
+	}
+	t.hops[ttl-1].IP = ip.String()
+	t.hops[ttl-1].RTT = now.Sub(t.sent[ttl-1])
+	if ip.Equal(t.dst) && (t.reached == 0 || ttl < t.reached) {
+		if t.reached == 0 {
+			t.reachedAt = now
+		}
+		t.reached = ttl
+	}
+}
+
+// done returns true if the destination answered and either all hops before it answered
+// or the destination answered at least hopGrace before now.
+// Hops that do not answer at all do not hold up the trace.
+func (t *trace) done(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.reached == 0 {
+		return false
+	}
+	if now.Sub(t.reachedAt) >= hopGrace {
+		return true
+	}
+	for _, h := range t.hops[:t.reached] {
+		if h.IP == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// result returns the hops up to the destination
+// or up to the last hop that answered, if the destination did not answer.
+func (t *trace) result() ([]Hop, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := t.reached
+	if n == 0 {
+		for i, h := range t.hops {
+			if h.IP != "" {
+				n = i + 1
+			}
+		}
+	}
+	hops := make([]Hop, n)
+	copy(hops, t.hops)
+	return hops, t.reached != 0
+}
+
+// Trace returns the hops to the host of the URL and whether the host answered.
+// TCP probes are sent to the port of the URL.
+// The probes are sent from the source of the context.
+func (p *TracerouteProber) Trace(ctx context.Context, u url.URL) ([]Hop, bool, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultTraceTimeout)
+		defer cancel()
+	}
+	dst, err := lookupIPv4(ctx, u.Hostname())
+	if err != nil {
+		return nil, false, err
+	}
+	s := SourceFromContext(ctx)
+	var src net.IP
+	if s.Address != "" {
+		if src = net.ParseIP(s.Address).To4(); src == nil {
+			return nil, false, fmt.Errorf("invalid IPv4 source address %q", s.Address)
+		}
+	}
+	laddr := "0.0.0.0"
+	if src != nil {
+		laddr = src.String()
+	}
+	lc := net.ListenConfig{Control: s.control()}
+	c, err := lc.ListenPacket(ctx, "ip4:icmp", laddr)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to listen for ICMP messages: %w", err)
+	}
+	defer c.Close()
+	t := newTrace(dst, p.maxHops)
+	// The TCP probes stop when the trace is done.
+	ctxT, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	switch p.method {
+	case TracerouteICMP:
+		err = p.sendICMP(c, t)
+	case TracerouteUDP:
+		var closers []func() error
+		closers, err = p.sendUDP(ctxT, s, t)
+		for _, closeConn := range closers {
+			defer closeConn()
+		}
+	case TracerouteTCP:
+		port := u.Port()
+		if port == "" {
+			port = "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+		}
+		p.sendTCP(ctxT, &wg, s, src, net.JoinHostPort(dst.String(), port), t)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if err := p.receive(ctx, c, t); err != nil {
+		return nil, false, err
+	}
+	hops, reached := t.result()
+	return hops, reached, nil
+}
+
+func lookupIPv4(ctx context.Context, host string) (net.IP, error) {
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, ip := range ips {
+		if ip4 := ip.IP.To4(); ip4 != nil {
+			return ip4, nil
+		}
+	}
+	return nil, fmt.Errorf("%s has no IPv4 address", host)
+}
+
+func (p *TracerouteProber) sendICMP(c net.PacketConn, t *trace) error {
+	pc := ipv4.NewPacketConn(c)
+	for ttl := 1; ttl <= p.maxHops; ttl++ {
+		if err := pc.SetTTL(ttl); err != nil {
+			return fmt.Errorf("failed to set TTL: %w", err)
+		}
+		m := icmp.Message{Type: ipv4.ICMPTypeEcho, Body: &icmp.Echo{ID: t.id, Seq: ttl, Data: []byte("adjacency")}}
+		b, err := m.Marshal(nil)
+		if err != nil {
+			return fmt.Errorf("failed to build ICMP echo request: %w", err)
+		}
+		t.send(ttl, 0, time.Now())
+		if _, err := c.WriteTo(b, &net.IPAddr{IP: t.dst}); err != nil {
+			return fmt.Errorf("failed to send ICMP echo request: %w", err)
+		}
+	}
+	return nil
+}
+
+// sendUDP sends the UDP probes and returns the functions that close their sockets.
+// The sockets stay open until the trace is done, so that their ports are not reused.
+func (p *TracerouteProber) sendUDP(ctx context.Context, s Source, t *trace) ([]func() error, error) {
+	d, err := s.dialer("udp4")
+	if err != nil {
+		return nil, err
+	}
+	var closers []func() error
+	for ttl := 1; ttl <= p.maxHops; ttl++ {
+		conn, err := d.DialContext(ctx, "udp4", net.JoinHostPort(t.dst.String(), strconv.Itoa(tracerouteBasePort+ttl-1)))
+		if err != nil {
+			return closers, fmt.Errorf("failed to create UDP socket: %w", err)
+		}
+		closers = append(closers, conn.Close)
+		if err := ipv4.NewConn(conn).SetTTL(ttl); err != nil {
+			return closers, fmt.Errorf("failed to set TTL: %w", err)
+		}
+		t.send(ttl, conn.LocalAddr().(*net.UDPAddr).Port, time.Now())
+		// Errors are reported to the socket by ICMP messages of earlier probes, so they are ignored.
+		conn.Write([]byte("adjacency"))
+	}
+	return closers, nil
+}
+
+// sendTCP starts connections with increasing TTLs.
+// Connections that are established or refused were answered by the destination.
+func (p *TracerouteProber) sendTCP(ctx context.Context, wg *sync.WaitGroup, s Source, src net.IP, addr string, t *trace) {
+	bind := s.control()
+	for ttl := 1; ttl <= p.maxHops; ttl++ {
+		ttl := ttl
+		d := net.Dialer{Control: func(network, address string, c syscall.RawConn) error {
+			if bind != nil {
+				if err := bind(network, address, c); err != nil {
+					return err
+				}
+			}
+			var port int
+			var err error
+			if cerr := c.Control(func(fd uintptr) {
+				port, err = prepareTraceSocket(fd, ttl, src)
+			}); cerr != nil {
+				return cerr
+			}
+			if err != nil {
+				return fmt.Errorf("failed to prepare TCP socket: %w", err)
+			}
+			t.send(ttl, port, time.Now())
+			return nil
+		}}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := d.DialContext(ctx, "tcp4", addr)
+			now := time.Now()
+			if err == nil {
+				conn.Close()
+			}
+			if err == nil || errors.Is(err, syscall.ECONNREFUSED) {
+				t.answer(ttl, t.dst, now)
+			}
+		}()
+	}
+}
+
+// receive reads ICMP messages until the trace is done or the context expires.
+func (p *TracerouteProber) receive(ctx context.Context, c net.PacketConn, t *trace) error {
+	buf := make([]byte, 1500)
+	for !t.done(time.Now()) {
+		if ctx.Err() != nil {
+			return nil
+		}
+		// Read in short intervals, so that answers to TCP probes end the trace early.
+		deadline := time.Now().Add(100 * time.Millisecond)
+		if d, _ := ctx.Deadline(); d.Before(deadline) {
+			deadline = d
+		}
+		if err := c.SetReadDeadline(deadline); err != nil {
+			return fmt.Errorf("failed to set read deadline: %w", err)
+		}
+		n, peer, err := c.ReadFrom(buf)
+		if err != nil {
+			var nerr net.Error
+			if errors.As(err, &nerr) && nerr.Timeout() {
+				continue
+			}
+			return fmt.Errorf("failed to read ICMP message: %w", err)
+		}
+		now := time.Now()
+		ip, ok := peer.(*net.IPAddr)
+		if !ok {
+			continue
+		}
+		if ttl, ok := p.match(t, buf[:n]); ok {
+			t.answer(ttl, ip.IP, now)
+		}
+	}
+	return nil
+}
+
+// match returns the TTL of the probe that the ICMP message answers.
+func (p *TracerouteProber) match(t *trace, b []byte) (int, bool) {
+	m, err := icmp.ParseMessage(protocolICMP, b)
+	if err != nil {
+		return 0, false
+	}
+	var data []byte
+	switch body := m.Body.(type) {
+	case *icmp.Echo:
+		if m.Type != ipv4.ICMPTypeEchoReply || p.method != TracerouteICMP || body.ID != t.id {
+			return 0, false
+		}
+		return body.Seq, true
+	case *icmp.TimeExceeded:
+		data = body.Data
+	case *icmp.DstUnreach:
+		data = body.Data
+	default:
+		return 0, false
+	}
+	// The data is the IP header and the first eight bytes of the probe.
+	h, err := ipv4.ParseHeader(data)
+	if err != nil || !h.Dst.Equal(t.dst) || len(data) < h.Len+8 {
+		return 0, false
+	}
+	probe := data[h.Len:]
+	switch {
+	case p.method == TracerouteICMP && h.Protocol == protocolICMP:
+		if probe[0] != byte(ipv4.ICMPTypeEcho) || int(binary.BigEndian.Uint16(probe[4:6])) != t.id {
+			return 0, false
+		}
+		return int(binary.BigEndian.Uint16(probe[6:8])), true
+	case p.method == TracerouteUDP && h.Protocol == protocolUDP, p.method == TracerouteTCP && h.Protocol == protocolTCP:
+		return t.ttlOf(int(binary.BigEndian.Uint16(probe[0:2])))
+	}
+	return 0, false
+}
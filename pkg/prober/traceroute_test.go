@@ -0,0 +1,215 @@
+package prober
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// quote returns the IP header and the first eight bytes of a probe to dst,
+// as they are quoted in ICMP error messages.
+func quote(t *testing.T, dst net.IP, protocol int, probe []byte) []byte {
+	h := &ipv4.Header{Version: 4, Len: ipv4.HeaderLen, TotalLen: ipv4.HeaderLen + len(probe), TTL: 1, Protocol: protocol, Src: net.IPv4(10, 0, 0, 1), Dst: dst}
+	b, err := h.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(b, probe...)
+}
+
+func ports(src, dst int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint16(b[0:2], uint16(src))
+	binary.BigEndian.PutUint16(b[2:4], uint16(dst))
+	return b
+}
+
+func TestTracerouteMatch(t *testing.T) {
+	dst := net.IPv4(192, 0, 2, 1).To4()
+	echo := func(id, seq int) []byte {
+		b, err := (&icmp.Message{Type: ipv4.ICMPTypeEcho, Body: &icmp.Echo{ID: id, Seq: seq}}).Marshal(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	marshal := func(m icmp.Message) []byte {
+		b, err := m.Marshal(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	for _, tc := range []struct {
+		name   string
+		method string
+		msg    []byte
+		ttl    int
+		ok     bool
+	}{
+		{
+			name:   "echo reply",
+			method: TracerouteICMP,
+			msg:    marshal(icmp.Message{Type: ipv4.ICMPTypeEchoReply, Body: &icmp.Echo{ID: 7, Seq: 3}}),
+			ttl:    3,
+			ok:     true,
+		},
+		{
+			name:   "echo reply of another trace",
+			method: TracerouteICMP,
+			msg:    marshal(icmp.Message{Type: ipv4.ICMPTypeEchoReply, Body: &icmp.Echo{ID: 8, Seq: 3}}),
+		},
+		{
+			name:   "echo request",
+			method: TracerouteICMP,
+			msg:    echo(7, 3),
+		},
+		{
+			name:   "time exceeded for echo",
+			method: TracerouteICMP,
+			msg:    marshal(icmp.Message{Type: ipv4.ICMPTypeTimeExceeded, Body: &icmp.TimeExceeded{Data: quote(t, dst, protocolICMP, echo(7, 2)[:8])}}),
+			ttl:    2,
+			ok:     true,
+		},
+		{
+			name:   "time exceeded for UDP",
+			method: TracerouteUDP,
+			msg:    marshal(icmp.Message{Type: ipv4.ICMPTypeTimeExceeded, Body: &icmp.TimeExceeded{Data: quote(t, dst, protocolUDP, ports(40000, tracerouteBasePort))}}),
+			ttl:    1,
+			ok:     true,
+		},
+		{
+			name:   "port unreachable for UDP",
+			method: TracerouteUDP,
+			msg:    marshal(icmp.Message{Type: ipv4.ICMPTypeDestinationUnreachable, Code: 3, Body: &icmp.DstUnreach{Data: quote(t, dst, protocolUDP, ports(40001, tracerouteBasePort+1))}}),
+			ttl:    2,
+			ok:     true,
+		},
+		{
+			name:   "UDP from another socket",
+			method: TracerouteUDP,
+			msg:    marshal(icmp.Message{Type: ipv4.ICMPTypeTimeExceeded, Body: &icmp.TimeExceeded{Data: quote(t, dst, protocolUDP, ports(40002, tracerouteBasePort))}}),
+		},
+		{
+			name:   "UDP to another destination",
+			method: TracerouteUDP,
+			msg:    marshal(icmp.Message{Type: ipv4.ICMPTypeTimeExceeded, Body: &icmp.TimeExceeded{Data: quote(t, net.IPv4(192, 0, 2, 2), protocolUDP, ports(40000, tracerouteBasePort))}}),
+		},
+		{
+			name:   "time exceeded for TCP",
+			method: TracerouteTCP,
+			msg:    marshal(icmp.Message{Type: ipv4.ICMPTypeTimeExceeded, Body: &icmp.TimeExceeded{Data: quote(t, dst, protocolTCP, ports(40001, 80))}}),
+			ttl:    2,
+			ok:     true,
+		},
+		{
+			name:   "UDP in a TCP trace",
+			method: TracerouteTCP,
+			msg:    marshal(icmp.Message{Type: ipv4.ICMPTypeTimeExceeded, Body: &icmp.TimeExceeded{Data: quote(t, dst, protocolUDP, ports(40001, 80))}}),
+		},
+		{
+			name:   "garbage",
+			method: TracerouteUDP,
+			msg:    []byte{11, 0},
+		},
+	} {
+		p, err := NewTracerouteProber(tc.method, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr := newTrace(dst, 3)
+		tr.id = 7
+		tr.ports[40000] = 1
+		tr.ports[40001] = 2
+		ttl, ok := p.match(tr, tc.msg)
+		if ttl != tc.ttl || ok != tc.ok {
+			t.Errorf("test case %q: expected %d, %t, got %d, %t", tc.name, tc.ttl, tc.ok, ttl, ok)
+		}
+	}
+}
+
+func TestTraceResult(t *testing.T) {
+	dst := net.IPv4(192, 0, 2, 1)
+	start := time.Now()
+	tr := newTrace(dst, 5)
+	for ttl := 1; ttl <= 5; ttl++ {
+		tr.send(ttl, 0, start)
+	}
+	tr.answer(1, net.IPv4(10, 0, 0, 1), start.Add(time.Millisecond))
+	tr.answer(3, dst, start.Add(3*time.Millisecond))
+	tr.answer(4, dst, start.Add(4*time.Millisecond))
+	if tr.done(start.Add(4 * time.Millisecond)) {
+		t.Error("expected the trace to wait for the second hop")
+	}
+	if !tr.done(start.Add(3*time.Millisecond + hopGrace)) {
+		t.Error("expected the trace to stop waiting for the second hop")
+	}
+	hops, reached := tr.result()
+	expected := []Hop{
+		{TTL: 1, IP: "10.0.0.1", RTT: time.Millisecond},
+		{TTL: 2},
+		{TTL: 3, IP: "192.0.2.1", RTT: 3 * time.Millisecond},
+	}
+	if diff := pretty.Compare(hops, expected); diff != "" || !reached {
+		t.Errorf("expected the destination to be reached, got diff:\n%s", diff)
+	}
+	tr.answer(2, net.IPv4(10, 0, 0, 2), start.Add(2*time.Millisecond))
+	// Later answers do not overwrite earlier ones.
+	tr.answer(2, net.IPv4(10, 0, 0, 3), start.Add(5*time.Millisecond))
+	if !tr.done(start.Add(4 * time.Millisecond)) {
+		t.Error("expected the trace to be done")
+	}
+
+	tr = newTrace(dst, 5)
+	for ttl := 1; ttl <= 5; ttl++ {
+		tr.send(ttl, 0, start)
+	}
+	tr.answer(2, net.IPv4(10, 0, 0, 2), start.Add(2*time.Millisecond))
+	hops, reached = tr.result()
+	expected = []Hop{{TTL: 1}, {TTL: 2, IP: "10.0.0.2", RTT: 2 * time.Millisecond}}
+	if diff := pretty.Compare(hops, expected); diff != "" || reached {
+		t.Errorf("expected the destination not to be reached, got diff:\n%s", diff)
+	}
+}
+
+func TestTraceroute(t *testing.T) {
+	if c, err := net.ListenPacket("ip4:icmp", "127.0.0.1"); err != nil {
+		t.Skipf("raw ICMP sockets are not permitted: %v", err)
+	} else {
+		c.Close()
+	}
+	s := httptest.NewServer(http.NotFoundHandler())
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range []string{TracerouteICMP, TracerouteUDP, TracerouteTCP} {
+		p, err := NewTracerouteProber(method, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		hops, reached, err := p.Trace(ctx, *u)
+		cancel()
+		if err != nil {
+			t.Errorf("method %s: failed to trace: %v", method, err)
+			continue
+		}
+		if !reached || len(hops) != 1 || hops[0].IP != "127.0.0.1" {
+			t.Errorf("method %s: expected to reach 127.0.0.1 in one hop, got %+v", method, hops)
+		}
+	}
+	if _, err := NewTracerouteProber("sctp", 4); err == nil {
+		t.Error("expected an error for an unknown method")
+	}
+}
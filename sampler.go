@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"log"
+	mrand "math/rand"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/kilo-io/adjacency_service/pkg/prober"
+)
+
+type sample struct {
+	l Latency
+	t time.Time
+}
+
+// sampler probes a rotating random subset of the nodes of the adjacency service per round,
+// so that large clusters do not have to probe all pairs of nodes for every request.
+// It remembers the latest sample of every node, so that the vectors of all nodes
+// together form a sparse matrix that fills up over time.
+// It is safe to use concurrently.
+type sampler struct {
+	srv     string
+	probers []prober.Prober
+	timeout time.Duration
+	// size is the number of nodes that are probed per round.
+	size int
+	// interval is the time between two rounds.
+	interval time.Duration
+	// coverage is the maximum time after which every node must have been probed.
+	// If it is set, size is increased as needed.
+	coverage time.Duration
+	c        *vivaldi
+
+	mu      sync.Mutex
+	samples map[string]sample
+	// queue are the nodes that still need to be probed in the current cycle
+	// and probed are the nodes that were already probed in it in their order,
+	// which the next cycle repeats.
+	queue  []*url.URL
+	probed []*url.URL
+	rand   *mrand.Rand
+}
+
+func newSampler(srv string, probers []prober.Prober, timeout time.Duration, size int, interval, coverage time.Duration, c *vivaldi) *sampler {
+	return &sampler{
+		srv:      srv,
+		probers:  probers,
+		timeout:  timeout,
+		size:     size,
+		interval: interval,
+		coverage: coverage,
+		c:        c,
+		samples:  make(map[string]sample),
+		rand:     mrand.New(mrand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// sampleSize returns the number of nodes that need to be probed per round,
+// so that all n nodes are probed within the coverage.
+func (s *sampler) sampleSize(n int) int {
+	k := s.size
+	if s.coverage > 0 {
+		rounds := int(s.coverage / s.interval)
+		if rounds < 1 {
+			return n
+		}
+		if need := (n + rounds - 1) / rounds; need > k {
+			k = need
+		}
+	}
+	if k > n {
+		k = n
+	}
+	return k
+}
+
+// next returns the nodes that should be probed in the next round.
+// Every node is probed once per cycle. The order of the nodes is random,
+// but every cycle keeps the order of the previous one,
+// so that the time between two probes of a node is the length of a cycle.
+// Nodes that appear during a cycle are probed at the end of the same cycle.
+func (s *sampler) next(nodes []*url.URL) []*url.URL {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current := make(map[string]bool, len(nodes))
+	for _, u := range nodes {
+		current[u.String()] = true
+	}
+	// Drop nodes that disappeared.
+	known := make(map[string]bool, len(s.queue)+len(s.probed))
+	filter := func(us []*url.URL) []*url.URL {
+		var f []*url.URL
+		for _, u := range us {
+			known[u.String()] = true
+			if current[u.String()] {
+				f = append(f, u)
+			}
+		}
+		return f
+	}
+	queue, probed := filter(s.queue), filter(s.probed)
+	var added []*url.URL
+	for _, u := range nodes {
+		if !known[u.String()] {
+			added = append(added, u)
+		}
+	}
+	s.rand.Shuffle(len(added), func(i, j int) {
+		added[i], added[j] = added[j], added[i]
+	})
+	queue = append(queue, added...)
+	if len(queue) == 0 {
+		queue, probed = probed, nil
+	}
+	k := s.sampleSize(len(nodes))
+	if k > len(queue) {
+		k = len(queue)
+	}
+	next := queue[:k]
+	s.queue = queue[k:]
+	s.probed = append(probed, next...)
+	return next
+}
+
+func (s *sampler) round(ctx context.Context) {
+	nodes, err := resolveSRV(s.srv, "", "")
+	if err != nil {
+		log.Printf("failed to resolve SRV record: %v\n", err)
+		errorCounter.Inc()
+		return
+	}
+	lats := getLatencies(ctx, s.probers, s.next(nodes), s.timeout)
+	now := time.Now()
+	s.mu.Lock()
+	for _, l := range lats {
+		s.samples[l.Destination] = sample{l: *l, t: now}
+	}
+	s.mu.Unlock()
+	if s.c != nil {
+		s.c.UpdateFrom(ctx, lats)
+	}
+}
+
+// Run probes a sample of nodes every interval until the context is canceled.
+func (s *sampler) Run(ctx context.Context) {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		s.round(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Latencies returns the latest samples of the given nodes with their age.
+// Nodes that were not probed yet are omitted.
+func (s *sampler) Latencies(nodes []*url.URL, now time.Time) []*Latency {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var lats []*Latency
+	for _, u := range nodes {
+		sa, ok := s.samples[u.String()]
+		if !ok {
+			continue
+		}
+		l := sa.l
+		l.Age = now.Sub(sa.t)
+		lats = append(lats, &l)
+	}
+	return lats
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	mrand "math/rand"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestSampleSize(t *testing.T) {
+	for i, tc := range []struct {
+		name     string
+		size     int
+		interval time.Duration
+		coverage time.Duration
+		n        int
+		k        int
+	}{
+		{name: "no coverage", size: 3, interval: time.Second, n: 10, k: 3},
+		{name: "larger than cluster", size: 30, interval: time.Second, n: 10, k: 10},
+		{name: "coverage satisfied", size: 3, interval: time.Second, coverage: 5 * time.Second, n: 10, k: 3},
+		{name: "coverage increases size", size: 1, interval: time.Second, coverage: 3 * time.Second, n: 10, k: 4},
+		{name: "coverage shorter than interval", size: 1, interval: time.Second, coverage: time.Millisecond, n: 10, k: 10},
+	} {
+		s := newSampler("", nil, 0, tc.size, tc.interval, tc.coverage, nil)
+		if k := s.sampleSize(tc.n); k != tc.k {
+			t.Errorf("%d (%s): got %d, expected %d", i, tc.name, k, tc.k)
+		}
+	}
+}
+
+func TestSamplerNext(t *testing.T) {
+	var nodes []*url.URL
+	for i := 0; i < 10; i++ {
+		nodes = append(nodes, &url.URL{Scheme: "http", Host: fmt.Sprintf("n%d:3000", i)})
+	}
+	s := newSampler("", nil, 0, 3, time.Second, 0, nil)
+	s.rand = mrand.New(mrand.NewSource(1))
+	// Every node must be probed exactly once per cycle of four rounds.
+	for cycle := 0; cycle < 3; cycle++ {
+		seen := make(map[string]int)
+		for round := 0; round < 4; round++ {
+			for _, u := range s.next(nodes) {
+				seen[u.Host]++
+			}
+		}
+		if len(seen) != len(nodes) {
+			t.Errorf("cycle %d: expected %d nodes to be probed, got %d", cycle, len(nodes), len(seen))
+		}
+		for h, n := range seen {
+			if n != 1 {
+				t.Errorf("cycle %d: expected %s to be probed once, got %d", cycle, h, n)
+			}
+		}
+	}
+	// Nodes that disappear are not probed anymore
+	// and new nodes are probed in the current cycle.
+	s = newSampler("", nil, 0, 3, time.Second, 0, nil)
+	s.next(nodes)
+	current := append([]*url.URL{{Scheme: "http", Host: "new:3000"}}, nodes[5:]...)
+	seen := make(map[string]bool)
+	for round := 0; round < 3; round++ {
+		for _, u := range s.next(current) {
+			seen[u.Host] = true
+		}
+	}
+	if !seen["new:3000"] {
+		t.Error("expected new node to be probed")
+	}
+	for _, u := range nodes[:5] {
+		if seen[u.Host] {
+			t.Errorf("expected %s to not be probed anymore", u.Host)
+		}
+	}
+}
+
+func TestSamplerGap(t *testing.T) {
+	for i, tc := range []struct {
+		n        int
+		coverage time.Duration
+		// gap is the largest number of rounds between two probes of a node.
+		gap int
+	}{
+		{n: 4, coverage: 2 * time.Second, gap: 2},
+		{n: 7, coverage: 3 * time.Second, gap: 3},
+		{n: 10, coverage: 4 * time.Second, gap: 4},
+	} {
+		var nodes []*url.URL
+		for j := 0; j < tc.n; j++ {
+			nodes = append(nodes, &url.URL{Scheme: "http", Host: fmt.Sprintf("n%d:3000", j)})
+		}
+		for seed := int64(0); seed < 10; seed++ {
+			s := newSampler("", nil, 0, 1, time.Second, tc.coverage, nil)
+			s.rand = mrand.New(mrand.NewSource(seed))
+			last := make(map[string]int)
+			gap := 0
+			for round := 0; round < 10*tc.gap; round++ {
+				for _, u := range s.next(nodes) {
+					if r, ok := last[u.Host]; ok && round-r > gap {
+						gap = round - r
+					}
+					last[u.Host] = round
+				}
+			}
+			if gap > tc.gap {
+				t.Errorf("%d (seed %d): expected every node to be probed at least every %d rounds, got a gap of %d", i, seed, tc.gap, gap)
+			}
+		}
+	}
+}
+
+func TestSamplerLatencies(t *testing.T) {
+	now := time.Now()
+	s := newSampler("", nil, 0, 1, time.Second, 0, nil)
+	s.samples["http://n0:3000"] = sample{l: Latency{Destination: "http://n0:3000", Ok: true, Duration: time.Millisecond}, t: now.Add(-5 * time.Second)}
+	s.samples["http://n1:3000"] = sample{l: Latency{Destination: "http://n1:3000", Ok: true, Duration: time.Millisecond}, t: now}
+	nodes := []*url.URL{
+		{Scheme: "http", Host: "n0:3000"},
+		{Scheme: "http", Host: "n2:3000"},
+	}
+	el := []*Latency{
+		{Destination: "http://n0:3000", Ok: true, Duration: time.Millisecond, Age: 5 * time.Second},
+	}
+	if diff := pretty.Compare(s.Latencies(nodes, now), el); diff != "" {
+		t.Errorf("unexpected latencies:\n%s", diff)
+	}
+	if s := el[0].String(); s != "1ms (5s)" {
+		t.Errorf("got %q, expected the age in the output", s)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+// A requirement is a single term of a label selector.
+type requirement struct {
+	key string
+	// op is one of "=", "!=", "in", "notin", "exists" and "!exists".
+	op     string
+	values []string
+}
+
+func (r requirement) matches(labels map[string]string) bool {
+	v, ok := labels[r.key]
+	if !ok {
+		v, ok = labels[labelAliases[r.key]]
+	}
+	switch r.op {
+	case "exists":
+		return ok
+	case "!exists":
+		return !ok
+	case "=", "in":
+		if !ok {
+			return false
+		}
+		for _, val := range r.values {
+			if v == val {
+				return true
+			}
+		}
+		return false
+	case "!=", "notin":
+		for _, val := range r.values {
+			if ok && v == val {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// A selector selects nodes either by their labels, using the syntax of
+// Kubernetes label selectors, e.g. "zone in (a,b),role!=edge",
+// or by their host name, using a glob pattern, e.g. "node-*.example.com".
+// Selectors without any of the characters "=", "!" and "(" are glob patterns.
+type selector struct {
+	glob         string
+	requirements []requirement
+}
+
+// splitSelector splits a label selector at every comma that is not in parentheses.
+func splitSelector(s string) ([]string, error) {
+	var terms []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses in %q", s)
+			}
+		case ',':
+			if depth == 0 {
+				terms = append(terms, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in %q", s)
+	}
+	return append(terms, s[start:]), nil
+}
+
+func parseRequirement(term string) (requirement, error) {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return requirement{}, errors.New("empty term")
+	}
+	for _, op := range []string{" notin ", " in "} {
+		if i := strings.Index(term, op); i > 0 {
+			list := strings.TrimSpace(term[i+len(op):])
+			if !strings.HasPrefix(list, "(") || !strings.HasSuffix(list, ")") {
+				return requirement{}, fmt.Errorf("values of %q must be in parentheses", term)
+			}
+			var values []string
+			for _, v := range strings.Split(list[1:len(list)-1], ",") {
+				values = append(values, strings.TrimSpace(v))
+			}
+			return requirement{key: strings.TrimSpace(term[:i]), op: strings.TrimSpace(op), values: values}, nil
+		}
+	}
+	for _, op := range []string{"!=", "==", "="} {
+		if i := strings.Index(term, op); i >= 0 {
+			key := strings.TrimSpace(term[:i])
+			if key == "" {
+				return requirement{}, fmt.Errorf("missing key in %q", term)
+			}
+			value := strings.TrimSpace(term[i+len(op):])
+			if op == "==" {
+				op = "="
+			}
+			return requirement{key: key, op: op, values: []string{value}}, nil
+		}
+	}
+	if strings.HasPrefix(term, "!") {
+		return requirement{key: strings.TrimSpace(term[1:]), op: "!exists"}, nil
+	}
+	if strings.ContainsAny(term, " ()") {
+		return requirement{}, fmt.Errorf("invalid term %q", term)
+	}
+	return requirement{key: term, op: "exists"}, nil
+}
+
+// isLabelSelector returns true if the string contains any of the characters =, ! and (
+// outside of the character classes of a glob pattern, e.g. [!a]*, and unescaped.
+func isLabelSelector(s string) bool {
+	class := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case c == '[' && !class:
+			class = true
+		case c == ']' && class:
+			class = false
+		case !class && strings.IndexByte("=!(", c) >= 0:
+			return true
+		}
+	}
+	return false
+}
+
+func parseSelector(s string) (*selector, error) {
+	if !isLabelSelector(s) {
+		if _, err := path.Match(s, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", s, err)
+		}
+		return &selector{glob: s}, nil
+	}
+	terms, err := splitSelector(s)
+	if err != nil {
+		return nil, err
+	}
+	sel := &selector{}
+	for _, t := range terms {
+		r, err := parseRequirement(t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse label selector: %w", err)
+		}
+		sel.requirements = append(sel.requirements, r)
+	}
+	return sel, nil
+}
+
+// selectorFromValues parses the selector in the given query parameter.
+// It returns nil if the parameter is not set.
+func selectorFromValues(q url.Values, param string) (*selector, error) {
+	s := q.Get(param)
+	if s == "" {
+		return nil, nil
+	}
+	sel, err := parseSelector(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", param, err)
+	}
+	return sel, nil
+}
+
+// needsLabels returns true if the labels of the nodes are needed to evaluate the selector.
+func (s *selector) needsLabels() bool {
+	return len(s.requirements) > 0
+}
+
+// Matches returns true if the node with the given host name and labels is selected.
+func (s *selector) Matches(host string, labels map[string]string) bool {
+	if !s.needsLabels() {
+		ok, _ := path.Match(s.glob, host)
+		return ok
+	}
+	for _, r := range s.requirements {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// fetchLabels gets the labels that the adjacency service at the given URL advertises.
+func fetchLabels(ctx context.Context, u url.URL) (map[string]string, error) {
+	u.Path = "/labels"
+	u.RawQuery = ""
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make GET request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("expected status code 200, got %d", resp.StatusCode)
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(body, &labels); err != nil {
+		return nil, fmt.Errorf("response from node has wrong format: maybe it is not running this service?: %w", err)
+	}
+	return labels, nil
+}
+
+// filterURLs returns the URLs of the nodes that are selected by the selector.
+// If the selector needs labels, they are fetched from every node concurrently;
+// nodes whose labels cannot be fetched are treated as having no labels.
+func filterURLs(ctx context.Context, urls []*url.URL, s *selector) []*url.URL {
+	if s == nil {
+		return urls
+	}
+	labels := make([]map[string]string, len(urls))
+	if s.needsLabels() {
+		var wg sync.WaitGroup
+		for i := range urls {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				var err error
+				if labels[i], err = fetchLabels(ctx, *urls[i]); err != nil {
+					log.Printf("failed to get labels from %s: %v\n", urls[i].Host, err)
+				}
+			}(i)
+		}
+		wg.Wait()
+	}
+	var filtered []*url.URL
+	for i, u := range urls {
+		if s.Matches(u.Hostname(), labels[i]) {
+			filtered = append(filtered, u)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestParseSelector(t *testing.T) {
+	for i, tc := range []struct {
+		name string
+		s    string
+		sel  *selector
+		err  bool
+	}{
+		{
+			name: "glob",
+			s:    "node-*.example.com",
+			sel:  &selector{glob: "node-*.example.com"},
+		},
+		{
+			name: "glob with negated class",
+			s:    "node-[!a]*",
+			sel:  &selector{glob: "node-[!a]*"},
+		},
+		{
+			name: "invalid glob",
+			s:    "node-[",
+			err:  true,
+		},
+		{
+			name: "equality",
+			s:    "zone=a,region==eu,role!=edge",
+			sel: &selector{requirements: []requirement{
+				{key: "zone", op: "=", values: []string{"a"}},
+				{key: "region", op: "=", values: []string{"eu"}},
+				{key: "role", op: "!=", values: []string{"edge"}},
+			}},
+		},
+		{
+			name: "set",
+			s:    "zone in (a, b),location notin (x),gpu,!spot",
+			sel: &selector{requirements: []requirement{
+				{key: "zone", op: "in", values: []string{"a", "b"}},
+				{key: "location", op: "notin", values: []string{"x"}},
+				{key: "gpu", op: "exists"},
+				{key: "spot", op: "!exists"},
+			}},
+		},
+		{
+			name: "unbalanced",
+			s:    "zone in (a,b",
+			err:  true,
+		},
+		{
+			name: "missing parentheses",
+			s:    "zone in a,b=c",
+			err:  true,
+		},
+		{
+			name: "missing key",
+			s:    "=a",
+			err:  true,
+		},
+	} {
+		sel, err := parseSelector(tc.s)
+		if (err != nil) != tc.err {
+			t.Errorf("%d (%s): got error %v, expected error = %t", i, tc.name, err, tc.err)
+			continue
+		}
+		if diff := pretty.Compare(sel, tc.sel); !tc.err && diff != "" {
+			t.Errorf("%d (%s): unexpected selector:\n%s", i, tc.name, diff)
+		}
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{
+		"topology.kubernetes.io/zone": "a",
+		"role":                        "worker",
+	}
+	for i, tc := range []struct {
+		s      string
+		host   string
+		labels map[string]string
+		ok     bool
+	}{
+		{s: "node-*", host: "node-1", ok: true},
+		{s: "node-*", host: "edge-1", ok: false},
+		{s: "zone=a", labels: labels, ok: true},
+		{s: "zone=b", labels: labels, ok: false},
+		{s: "zone in (b,a),role", labels: labels, ok: true},
+		{s: "zone notin (a)", labels: labels, ok: false},
+		{s: "role!=edge", labels: nil, ok: true},
+		{s: "!role", labels: labels, ok: false},
+		{s: "zone=a", labels: nil, ok: false},
+	} {
+		sel, err := parseSelector(tc.s)
+		if err != nil {
+			t.Fatalf("%d: failed to parse selector: %v", i, err)
+		}
+		if ok := sel.Matches(tc.host, tc.labels); ok != tc.ok {
+			t.Errorf("%d (%s): got %t, expected %t", i, tc.s, ok, tc.ok)
+		}
+	}
+}
+
+func TestFilterURLs(t *testing.T) {
+	a := httptest.NewServer(http.HandlerFunc(labelsHandler(&labeler{static: labelsFlag{"zone": "a"}})))
+	defer a.Close()
+	b := httptest.NewServer(http.HandlerFunc(labelsHandler(&labeler{static: labelsFlag{"zone": "b"}})))
+	defer b.Close()
+	// A server that does not run the adjacency service.
+	c := httptest.NewServer(http.NotFoundHandler())
+	defer c.Close()
+	var urls []*url.URL
+	for _, s := range []*httptest.Server{a, b, c} {
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls = append(urls, u)
+	}
+	for i, tc := range []struct {
+		s    string
+		urls []*url.URL
+	}{
+		{s: "zone=a", urls: urls[:1]},
+		{s: "zone!=a", urls: urls[1:]},
+		{s: "zone in (a,b)", urls: urls[:2]},
+		{s: "127.0.0.*", urls: urls},
+	} {
+		sel, err := parseSelector(tc.s)
+		if err != nil {
+			t.Fatalf("%d: failed to parse selector: %v", i, err)
+		}
+		if diff := pretty.Compare(filterURLs(context.Background(), urls, sel), tc.urls); diff != "" {
+			t.Errorf("%d (%s): unexpected URLs:\n%s", i, tc.s, diff)
+		}
+	}
+}
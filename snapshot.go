@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Snapshot is a matrix that was collected at a specific time.
+type Snapshot struct {
+	ID     int       `json:"id"`
+	Time   time.Time `json:"time"`
+	Matrix matrix    `json:"matrix"`
+}
+
+// snapshotStore keeps the latest snapshots of the square matrix in memory.
+// It is safe to use concurrently.
+type snapshotStore struct {
+	// max is the number of snapshots that are kept.
+	max int
+
+	mu        sync.Mutex
+	snapshots []Snapshot
+	next      int
+	// subscribers are called with every new snapshot in the order of their IDs.
+	subscribers map[int]func(Snapshot)
+	nextSub     int
+}
+
+func newSnapshotStore(max int) *snapshotStore {
+	return &snapshotStore{
+		max:         max,
+		next:        1,
+		subscribers: make(map[int]func(Snapshot)),
+	}
+}
+
+// Subscribe registers a function that is called with every new snapshot
+// and returns a function that unregisters it.
+// The functions are called sequentially in the order they were registered.
+func (s *snapshotStore) Subscribe(f func(Snapshot)) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextSub
+	s.nextSub++
+	s.subscribers[id] = f
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subscribers, id)
+	}
+}
+
+// Add stores the matrix as a new snapshot and notifies all subscribers.
+func (s *snapshotStore) Add(m matrix, t time.Time) Snapshot {
+	s.mu.Lock()
+	sn := Snapshot{ID: s.next, Time: t, Matrix: m}
+	s.next++
+	s.snapshots = append(s.snapshots, sn)
+	if len(s.snapshots) > s.max {
+		s.snapshots = s.snapshots[len(s.snapshots)-s.max:]
+	}
+	ids := make([]int, 0, len(s.subscribers))
+	for id := range s.subscribers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	subscribers := make([]func(Snapshot), len(ids))
+	for i, id := range ids {
+		subscribers[i] = s.subscribers[id]
+	}
+	s.mu.Unlock()
+	for _, f := range subscribers {
+		f(sn)
+	}
+	return sn
+}
+
+// Get returns the snapshot with the given ID.
+// The IDs "latest" and "previous" refer to the last and the second to last snapshot.
+func (s *snapshotStore) Get(id string) (Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.snapshots)
+	switch id {
+	case "latest":
+		if n > 0 {
+			return s.snapshots[n-1], nil
+		}
+	case "previous":
+		if n > 1 {
+			return s.snapshots[n-2], nil
+		}
+	default:
+		i, err := strconv.Atoi(id)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("snapshot ID %q is neither a number nor latest or previous", id)
+		}
+		for _, sn := range s.snapshots {
+			if sn.ID == i {
+				return sn, nil
+			}
+		}
+	}
+	return Snapshot{}, fmt.Errorf("snapshot %s does not exist", id)
+}
+
+// List returns the IDs and times of all stored snapshots without their matrices.
+func (s *snapshotStore) List() []Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := make([]Snapshot, len(s.snapshots))
+	for i, sn := range s.snapshots {
+		l[i] = Snapshot{ID: sn.ID, Time: sn.Time}
+	}
+	return l
+}
+
+// Run collects the square matrix every interval and adds it as a snapshot
+// until the context is canceled.
+func (s *snapshotStore) Run(ctx context.Context, interval time.Duration, collect func(context.Context) (matrix, error)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		m, err := collect(ctx)
+		if err != nil {
+			log.Printf("failed to collect snapshot: %v\n", err)
+			errorCounter.Inc()
+			continue
+		}
+		s.Add(m, time.Now())
+	}
+}
+
+// snapshotsHandler lists the stored snapshots or returns the one whose ID is given in the path.
+// A POST request takes a new snapshot immediately.
+func snapshotsHandler(s *snapshotStore, collect func(context.Context) (matrix, error)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/snapshots"), "/")
+		if r.Method == http.MethodPost {
+			m, err := collect(r.Context())
+			if err != nil {
+				errorCounter.Inc()
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			sn := s.Add(m, time.Now())
+			writeJSON(w, Snapshot{ID: sn.ID, Time: sn.Time})
+			return
+		}
+		if id == "" {
+			writeJSON(w, s.List())
+			return
+		}
+		sn, err := s.Get(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, sn)
+	}
+}
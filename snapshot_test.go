@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshotStore(t *testing.T) {
+	s := newSnapshotStore(2)
+	if _, err := s.Get("latest"); err == nil {
+		t.Error("expected an error for an empty store")
+	}
+	var notified []int
+	s.Subscribe(func(sn Snapshot) {
+		notified = append(notified, sn.ID)
+	})
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		s.Add(squareMatrix([][]int{{i}}), now.Add(time.Duration(i)*time.Second))
+	}
+	if len(notified) != 3 || notified[2] != 3 {
+		t.Errorf("expected subscriber to be notified of every snapshot, got %v", notified)
+	}
+	if l := s.List(); len(l) != 2 || l[0].ID != 2 || l[1].ID != 3 || l[0].Matrix != nil {
+		t.Errorf("expected the two latest snapshots without matrices, got %v", l)
+	}
+	for _, tc := range []struct {
+		id  string
+		eID int
+		err bool
+	}{
+		{id: "latest", eID: 3},
+		{id: "previous", eID: 2},
+		{id: "2", eID: 2},
+		{id: "1", err: true},
+		{id: "foo", err: true},
+	} {
+		sn, err := s.Get(tc.id)
+		if (err != nil) != tc.err {
+			t.Errorf("%s: got error %v, expected error: %t", tc.id, err, tc.err)
+			continue
+		}
+		if err == nil && sn.ID != tc.eID {
+			t.Errorf("%s: got snapshot %d, expected %d", tc.id, sn.ID, tc.eID)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/kilo-io/adjacency_service/pkg/prober"
+)
+
+// newSource returns the source of probes from the given IP address or the interface to take it from,
+// see addressFromFlag, and the interface that probes are bound to.
+func newSource(addr, iface string) (prober.Source, error) {
+	a, err := addressFromFlag(addr)
+	if err != nil {
+		return prober.Source{}, fmt.Errorf("failed to get source address: %w", err)
+	}
+	if iface != "" {
+		if _, err := net.InterfaceByName(iface); err != nil {
+			return prober.Source{}, fmt.Errorf("failed to find source interface %s: %w", iface, err)
+		}
+	}
+	return prober.Source{Address: a, Interface: iface}, nil
+}
+
+// sourceFromValues returns the source of probes that is requested in the query.
+// The zero source means that the default source of the node is used.
+func sourceFromValues(q url.Values) (prober.Source, error) {
+	return newSource(q.Get("sourceAddress"), q.Get("sourceInterface"))
+}
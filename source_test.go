@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+
+	"github.com/kilo-io/adjacency_service/pkg/prober"
+)
+
+func TestSourceFromValues(t *testing.T) {
+	for _, tc := range []struct {
+		q        string
+		expected prober.Source
+		err      bool
+	}{
+		{q: ""},
+		{q: "sourceAddress=10.4.0.1", expected: prober.Source{Address: "10.4.0.1"}},
+		{q: "sourceAddress=lo", expected: prober.Source{Address: "127.0.0.1"}},
+		{q: "sourceInterface=lo", expected: prober.Source{Interface: "lo"}},
+		{q: "sourceAddress=127.0.0.1&sourceInterface=lo", expected: prober.Source{Address: "127.0.0.1", Interface: "lo"}},
+		{q: "sourceAddress=does-not-exist0", err: true},
+		{q: "sourceInterface=does-not-exist0", err: true},
+	} {
+		q, err := url.ParseQuery(tc.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := sourceFromValues(q)
+		if (err != nil) != tc.err {
+			t.Errorf("query %q: expected error %t, got %v", tc.q, tc.err, err)
+		}
+		if diff := pretty.Compare(s, tc.expected); diff != "" {
+			t.Errorf("query %q: got diff:\n%s", tc.q, diff)
+		}
+	}
+}
+
+func TestTimeHTTPRequestSourceIP(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("binding to 127.0.0.2 requires Linux")
+	}
+	s := httptest.NewServer(http.NotFoundHandler())
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	probers := []prober.Prober{prober.NewTCPProber()}
+	for _, tc := range []struct {
+		s        prober.Source
+		expected string
+	}{
+		{expected: "127.0.0.1"},
+		{s: prober.Source{Address: "127.0.0.2"}, expected: "127.0.0.2"},
+	} {
+		l := timeHTTPRequest(prober.WithSource(context.Background(), tc.s), probers, u, time.Second)
+		if !l.Ok || l.SourceIP != tc.expected {
+			t.Errorf("source %q: expected a successful probe from %q, got %+v", tc.s, tc.expected, l)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-graphviz"
+	"github.com/goccy/go-graphviz/cgraph"
+)
+
+// svgOptions control which edges are drawn and how nodes are grouped
+// when a matrix is rendered as a graph.
+type svgOptions struct {
+	// square is true if the destinations of the matrix are nodes of the adjacency service,
+	// so that the sources are among them.
+	square bool
+	// min and max drop edges that are faster or slower, respectively.
+	// A value of 0 disables the filter.
+	min, max time.Duration
+	// top keeps only the k fastest edges per node.
+	// If slowest is set, the k slowest edges are kept instead.
+	// A value of 0 disables the filter.
+	top     int
+	slowest bool
+	// cluster is the label key by which nodes are grouped into subgraphs.
+	cluster string
+	// highlight maps the hosts and ports of a source and a destination
+	// to the color of their edge.
+	// Highlighted edges are always drawn.
+	highlight map[[2]string]string
+	// highlightNodes maps the hosts and ports of nodes to their color.
+	highlightNodes map[string]string
+}
+
+// filtered returns true if any option that removes edges is set.
+func (o svgOptions) filtered() bool {
+	return o.min != 0 || o.max != 0 || o.top != 0
+}
+
+func svgOptionsFromRequest(r *http.Request) (svgOptions, error) {
+	var o svgOptions
+	var err error
+	q := r.URL.Query()
+	if v := q.Get("min"); v != "" {
+		if o.min, err = time.ParseDuration(v); err != nil {
+			return o, fmt.Errorf("failed to parse min: %w", err)
+		}
+	}
+	if v := q.Get("max"); v != "" {
+		if o.max, err = time.ParseDuration(v); err != nil {
+			return o, fmt.Errorf("failed to parse max: %w", err)
+		}
+	}
+	if o.min != 0 && o.max != 0 && o.min > o.max {
+		return o, fmt.Errorf("min %v must not be larger than max %v", o.min, o.max)
+	}
+	if v := q.Get("top"); v != "" {
+		if o.top, err = strconv.Atoi(v); err != nil {
+			return o, fmt.Errorf("failed to parse top: %w", err)
+		}
+		if o.top < 0 {
+			return o, fmt.Errorf("top must not be negative, got %d", o.top)
+		}
+	}
+	switch v := q.Get("order"); v {
+	case "", "fastest":
+	case "slowest":
+		o.slowest = true
+	default:
+		return o, fmt.Errorf("order must be either fastest or slowest, got %q", v)
+	}
+	o.cluster = q.Get("cluster")
+	return o, nil
+}
+
+// edges returns the indices of the destinations of the given vector
+// that should be drawn according to the options.
+func (o svgOptions) edges(v Vector) []int {
+	var idx []int
+	for j, l := range v.Latencies {
+		if o.filtered() {
+			// Failed probes have no meaningful duration,
+			// so they are dropped as soon as edges are filtered.
+			if !l.Ok {
+				continue
+			}
+			if o.min != 0 && l.Duration < o.min {
+				continue
+			}
+			if o.max != 0 && l.Duration > o.max {
+				continue
+			}
+		}
+		idx = append(idx, j)
+	}
+	if o.top != 0 {
+		sort.SliceStable(idx, func(a, b int) bool {
+			if o.slowest {
+				return v.Latencies[idx[a]].Duration > v.Latencies[idx[b]].Duration
+			}
+			return v.Latencies[idx[a]].Duration < v.Latencies[idx[b]].Duration
+		})
+		if len(idx) > o.top {
+			idx = idx[:o.top]
+		}
+	}
+	return idx
+}
+
+// subGraph returns the graph the node with the given labels should be created in.
+// Clusters are created on demand and cached in clusters.
+func (o svgOptions) subGraph(graph *cgraph.Graph, clusters map[string]*cgraph.Graph, labels map[string]string) *cgraph.Graph {
+	if o.cluster == "" {
+		return graph
+	}
+	v := labelValue(labels, o.cluster)
+	if v == "" {
+		return graph
+	}
+	if c, ok := clusters[v]; ok {
+		return c
+	}
+	// Graphviz only draws subgraphs as a box if their name starts with "cluster".
+	c := graph.SubGraph(fmt.Sprintf("cluster_%d", len(clusters)), 1)
+	c.SetLabel(fmt.Sprintf("%s=%s", o.cluster, v))
+	clusters[v] = c
+	return c
+}
+
+// writeSVG renders the matrix as SVG to the response.
+func writeSVG(w http.ResponseWriter, m matrix, o svgOptions) {
+	buf := &bytes.Buffer{}
+	if err := m.SVG(buf, o); err != nil {
+		log.Println(err)
+		errorCounter.Inc()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("content-type", "image/svg+xml")
+	w.Write(buf.Bytes())
+}
+
+// SVG renders the matrix as a graph in SVG format to the given writer.
+func (m matrix) SVG(w io.Writer, o svgOptions) (err error) {
+	g := graphviz.New()
+	graph, err := g.Graph()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := graph.Close(); err != nil {
+			log.Println(err)
+			return
+		}
+		g.Close()
+	}()
+	clusters := make(map[string]*cgraph.Graph)
+	nodes := make([]*cgraph.Node, len(m))
+	for i, v := range m {
+		nodes[i], err = o.subGraph(graph, clusters, v.Labels).CreateNode(v.name())
+		if err != nil {
+			return err
+		}
+		if c, ok := o.highlightNodes[v.id()]; ok {
+			nodes[i].SetColor(c)
+		}
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	// If the srv record references the adjacency service, not some other service,
+	// destinations that are also sources are drawn as the node of the source.
+	// The sources can be a subset of the destinations, so they are matched by their identity.
+	sources := make(map[string]*cgraph.Node, len(m))
+	if o.square {
+		for i, v := range m {
+			sources[v.id()] = nodes[i]
+		}
+	}
+	targetNodes := make([]*cgraph.Node, len(m[0].Latencies))
+	for j, l := range m.columns() {
+		if n, ok := sources[l.id()]; ok {
+			targetNodes[j] = n
+			continue
+		}
+		targetNodes[j], err = o.subGraph(graph, clusters, l.Labels).CreateNode(l.name())
+		if err != nil {
+			return err
+		}
+		targetNodes[j] = targetNodes[j].SetStyle(cgraph.DashedNodeStyle)
+		if c, ok := o.highlightNodes[l.id()]; ok {
+			targetNodes[j].SetColor(c)
+		}
+	}
+	for i, n := range nodes {
+		idx := o.edges(m[i])
+		drawn := make(map[int]bool, len(idx))
+		for _, j := range idx {
+			drawn[j] = true
+		}
+		for j, l := range m[i].Latencies {
+			if _, ok := o.highlight[[2]string{m[i].id(), l.id()}]; ok && !drawn[j] && l.Destination != dummy {
+				idx = append(idx, j)
+			}
+		}
+		for _, j := range idx {
+			if j >= len(targetNodes) {
+				continue
+			}
+			e, err := graph.CreateEdge(fmt.Sprintf("%d:%d", i, j), n, targetNodes[j])
+			if err != nil {
+				return err
+			}
+			switch l := m[i].Latencies[j]; {
+			case l.Stats != nil:
+				e.SetLabel(l.Stats.String())
+			case l.MTU != nil:
+				e.SetLabel(l.MTU.String())
+			case l.Throughput != nil:
+				e.SetLabel(l.Throughput.String())
+			case l.Clock != nil:
+				e.SetLabel(l.Clock.String())
+			case l.Overlay != nil || l.Underlay != nil:
+				e.SetLabel(l.dualString())
+			case !l.Ok:
+				e.SetLabel(l.String())
+			default:
+				e.SetLabel(fmt.Sprint(l.Duration))
+			}
+			if c, ok := o.highlight[[2]string{m[i].id(), m[i].Latencies[j].id()}]; ok {
+				e.SetColor(c)
+				e.SetPenWidth(2)
+			} else if l := m[i].Latencies[j]; l.MTU != nil && l.MTU.Below {
+				e.SetColor(belowMTUColor)
+				e.SetPenWidth(2)
+			} else if m[i].Latencies[j].Anomaly != 0 {
+				e.SetColor(anomalyColor)
+				e.SetPenWidth(2)
+			}
+			var es cgraph.EdgeStyle
+			switch d := m[i].Latencies[j].Duration; {
+			case d > 10000000000: // > 10s
+				es = cgraph.DottedEdgeStyle
+			case d > 100000000: // > 100ms
+				es = cgraph.DashedEdgeStyle
+			case d > 10000000: // > 10ms
+				es = cgraph.SolidEdgeStyle
+			default: // <= 10ms
+				es = cgraph.BoldEdgeStyle
+			}
+			e.SetStyle(es)
+		}
+	}
+	return g.Render(graph, "svg", w)
+}
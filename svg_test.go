@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestSVGOptionsFromRequest(t *testing.T) {
+	for i, tc := range []struct {
+		name  string
+		query string
+		o     svgOptions
+		err   bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "all",
+			query: "min=1ms&max=1s&top=3&order=slowest&cluster=zone",
+			o: svgOptions{
+				min:     time.Millisecond,
+				max:     time.Second,
+				top:     3,
+				slowest: true,
+				cluster: "zone",
+			},
+		},
+		{
+			name:  "min larger than max",
+			query: "min=1s&max=1ms",
+			err:   true,
+		},
+		{
+			name:  "negative top",
+			query: "top=-1",
+			err:   true,
+		},
+		{
+			name:  "invalid order",
+			query: "order=random",
+			err:   true,
+		},
+	} {
+		o, err := svgOptionsFromRequest(httptest.NewRequest("GET", "/?"+tc.query, nil))
+		if (err != nil) != tc.err {
+			t.Errorf("%d (%s): got error %v, expected error = %t", i, tc.name, err, tc.err)
+			continue
+		}
+		if diff := pretty.Compare(o, tc.o); !tc.err && diff != "" {
+			t.Errorf("%d (%s): unexpected options:\n%s", i, tc.name, diff)
+		}
+	}
+}
+
+func TestSVGEdges(t *testing.T) {
+	v := Vector{
+		Latencies: []Latency{
+			{Destination: "1", Ok: true, Duration: 3 * time.Millisecond},
+			{Destination: "2", Ok: false},
+			{Destination: "3", Ok: true, Duration: 1 * time.Millisecond},
+			{Destination: "4", Ok: true, Duration: 20 * time.Millisecond},
+			{Destination: "5", Ok: true, Duration: 5 * time.Millisecond},
+		},
+	}
+	for i, tc := range []struct {
+		name string
+		o    svgOptions
+		e    []int
+	}{
+		{
+			name: "no filter",
+			e:    []int{0, 1, 2, 3, 4},
+		},
+		{
+			name: "min",
+			o:    svgOptions{min: 3 * time.Millisecond},
+			e:    []int{0, 3, 4},
+		},
+		{
+			name: "max",
+			o:    svgOptions{max: 5 * time.Millisecond},
+			e:    []int{0, 2, 4},
+		},
+		{
+			name: "top fastest",
+			o:    svgOptions{top: 2},
+			e:    []int{2, 0},
+		},
+		{
+			name: "top slowest",
+			o:    svgOptions{top: 2, slowest: true},
+			e:    []int{3, 4},
+		},
+		{
+			name: "top larger than row",
+			o:    svgOptions{top: 10, max: 4 * time.Millisecond},
+			e:    []int{2, 0},
+		},
+	} {
+		if diff := pretty.Compare(tc.o.edges(v), tc.e); diff != "" {
+			t.Errorf("%d (%s): unexpected edges:\n%s", i, tc.name, diff)
+		}
+	}
+}
+
+func TestSVGHighlight(t *testing.T) {
+	m := squareMatrix([][]int{
+		{0, 1, 10},
+		{1, 0, 1},
+		{-1, 1, 0},
+	})
+	o := svgOptions{
+		square: true,
+		top:    1,
+		highlight: map[[2]string]string{
+			{"n0:3000", "n2:3000"}: "red",
+		},
+	}
+	buf := &bytes.Buffer{}
+	if err := m.SVG(buf, o); err != nil {
+		t.Fatalf("failed to render SVG: %v", err)
+	}
+	if !strings.Contains(buf.String(), `stroke="#ff0000"`) {
+		t.Errorf("expected highlighted edge in SVG:\n%s", buf.String())
+	}
+}
+
+func TestSVGSourcesSubset(t *testing.T) {
+	// The sources n1 and n2 are a subset of the destinations.
+	m := squareMatrix([][]int{
+		{0, 5, 5},
+		{1, 0, 7},
+		{2, 3, 0},
+	})[1:]
+	buf := &bytes.Buffer{}
+	if err := m.SVG(buf, svgOptions{square: true, min: time.Millisecond}); err != nil {
+		t.Fatalf("failed to render SVG: %v", err)
+	}
+	s := buf.String()
+	if n := strings.Count(s, `class="node"`); n != 3 {
+		t.Errorf("expected 3 nodes, got %d:\n%s", n, s)
+	}
+	for _, e := range []string{"n1&#45;&gt;n0", "n1&#45;&gt;n2", "n2&#45;&gt;n0", "n2&#45;&gt;n1"} {
+		if !strings.Contains(s, "<title>"+e+"</title>") {
+			t.Errorf("expected edge %s in SVG:\n%s", e, s)
+		}
+	}
+	if n := strings.Count(s, `class="edge"`); n != 4 {
+		t.Errorf("expected 4 edges, got %d", n)
+	}
+}
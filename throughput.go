@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/kilo-io/adjacency_service/pkg/prober"
+)
+
+const (
+	// statThroughput is the value of the stat query parameter that makes the nodes test the throughput.
+	// It is only set by /throughput, so that matrices do not burn bandwidth by accident.
+	statThroughput = "throughput"
+	// maxThroughputBytes is the largest number of bytes that the throughput endpoint receives or sends.
+	maxThroughputBytes = 1 << 30
+	// maxThroughputStreams is the largest number of parallel streams of a throughput test.
+	maxThroughputStreams = 16
+)
+
+// Throughput is the result of a throughput test from a node to a destination.
+type Throughput struct {
+	Mbps      float64       `json:"mbps"`
+	Bytes     int64         `json:"bytes"`
+	Streams   int           `json:"streams"`
+	Direction string        `json:"direction"`
+	Duration  time.Duration `json:"duration"`
+	Ok        bool          `json:"ok"`
+}
+
+func (t *Throughput) String() string {
+	if !t.Ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f Mbit/s", t.Mbps)
+}
+
+// throughputOptionsFromValues returns the options of a throughput test from the
+// bytes, streams and direction query parameters.
+// Options that are not set are zero, so that every node uses its own defaults.
+func throughputOptionsFromValues(q url.Values) (prober.ThroughputOptions, error) {
+	o := prober.ThroughputOptions{Direction: q.Get("direction")}
+	var err error
+	if b := q.Get("bytes"); b != "" {
+		if o.Bytes, err = strconv.ParseInt(b, 10, 64); err != nil {
+			return o, fmt.Errorf("failed to parse bytes: %w", err)
+		}
+		if o.Bytes == 0 {
+			return o, errors.New("bytes must be positive")
+		}
+	}
+	if s := q.Get("streams"); s != "" {
+		if o.Streams, err = strconv.Atoi(s); err != nil {
+			return o, fmt.Errorf("failed to parse streams: %w", err)
+		}
+		if o.Streams == 0 {
+			return o, errors.New("streams must be positive")
+		}
+	}
+	return o, checkThroughputOptions(o)
+}
+
+// checkThroughputOptions checks the options of a throughput test that are not zero.
+func checkThroughputOptions(o prober.ThroughputOptions) error {
+	if o.Bytes < 0 || o.Bytes > maxThroughputBytes {
+		return fmt.Errorf("bytes must be between 1 and %d, got %d", maxThroughputBytes, o.Bytes)
+	}
+	if o.Streams < 0 || o.Streams > maxThroughputStreams {
+		return fmt.Errorf("streams must be between 1 and %d, got %d", maxThroughputStreams, o.Streams)
+	}
+	switch o.Direction {
+	case "", prober.ThroughputUpload, prober.ThroughputDownload:
+	default:
+		return fmt.Errorf("unknown direction %q", o.Direction)
+	}
+	return nil
+}
+
+// setThroughputValues sets the query parameters of the options that are not zero.
+func setThroughputValues(q url.Values, o prober.ThroughputOptions) {
+	if o.Bytes != 0 {
+		q.Set("bytes", strconv.FormatInt(o.Bytes, 10))
+	}
+	if o.Streams != 0 {
+		q.Set("streams", strconv.Itoa(o.Streams))
+	}
+	if o.Direction != "" {
+		q.Set("direction", o.Direction)
+	}
+}
+
+// probeThroughput tests the throughput to the given URLs and returns the results by destination.
+// The destinations are tested one after the other, so that the tests do not share the link of this node.
+// Options that are zero are replaced by the defaults of the node.
+func (vr *vectorer) probeThroughput(ctx context.Context, urls []*url.URL, o prober.ThroughputOptions) map[string]*Throughput {
+	if o.Bytes == 0 {
+		o.Bytes = vr.tpDefaults.Bytes
+	}
+	if o.Streams == 0 {
+		o.Streams = vr.tpDefaults.Streams
+	}
+	if o.Direction == "" {
+		o.Direction = vr.tpDefaults.Direction
+	}
+	tps := make(map[string]*Throughput, len(urls))
+	for _, u := range urls {
+		t := &Throughput{Bytes: o.Bytes, Streams: o.Streams, Direction: o.Direction}
+		ctxT, cancelT := context.WithTimeout(ctx, vr.tpTimeout)
+		r, err := vr.tp.Throughput(ctxT, *u, o)
+		cancelT()
+		if err != nil {
+			log.Printf("failed to test throughput to %s: %v\n", u.Host, err)
+			errorCounter.Inc()
+		} else {
+			t.Mbps, t.Duration, t.Ok = r.Mbps(), r.Duration, true
+		}
+		tps[u.String()] = t
+	}
+	return tps
+}
+
+// throughputHandler collects the matrix of the throughput between the nodes in the requested format.
+// It is the only handler that sets the throughput stat, because the tests transfer a lot of data.
+func throughputHandler(srv string, timeout time.Duration, asymmetryRatio float64) func(http.ResponseWriter, *http.Request) {
+	return matrixHandler(srv, timeout, asymmetryRatio, nil, statThroughput)
+}
+
+// throughputDataHandler receives and sends the data of throughput tests of other nodes.
+// It discards the bodies of POST requests and answers with their number of bytes,
+// and answers GET requests with the number of bytes given by the bytes query parameter.
+func throughputDataHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		// HTTP/1.x handlers must read the body before they write the response.
+		n, err := io.Copy(ioutil.Discard, http.MaxBytesReader(w, r.Body, maxThroughputBytes))
+		if err != nil {
+			errorCounter.Inc()
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		fmt.Fprintln(w, n)
+	case http.MethodGet:
+		n, err := strconv.ParseInt(r.URL.Query().Get("bytes"), 10, 64)
+		if err != nil || n < 0 || n > maxThroughputBytes {
+			errorCounter.Inc()
+			http.Error(w, fmt.Sprintf("bytes must be between 0 and %d", maxThroughputBytes), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.FormatInt(n, 10))
+		buf := make([]byte, 32<<10)
+		for n > 0 {
+			b := buf
+			if n < int64(len(b)) {
+				b = b[:n]
+			}
+			if _, err := w.Write(b); err != nil {
+				log.Printf("failed to send throughput data: %v\n", err)
+				return
+			}
+			n -= int64(len(b))
+		}
+	default:
+		errorCounter.Inc()
+		http.Error(w, "only GET and POST are allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+
+	"github.com/kilo-io/adjacency_service/pkg/prober"
+)
+
+func TestThroughputString(t *testing.T) {
+	for _, tc := range []struct {
+		t        Throughput
+		expected string
+	}{
+		{t: Throughput{Mbps: 941.46, Ok: true}, expected: "941.5 Mbit/s"},
+		{t: Throughput{Mbps: 0.04, Ok: true}, expected: "0.0 Mbit/s"},
+		{t: Throughput{}, expected: "-"},
+	} {
+		if s := (Latency{Throughput: &tc.t, Ok: true, Duration: time.Millisecond}).String(); s != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, s)
+		}
+	}
+}
+
+func TestThroughputOptionsFromValues(t *testing.T) {
+	for _, tc := range []struct {
+		q        string
+		expected prober.ThroughputOptions
+		err      bool
+	}{
+		{q: ""},
+		{q: "bytes=1048576&streams=4&direction=download", expected: prober.ThroughputOptions{Bytes: 1 << 20, Streams: 4, Direction: prober.ThroughputDownload}},
+		{q: "bytes=0", err: true},
+		{q: "bytes=2147483648", err: true},
+		{q: "bytes=ten", err: true},
+		{q: "streams=0", err: true},
+		{q: "streams=17", err: true},
+		{q: "direction=sideways", err: true},
+	} {
+		q, err := url.ParseQuery(tc.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		o, err := throughputOptionsFromValues(q)
+		if (err != nil) != tc.err {
+			t.Errorf("query %q: expected error %t, got %v", tc.q, tc.err, err)
+		}
+		if err != nil {
+			continue
+		}
+		if o != tc.expected {
+			t.Errorf("query %q: expected %+v, got %+v", tc.q, tc.expected, o)
+		}
+		// The options survive the query of the vectors.
+		encoded := url.Values{}
+		setThroughputValues(encoded, o)
+		if decoded, err := throughputOptionsFromValues(encoded); err != nil || decoded != o {
+			t.Errorf("query %q: expected %+v after encoding, got %+v, %v", tc.q, o, decoded, err)
+		}
+	}
+	for _, q := range []string{"stat=throughput&bytes=1000", "stat=mtu&bytes=ten"} {
+		v, err := url.ParseQuery(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := matrixQueryFromValues(v, "_service._tcp.example.com"); err != nil {
+			t.Errorf("query %q: unexpected error %v", q, err)
+		}
+	}
+}
+
+func TestThroughputDataHandler(t *testing.T) {
+	for _, tc := range []struct {
+		method   string
+		target   string
+		body     string
+		code     int
+		expected string
+	}{
+		{method: http.MethodPost, target: "/throughput/data", body: strings.Repeat("a", 5000), code: http.StatusOK, expected: "5000\n"},
+		{method: http.MethodGet, target: "/throughput/data?bytes=3", code: http.StatusOK, expected: "\x00\x00\x00"},
+		{method: http.MethodGet, target: "/throughput/data?bytes=70000", code: http.StatusOK},
+		{method: http.MethodGet, target: "/throughput/data", code: http.StatusBadRequest},
+		{method: http.MethodGet, target: "/throughput/data?bytes=-1", code: http.StatusBadRequest},
+		{method: http.MethodPut, target: "/throughput/data", code: http.StatusMethodNotAllowed},
+	} {
+		w := httptest.NewRecorder()
+		throughputDataHandler(w, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)))
+		if w.Code != tc.code {
+			t.Errorf("%s %s: expected status %d, got %d", tc.method, tc.target, tc.code, w.Code)
+		}
+		if tc.expected != "" && w.Body.String() != tc.expected {
+			t.Errorf("%s %s: expected body %q, got %q", tc.method, tc.target, tc.expected, w.Body.String())
+		}
+	}
+	w := httptest.NewRecorder()
+	throughputDataHandler(w, httptest.NewRequest(http.MethodGet, "/throughput/data?bytes=70000", nil))
+	if w.Body.Len() != 70000 {
+		t.Errorf("expected 70000 bytes, got %d", w.Body.Len())
+	}
+}
+
+func TestProbeThroughput(t *testing.T) {
+	m := http.NewServeMux()
+	m.HandleFunc(prober.ThroughputPath, throughputDataHandler)
+	s := httptest.NewServer(m)
+	defer s.Close()
+	// This server does not run the adjacency service.
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+
+	vr := &vectorer{
+		tp:         prober.NewThroughputProber(prober.NewSourceClient()),
+		tpDefaults: prober.ThroughputOptions{Bytes: 1 << 20, Streams: 1, Direction: prober.ThroughputUpload},
+		tpTimeout:  5 * time.Second,
+	}
+	var urls []*url.URL
+	for _, s := range []*httptest.Server{s, plain} {
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls = append(urls, u)
+	}
+	for _, o := range []prober.ThroughputOptions{
+		{},
+		{Bytes: 1 << 16, Streams: 2, Direction: prober.ThroughputDownload},
+	} {
+		tps := vr.probeThroughput(context.Background(), urls, o)
+		expected := vr.tpDefaults
+		if o.Bytes != 0 {
+			expected = o
+		}
+		got := tps[s.URL]
+		if got == nil || !got.Ok || got.Mbps <= 0 || got.Duration <= 0 {
+			t.Errorf("options %+v: unexpected throughput %+v", o, got)
+			continue
+		}
+		got.Mbps, got.Duration = 0, 0
+		want := map[string]*Throughput{
+			s.URL:     {Bytes: expected.Bytes, Streams: expected.Streams, Direction: expected.Direction, Ok: true},
+			plain.URL: {Bytes: expected.Bytes, Streams: expected.Streams, Direction: expected.Direction},
+		}
+		if diff := pretty.Compare(tps, want); diff != "" {
+			t.Errorf("options %+v: got diff:\n%s", o, diff)
+		}
+	}
+}
+
+func TestThroughputOnlyViaThroughputHandler(t *testing.T) {
+	srv := "_service._tcp.example.com"
+	for target, h := range map[string]func(http.ResponseWriter, *http.Request){
+		"/?stat=throughput":                       collectAllHandler(srv, time.Second, 2, nil),
+		"/analysis/reachability?stat=throughput":  reachabilityHandler(srv, time.Second),
+		"/analysis/asymmetry?stat=throughput":     asymmetryHandler(srv, time.Second, 2),
+		"/coordinates?stat=throughput&format=svg": coordinatesHandler(srv, time.Second),
+	} {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", target, http.StatusBadRequest, w.Code)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kilo-io/adjacency_service/pkg/prober"
+)
+
+// pathForwardMargin is the part of the remaining time of a request for the path from another node
+// that is not passed on to that node, so that the node answers before the request times out.
+const pathForwardMargin = 500 * time.Millisecond
+
+// Path is the route from a node to a destination as found by a traceroute from the node.
+type Path struct {
+	// Source is the name of the node.
+	Source      string       `json:"source"`
+	Destination string       `json:"destination"`
+	Method      string       `json:"method"`
+	Hops        []prober.Hop `json:"hops"`
+	// Reached is false if the destination did not answer within the maximum number of hops.
+	Reached bool      `json:"reached"`
+	Time    time.Time `json:"time"`
+	// Changed is the time the path last changed and Previous are the hops before the change.
+	// They are only set if the path changed since the node traced the destination for the first time.
+	Changed  *time.Time   `json:"changed,omitempty"`
+	Previous []prober.Hop `json:"previous,omitempty"`
+}
+
+func (p Path) String() string {
+	ips := make([]string, 0, len(p.Hops))
+	for _, h := range p.Hops {
+		if h.IP == "" {
+			ips = append(ips, "*")
+			continue
+		}
+		ips = append(ips, h.IP)
+	}
+	return strings.Join(ips, " > ")
+}
+
+// samePath returns true if both lists of hops have the same routers.
+// Hops that did not answer match any router,
+// because routers drop ICMP messages when they are rate-limited.
+func samePath(a, b []prober.Hop) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].IP != "" && b[i].IP != "" && a[i].IP != b[i].IP {
+			return false
+		}
+	}
+	return true
+}
+
+// pathStore keeps the last path to every destination.
+// It is safe to use concurrently.
+type pathStore struct {
+	mu    sync.Mutex
+	paths map[string]Path
+}
+
+func newPathStore() *pathStore {
+	return &pathStore{paths: make(map[string]Path)}
+}
+
+// Update stores the path and returns it with the time and the hops of the last change.
+// The returned bool is true if the path differs from the last stored path to the destination.
+// Paths that did not reach their destination only replace paths that did not either,
+// so that a single lost answer of the destination is not a change.
+func (s *pathStore) Update(p Path) (Path, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	last, ok := s.paths[p.Destination]
+	if !ok {
+		s.paths[p.Destination] = p
+		return p, false
+	}
+	p.Changed, p.Previous = last.Changed, last.Previous
+	if last.Reached && !p.Reached {
+		return p, false
+	}
+	changed := !samePath(last.Hops, p.Hops)
+	if changed {
+		t := p.Time
+		p.Changed, p.Previous = &t, last.Hops
+	}
+	s.paths[p.Destination] = p
+	return p, changed
+}
+
+// tracer traces the paths to destinations and reports when they change.
+type tracer struct {
+	tp      *prober.TracerouteProber
+	ps      *pathStore
+	node    string
+	timeout time.Duration
+}
+
+func newTracer(tp *prober.TracerouteProber, node string, timeout time.Duration) *tracer {
+	return &tracer{tp: tp, ps: newPathStore(), node: node, timeout: timeout}
+}
+
+// Trace traces the path to the host of the URL and stores it.
+// Changes of the path are logged and counted.
+func (t *tracer) Trace(ctx context.Context, u *url.URL) (Path, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	hops, reached, err := t.tp.Trace(ctx, *u)
+	if err != nil {
+		return Path{}, fmt.Errorf("failed to trace the path to %s: %w", u.Hostname(), err)
+	}
+	p, changed := t.ps.Update(Path{
+		Source:      t.node,
+		Destination: u.Hostname(),
+		Method:      t.tp.Method(),
+		Hops:        hops,
+		Reached:     reached,
+		Time:        time.Now(),
+	})
+	if changed {
+		log.Printf("path to %s changed from %s to %s\n", p.Destination, Path{Hops: p.Previous}, p)
+		pathChangeCounter.WithLabelValues(p.Destination).Inc()
+	}
+	return p, nil
+}
+
+// Run traces the paths to the nodes of the SRV record every interval until the context is done.
+func (t *tracer) Run(ctx context.Context, srv string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		urls, err := resolveSRV(srv, "", "")
+		if err != nil {
+			log.Printf("failed to resolve SRV record: %v\n", err)
+			errorCounter.Inc()
+		}
+		var wg sync.WaitGroup
+		for _, u := range urls {
+			wg.Add(1)
+			go func(u *url.URL) {
+				defer wg.Done()
+				if _, err := t.Trace(ctx, u); err != nil {
+					log.Println(err)
+					errorCounter.Inc()
+				}
+			}(u)
+		}
+		wg.Wait()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// errUnknownNode is returned if no node of the adjacency service has the name, host name or IP address.
+var errUnknownNode = errors.New("unknown node")
+
+// findNode returns the URL of the node of the SRV record with the given name, host name or IP address.
+func findNode(ctx context.Context, srv, name string) (*url.URL, error) {
+	urls, err := resolveSRV(srv, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve SRV record: %w", err)
+	}
+	for _, u := range urls {
+		if u.Hostname() == name {
+			return u, nil
+		}
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+		if err != nil {
+			continue
+		}
+		for _, ip := range ips {
+			if ip.IP.String() == name {
+				return u, nil
+			}
+		}
+	}
+	// Node names are only known by the nodes themselves.
+	for _, u := range urls {
+		if i, err := fetchInfo(ctx, *u); err == nil && i.Node == name {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errUnknownNode, name)
+}
+
+// isSelf returns true if the given name, host name or IP address is this node.
+func (vr *vectorer) isSelf(name string) bool {
+	if name == vr.node {
+		return true
+	}
+	if h, err := os.Hostname(); err == nil && h == name {
+		return true
+	}
+	addrs, err := interfaceAddresses()
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// pathHandler traces the path from the node src to dst.
+// Both can be node names, host names or IP addresses; dst can be any host.
+// Requests for other sources are passed on to them with the remaining time as the timeout query parameter,
+// which shortens the timeout of the node that traces the path.
+func pathHandler(vr *vectorer, t *tracer, timeout time.Duration) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		src, dst := r.URL.Query().Get("src"), r.URL.Query().Get("dst")
+		if dst == "" {
+			errorCounter.Inc()
+			http.Error(w, "the query parameter dst is required", http.StatusBadRequest)
+			return
+		}
+		to := timeout
+		if v := r.URL.Query().Get("timeout"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				errorCounter.Inc()
+				http.Error(w, fmt.Sprintf("timeout must be a positive duration, got %q", v), http.StatusBadRequest)
+				return
+			}
+			if d < to {
+				to = d
+			}
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), to)
+		defer cancel()
+		if src != "" && !vr.isSelf(src) {
+			u, err := findNode(ctx, vr.srv, src)
+			if err != nil {
+				log.Println(err)
+				errorCounter.Inc()
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			deadline, _ := ctx.Deadline()
+			remaining := time.Until(deadline) - pathForwardMargin
+			if remaining <= 0 {
+				errorCounter.Inc()
+				http.Error(w, fmt.Sprintf("no time left to get the path from %s", src), http.StatusGatewayTimeout)
+				return
+			}
+			u.Path = "/path"
+			u.RawQuery = url.Values{"dst": []string{dst}, "timeout": []string{remaining.String()}}.Encode()
+			status, body, err := get(ctx, u)
+			if err != nil {
+				log.Printf("failed to get path from %s: %v\n", src, err)
+				errorCounter.Inc()
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write(body)
+			return
+		}
+		u, err := findNode(ctx, vr.srv, dst)
+		if err != nil {
+			// The destination does not need to be a node of the adjacency service.
+			u = &url.URL{Scheme: "http", Host: dst}
+		}
+		p, err := t.Trace(prober.WithSource(ctx, vr.source), u)
+		if err != nil {
+			log.Println(err)
+			errorCounter.Inc()
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, p)
+	}
+}
+
+// get requests the URL and returns the status code and the body of the response.
+func get(ctx context.Context, u *url.URL) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to make GET request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	return resp.StatusCode, body, nil
+}
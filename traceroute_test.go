@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+
+	"github.com/kilo-io/adjacency_service/pkg/prober"
+)
+
+func TestPathStore(t *testing.T) {
+	now := time.Now()
+	hops := func(ips ...string) []prober.Hop {
+		var hs []prober.Hop
+		for i, ip := range ips {
+			hs = append(hs, prober.Hop{TTL: i + 1, IP: ip})
+		}
+		return hs
+	}
+	ps := newPathStore()
+	for i, tc := range []struct {
+		p        Path
+		changed  bool
+		expected *Path
+	}{
+		{
+			p: Path{Destination: "n1", Hops: hops("10.0.0.1", "10.0.0.2"), Reached: true, Time: now},
+		},
+		{
+			// Hops that did not answer match any router.
+			p: Path{Destination: "n1", Hops: hops("10.0.0.1", ""), Reached: true, Time: now.Add(time.Minute)},
+		},
+		{
+			// Another destination is independent.
+			p: Path{Destination: "n2", Hops: hops("10.0.0.3"), Reached: true, Time: now},
+		},
+		{
+			p:       Path{Destination: "n1", Hops: hops("10.0.0.4", "10.0.0.2"), Reached: true, Time: now.Add(2 * time.Minute)},
+			changed: true,
+			expected: &Path{
+				Destination: "n1",
+				Hops:        hops("10.0.0.4", "10.0.0.2"),
+				Reached:     true,
+				Time:        now.Add(2 * time.Minute),
+				Changed:     timePtr(now.Add(2 * time.Minute)),
+				Previous:    hops("10.0.0.1", ""),
+			},
+		},
+		{
+			// A path that did not reach its destination does not replace one that did.
+			p: Path{Destination: "n1", Hops: hops("10.0.0.4"), Time: now.Add(3 * time.Minute)},
+			expected: &Path{
+				Destination: "n1",
+				Hops:        hops("10.0.0.4"),
+				Time:        now.Add(3 * time.Minute),
+				Changed:     timePtr(now.Add(2 * time.Minute)),
+				Previous:    hops("10.0.0.1", ""),
+			},
+		},
+		{
+			p:       Path{Destination: "n1", Hops: hops("10.0.0.4", "10.0.0.5", "10.0.0.2"), Reached: true, Time: now.Add(4 * time.Minute)},
+			changed: true,
+		},
+	} {
+		p, changed := ps.Update(tc.p)
+		if changed != tc.changed {
+			t.Errorf("%d: expected changed to be %t", i, tc.changed)
+		}
+		if tc.expected == nil {
+			continue
+		}
+		if diff := pretty.Compare(p, tc.expected); diff != "" {
+			t.Errorf("%d: got diff:\n%s", i, diff)
+		}
+	}
+	if s := (Path{Hops: hops("10.0.0.1", "", "10.0.0.2")}).String(); s != "10.0.0.1 > * > 10.0.0.2" {
+		t.Errorf("unexpected string %q", s)
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestPathHandler(t *testing.T) {
+	tp, err := prober.NewTracerouteProber(prober.TracerouteICMP, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vr := &vectorer{node: "n0"}
+	h := pathHandler(vr, newTracer(tp, "n0", 2*time.Second), 2*time.Second)
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/path", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d without dst, got %d", http.StatusBadRequest, w.Code)
+	}
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/path?dst=127.0.0.1&timeout=-1s", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for a negative timeout, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	if c, err := net.ListenPacket("ip4:icmp", "127.0.0.1"); err != nil {
+		t.Skipf("raw ICMP sockets are not permitted: %v", err)
+	} else {
+		c.Close()
+	}
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/path?src=n0&dst=127.0.0.1&timeout=1s", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	var p Path
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Source != "n0" || p.Destination != "127.0.0.1" || p.Method != prober.TracerouteICMP || !p.Reached || len(p.Hops) != 1 {
+		t.Errorf("unexpected path %+v", p)
+	}
+}
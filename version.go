@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// version is the version of the software.
+// It is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
+// The versions of the response of /vector.
+const (
+	// apiLegacy is a list of latencies.
+	apiLegacy = 0
+	// apiVector is a vector object.
+	apiVector = 1
+	// apiEnvelope is a vector in an envelope with the identity of the node.
+	apiEnvelope = 2
+	// apiVersion is the latest version this node serves.
+	apiVersion = apiEnvelope
+)
+
+// vectorMediaType returns the media type of the given version of the response of /vector.
+func vectorMediaType(v int) string {
+	return fmt.Sprintf("application/vnd.adjacency.vector.v%d+json", v)
+}
+
+var vectorMediaTypeRE = regexp.MustCompile(`^application/vnd\.adjacency\.vector\.v(\d+)\+json$`)
+
+// vectorAccept is the Accept header the collector sends,
+// so that new nodes respond with an envelope and old nodes with what they know.
+var vectorAccept = vectorMediaType(apiVersion) + ", application/json;q=0.5"
+
+// The capabilities a node advertises in its envelope.
+const (
+	// capDestinations means that the node filters its destinations by the destinations selector.
+	capDestinations = "destinations"
+	capLabels       = "labels"
+	capCoordinates  = "coordinates"
+	capSampling     = "sampling"
+	capGRPC         = "grpc"
+	capWireGuard    = "wireguard"
+	// capDual means that the node advertises its overlay and underlay addresses.
+	capDual = "dual"
+	// capMTU means that the node probes the path MTU for stat=mtu and serves the echo endpoint.
+	capMTU = "mtu"
+	// capThroughput means that the node tests the throughput for /throughput and serves the throughput endpoint.
+	capThroughput = "throughput"
+	// capClock means that the node estimates clocks for stat=clock and serves the time endpoint.
+	capClock = "clock"
+)
+
+// vectorEnvelope is the versioned response of /vector.
+// Fields that are added later must be optional,
+// so that collectors of the same API version can ignore them.
+type vectorEnvelope struct {
+	APIVersion   int      `json:"apiVersion"`
+	Node         string   `json:"node"`
+	Version      string   `json:"version"`
+	Capabilities []string `json:"capabilities"`
+	Vector       Vector   `json:"vector"`
+}
+
+// acceptedAPIVersion returns the latest version of the response of /vector
+// that this node serves and that is accepted by the given Accept header.
+// Collectors that do not ask for a versioned media type get a plain vector.
+func acceptedAPIVersion(accept string) int {
+	best := apiVector
+	for _, part := range strings.Split(accept, ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		m := vectorMediaTypeRE.FindStringSubmatch(mt)
+		if m == nil {
+			continue
+		}
+		v, err := strconv.Atoi(m[1])
+		if err != nil || v > apiVersion {
+			continue
+		}
+		if v > best {
+			best = v
+		}
+	}
+	return best
+}
+
+// hasCapability returns true if the node of the vector advertised the capability.
+func (v Vector) hasCapability(c string) bool {
+	for _, vc := range v.Capabilities {
+		if vc == c {
+			return true
+		}
+	}
+	return false
+}
+
+// parseVectorResponse parses the response of /vector of any version into v.
+// The version is detected by the content type and, for older nodes, by the shape of the body.
+func parseVectorResponse(contentType string, body []byte, v *Vector) error {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		if m := vectorMediaTypeRE.FindStringSubmatch(mt); m != nil {
+			var e vectorEnvelope
+			if err := json.Unmarshal(body, &e); err != nil {
+				return fmt.Errorf("failed to parse envelope: %w", err)
+			}
+			v.Labels = e.Vector.Labels
+			v.Coordinate = e.Vector.Coordinate
+			v.Latencies = e.Vector.Latencies
+			v.Node = e.Node
+			v.Version = e.Version
+			v.APIVersion = e.APIVersion
+			v.Capabilities = e.Capabilities
+			return nil
+		}
+	}
+	// Older nodes respond with a vector or with a list of latencies.
+	if b := bytes.TrimSpace(body); len(b) > 0 && b[0] == '[' {
+		v.APIVersion = apiLegacy
+		if err := json.Unmarshal(b, &v.Latencies); err != nil {
+			return fmt.Errorf("response from node has wrong format: maybe it is not running this service?: %w", err)
+		}
+		return nil
+	}
+	var rv Vector
+	if err := json.Unmarshal(body, &rv); err != nil {
+		return fmt.Errorf("response from node has wrong format: maybe it is not running this service?: %w", err)
+	}
+	v.APIVersion = apiVector
+	v.Labels = rv.Labels
+	v.Coordinate = rv.Coordinate
+	v.Latencies = rv.Latencies
+	return nil
+}
+
+// filterDestinations removes the latencies of destinations that are not selected
+// from the vectors of nodes that do not filter their destinations themselves.
+// The labels of destinations are taken from the sources of the matrix;
+// destinations without known labels are kept if the selector needs labels.
+func (m matrix) filterDestinations(sel *selector) {
+	if sel == nil {
+		return
+	}
+	labels := make(map[string]map[string]string, len(m))
+	for _, v := range m {
+		if v.Labels != nil {
+			labels[hostPort(v.Source)] = v.Labels
+		}
+	}
+	for i, v := range m {
+		// Filtering again does not change the vectors of nodes that filtered themselves
+		// but did not advertise it yet.
+		if !v.Ok || v.hasCapability(capDestinations) {
+			continue
+		}
+		var lats []Latency
+		for _, l := range v.Latencies {
+			ls, ok := labels[hostPort(l.Destination)]
+			if (!ok && sel.needsLabels()) || sel.Matches(l.Host, ls) {
+				lats = append(lats, l)
+			}
+		}
+		m[i].Latencies = lats
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestAcceptedAPIVersion(t *testing.T) {
+	for i, tc := range []struct {
+		accept string
+		v      int
+	}{
+		{accept: "", v: apiVector},
+		{accept: "application/json", v: apiVector},
+		{accept: vectorAccept, v: apiEnvelope},
+		{accept: "application/vnd.adjacency.vector.v2+json; charset=utf-8", v: apiEnvelope},
+		// Versions that this node does not serve yet are ignored.
+		{accept: "application/vnd.adjacency.vector.v9+json, application/vnd.adjacency.vector.v2+json", v: apiEnvelope},
+		{accept: "application/vnd.adjacency.vector.v9+json", v: apiVector},
+	} {
+		if v := acceptedAPIVersion(tc.accept); v != tc.v {
+			t.Errorf("test case %d: expected version %d, got %d", i, tc.v, v)
+		}
+	}
+}
+
+func TestParseVectorResponse(t *testing.T) {
+	lats := []Latency{{Destination: "http://n1:3000", Ok: true, Duration: time.Millisecond}}
+	for i, tc := range []struct {
+		name        string
+		contentType string
+		body        string
+		v           Vector
+		err         bool
+	}{
+		{
+			name: "legacy",
+			body: `[{"destination":"http://n1:3000","duration":1000000,"ok":true}]`,
+			v:    Vector{Latencies: lats},
+		},
+		{
+			name:        "vector",
+			contentType: "text/plain; charset=utf-8",
+			body:        `{"labels":{"zone":"a"},"latencies":[{"destination":"http://n1:3000","duration":1000000,"ok":true}],"ok":true}`,
+			v:           Vector{Labels: map[string]string{"zone": "a"}, Latencies: lats, APIVersion: apiVector},
+		},
+		{
+			name:        "envelope",
+			contentType: vectorMediaType(apiEnvelope),
+			body:        `{"apiVersion":2,"node":"n0","version":"1.2.0","capabilities":["destinations"],"vector":{"latencies":[{"destination":"http://n1:3000","duration":1000000,"ok":true}],"ok":true},"future":true}`,
+			v:           Vector{Latencies: lats, Node: "n0", Version: "1.2.0", APIVersion: apiEnvelope, Capabilities: []string{capDestinations}},
+		},
+		{
+			name: "other service",
+			body: `<html></html>`,
+			err:  true,
+		},
+	} {
+		var v Vector
+		err := parseVectorResponse(tc.contentType, []byte(tc.body), &v)
+		if (err != nil) != tc.err {
+			t.Errorf("test case %d (%s): expected error %t, got %v", i, tc.name, tc.err, err)
+			continue
+		}
+		if tc.err {
+			continue
+		}
+		if diff := pretty.Compare(v, tc.v); diff != "" {
+			t.Errorf("test case %d (%s): got diff:\n%s", i, tc.name, diff)
+		}
+	}
+}
+
+func TestFilterDestinations(t *testing.T) {
+	m := squareMatrix([][]int{
+		{0, 1, 2},
+		{1, 0, 2},
+		{2, 2, 0},
+	})
+	// n1 filtered its destinations itself.
+	m[1].Capabilities = []string{capDestinations}
+	m[2].Ok = false
+	sel, err := parseSelector("n0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.filterDestinations(sel)
+	for i, n := range []int{1, 3, 3} {
+		if len(m[i].Latencies) != n {
+			t.Errorf("expected %d latencies for n%d, got %d", n, i, len(m[i].Latencies))
+		}
+	}
+	if m[0].Latencies[0].Host != "n0" {
+		t.Errorf("expected n0 to be kept, got %v", m[0].Latencies)
+	}
+}
+
+func TestGetVectorFromNegotiation(t *testing.T) {
+	v := Vector{Latencies: []Latency{{Destination: "http://n1:3000", Ok: true, Duration: time.Millisecond}}, Ok: true}
+	for i, tc := range []struct {
+		name    string
+		handler http.HandlerFunc
+		v       int
+	}{
+		{
+			name: "legacy",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(v.Latencies)
+			},
+			v: apiLegacy,
+		},
+		{
+			name: "vector",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(v)
+			},
+			v: apiVector,
+		},
+		{
+			name: "envelope",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				av := acceptedAPIVersion(r.Header.Get("Accept"))
+				if av < apiEnvelope {
+					http.Error(w, "collector did not ask for the envelope", http.StatusBadRequest)
+					return
+				}
+				w.Header().Set("Content-Type", vectorMediaType(av))
+				json.NewEncoder(w).Encode(vectorEnvelope{APIVersion: av, Node: "n0", Version: "1.2.0", Vector: v})
+			},
+			v: apiEnvelope,
+		},
+	} {
+		s := httptest.NewServer(tc.handler)
+		u, err := url.Parse(s.URL + "/vector")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rv, err := getVectorFrom(context.Background(), u)
+		s.Close()
+		if err != nil {
+			t.Errorf("test case %d (%s): failed to get vector: %v", i, tc.name, err)
+			continue
+		}
+		if rv.APIVersion != tc.v {
+			t.Errorf("test case %d (%s): expected API version %d, got %d", i, tc.name, tc.v, rv.APIVersion)
+		}
+		if diff := pretty.Compare(rv.Latencies, v.Latencies); diff != "" {
+			t.Errorf("test case %d (%s): got diff:\n%s", i, tc.name, diff)
+		}
+	}
+}
+
+func TestVersionColumn(t *testing.T) {
+	m := squareMatrix([][]int{
+		{0, 1},
+		{1, 0},
+	})
+	if s := m.String(fancy); strings.Contains(s, "VERSION") {
+		t.Errorf("expected no version column without versions:\n%s", s)
+	}
+	m[0].Version = "1.2.0"
+	s := m.String(fancy)
+	if !strings.Contains(s, "VERSION") || !strings.Contains(s, "1.2.0") || !strings.Contains(s, " - ") {
+		t.Errorf("expected version column:\n%s", s)
+	}
+}
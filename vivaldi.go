@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	mrand "math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goccy/go-graphviz"
+	"github.com/goccy/go-graphviz/cgraph"
+)
+
+// The tuning parameters of the Vivaldi algorithm,
+// see Dabek et al., "Vivaldi: A Decentralized Network Coordinate System".
+const (
+	// vivaldiCE is the weight of a new sample when the error is updated.
+	vivaldiCE = 0.25
+	// vivaldiCC is the fraction of the distance to the remote
+	// that a node moves with every sample.
+	vivaldiCC = 0.25
+	// vivaldiMaxError is the error of a node that has not moved yet.
+	vivaldiMaxError = 1.5
+	// vivaldiMinHeight keeps the height positive,
+	// which models the latency of the access link of a node.
+	vivaldiMinHeight = 10e-6
+	// vivaldiZero is the distance below which two coordinates are considered equal.
+	vivaldiZero = 1e-9
+)
+
+// A Coordinate is the position of a node in a two-dimensional Euclidean space
+// with an additional height. Distances are measured in seconds.
+type Coordinate struct {
+	Vec    [2]float64 `json:"vec"`
+	Height float64    `json:"height"`
+	// Error is the relative error of the coordinate.
+	Error float64 `json:"error"`
+}
+
+func newCoordinate() Coordinate {
+	return Coordinate{
+		Height: vivaldiMinHeight,
+		Error:  vivaldiMaxError,
+	}
+}
+
+// distance returns the distance to the other coordinate in seconds.
+func (c Coordinate) distance(o Coordinate) float64 {
+	return math.Hypot(c.Vec[0]-o.Vec[0], c.Vec[1]-o.Vec[1]) + c.Height + o.Height
+}
+
+// DistanceTo returns the estimated latency between the two coordinates.
+func (c Coordinate) DistanceTo(o Coordinate) time.Duration {
+	return time.Duration(c.distance(o) * float64(time.Second))
+}
+
+// ErrorTo returns the estimated absolute error of DistanceTo.
+func (c Coordinate) ErrorTo(o Coordinate) time.Duration {
+	return time.Duration(c.distance(o) * (c.Error + o.Error) / 2 * float64(time.Second))
+}
+
+// update moves the coordinate according to a measured round trip time to the remote coordinate.
+// The random source is used to pick a direction if both coordinates are equal.
+func (c Coordinate) update(remote Coordinate, rtt time.Duration, r *mrand.Rand) Coordinate {
+	s := rtt.Seconds()
+	if s <= 0 {
+		return c
+	}
+	// Weigh the sample by how certain this node is compared to the remote.
+	w := c.Error / (c.Error + remote.Error)
+	dist := c.distance(remote)
+	es := math.Abs(dist-s) / s
+	c.Error = math.Min(es*vivaldiCE*w+c.Error*(1-vivaldiCE*w), vivaldiMaxError)
+	force := vivaldiCC * w * (s - dist)
+	// Move along the unit vector pointing from the remote to this node.
+	d := [2]float64{c.Vec[0] - remote.Vec[0], c.Vec[1] - remote.Vec[1]}
+	mag := math.Hypot(d[0], d[1])
+	if mag > vivaldiZero {
+		d[0], d[1] = d[0]/mag, d[1]/mag
+	} else {
+		a := r.Float64() * 2 * math.Pi
+		d[0], d[1] = math.Cos(a), math.Sin(a)
+	}
+	c.Vec[0] += d[0] * force
+	c.Vec[1] += d[1] * force
+	if mag > vivaldiZero {
+		c.Height = math.Max((c.Height+remote.Height)*force/mag+c.Height, vivaldiMinHeight)
+	}
+	return c
+}
+
+// nodeCoordinate is the response of the /coordinate endpoint.
+type nodeCoordinate struct {
+	// ID identifies the process, so that a node does not update its coordinate with itself.
+	ID         string     `json:"id"`
+	Coordinate Coordinate `json:"coordinate"`
+}
+
+// vivaldi maintains the coordinate of this node.
+// It is safe to use concurrently.
+type vivaldi struct {
+	id string
+
+	mu   sync.Mutex
+	c    Coordinate
+	rand *mrand.Rand
+	// updating is 1 while an update runs in the background.
+	updating int32
+}
+
+func newVivaldi() *vivaldi {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		log.Printf("failed to generate an ID: %v\n", err)
+	}
+	return &vivaldi{
+		id:   hex.EncodeToString(id),
+		c:    newCoordinate(),
+		rand: mrand.New(mrand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Coordinate returns the current coordinate of this node.
+func (v *vivaldi) Coordinate() Coordinate {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.c
+}
+
+// Update updates the coordinate of this node with a round trip time to a remote node.
+func (v *vivaldi) Update(remote Coordinate, rtt time.Duration) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.c = v.c.update(remote, rtt, v.rand)
+}
+
+// fetchCoordinate gets the coordinate of the adjacency service at the given URL.
+func fetchCoordinate(ctx context.Context, u url.URL) (*nodeCoordinate, error) {
+	u.Path = "/coordinate"
+	u.RawQuery = ""
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make GET request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("expected status code 200, got %d", resp.StatusCode)
+	}
+	nc := &nodeCoordinate{}
+	if err := json.Unmarshal(body, nc); err != nil {
+		return nil, fmt.Errorf("response from node has wrong format: maybe it is not running this service?: %w", err)
+	}
+	return nc, nil
+}
+
+// UpdateFrom updates the coordinate of this node with the results of its own probes.
+// The coordinates of the probed nodes are fetched concurrently;
+// failed probes and nodes that do not run this service are ignored.
+func (v *vivaldi) UpdateFrom(ctx context.Context, lats []*Latency) {
+	var wg sync.WaitGroup
+	for _, l := range lats {
+		if !l.Ok {
+			continue
+		}
+		u, err := url.Parse(l.Destination)
+		if err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(u *url.URL, rtt time.Duration) {
+			defer wg.Done()
+			nc, err := fetchCoordinate(ctx, *u)
+			if err != nil {
+				log.Printf("failed to get coordinate from %s: %v\n", u.Host, err)
+				return
+			}
+			if nc.ID == v.id {
+				return
+			}
+			v.Update(nc.Coordinate, rtt)
+		}(u, l.Duration)
+	}
+	wg.Wait()
+}
+
+// UpdateInBackground updates the coordinate of this node like UpdateFrom without waiting for the coordinates of the probed nodes.
+// The update is given the timeout; it is skipped if the previous update is still running,
+// so that frequent vectors do not pile up requests to the probed nodes.
+func (v *vivaldi) UpdateInBackground(lats []*Latency, timeout time.Duration) {
+	if !atomic.CompareAndSwapInt32(&v.updating, 0, 1) {
+		return
+	}
+	// The latencies are copied, because the caller annotates them further.
+	cp := make([]*Latency, 0, len(lats))
+	for _, l := range lats {
+		cp = append(cp, &Latency{Destination: l.Destination, Duration: l.Duration, Ok: l.Ok})
+	}
+	go func() {
+		defer atomic.StoreInt32(&v.updating, 0)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		v.UpdateFrom(ctx, cp)
+	}()
+}
+
+func coordinateHandler(v *vivaldi) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, nodeCoordinate{ID: v.id, Coordinate: v.Coordinate()})
+	}
+}
+
+// A NodeCoordinate is the coordinate of a node in the embedding of the cluster.
+type NodeCoordinate struct {
+	Node       string     `json:"node"`
+	Coordinate Coordinate `json:"coordinate"`
+}
+
+// An Estimate is the latency between two nodes estimated from their coordinates.
+type Estimate struct {
+	Source      string        `json:"source"`
+	Destination string        `json:"destination"`
+	Estimate    time.Duration `json:"estimate"`
+	Error       time.Duration `json:"error"`
+	// Measured is the probed latency, if it is known.
+	Measured *time.Duration `json:"measured,omitempty"`
+}
+
+// Embedding is the embedding of all nodes of a matrix in the Vivaldi coordinate space.
+type Embedding struct {
+	Nodes     []NodeCoordinate `json:"nodes"`
+	Estimates []Estimate       `json:"estimates"`
+}
+
+// Embedding returns the coordinates of all sources of the matrix that advertised one
+// and the estimated latencies between all pairs of them.
+func (m matrix) Embedding() Embedding {
+	g := m.graph()
+	e := Embedding{
+		Nodes:     []NodeCoordinate{},
+		Estimates: []Estimate{},
+	}
+	var idx []int
+	for i, v := range m {
+		if v.Coordinate == nil {
+			continue
+		}
+		idx = append(idx, i)
+		e.Nodes = append(e.Nodes, NodeCoordinate{Node: g.names[i], Coordinate: *v.Coordinate})
+	}
+	for _, i := range idx {
+		for _, j := range idx {
+			if i == j {
+				continue
+			}
+			ci, cj := *m[i].Coordinate, *m[j].Coordinate
+			est := Estimate{
+				Source:      g.names[i],
+				Destination: g.names[j],
+				Estimate:    ci.DistanceTo(cj),
+				Error:       ci.ErrorTo(cj),
+			}
+			if l := g.lat[i][j]; l != nil && l.Ok {
+				d := l.Duration
+				est.Measured = &d
+			}
+			e.Estimates = append(e.Estimates, est)
+		}
+	}
+	return e
+}
+
+// SVG plots the embedding with every node at its position.
+// The size of a node is proportional to its height.
+func (e Embedding) SVG(w io.Writer) (err error) {
+	g := graphviz.New()
+	g.SetLayout(graphviz.NEATO)
+	graph, err := g.Graph()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := graph.Close(); err != nil {
+			log.Println(err)
+			return
+		}
+		g.Close()
+	}()
+	// Scale the embedding to roughly ten by ten inches.
+	var extent float64
+	for _, n := range e.Nodes {
+		extent = math.Max(extent, math.Max(math.Abs(n.Coordinate.Vec[0]), math.Abs(n.Coordinate.Vec[1])))
+	}
+	scale := 1.0
+	if extent > 0 {
+		scale = 5 / extent
+	}
+	for _, n := range e.Nodes {
+		var node *cgraph.Node
+		if node, err = graph.CreateNode(n.Node); err != nil {
+			return err
+		}
+		node.SetPos(n.Coordinate.Vec[0]*scale, n.Coordinate.Vec[1]*scale)
+		node.SetPin(true)
+		node.SetLabel(fmt.Sprintf("%s\n%v", n.Node, time.Duration(n.Coordinate.Height*float64(time.Second)).Round(time.Microsecond)))
+		node.SetWidth(0.5 + n.Coordinate.Height*scale)
+	}
+	return g.Render(graph, "svg", w)
+}
+
+func coordinatesHandler(srv string, timeout time.Duration) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m, _, ok := collectFromRequest(w, r, srv, timeout)
+		if !ok {
+			return
+		}
+		e := m.Embedding()
+		if r.URL.Query().Get("format") != "svg" {
+			writeJSON(w, e)
+			return
+		}
+		buf := &bytes.Buffer{}
+		if err := e.SVG(buf); err != nil {
+			log.Println(err)
+			errorCounter.Inc()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("content-type", "image/svg+xml")
+		w.Write(buf.Bytes())
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	mrand "math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestVivaldiConverges(t *testing.T) {
+	// The true positions of the nodes in milliseconds.
+	pos := [][2]float64{{0, 0}, {10, 0}, {0, 20}, {30, 30}, {5, 5}}
+	rtt := func(i, j int) time.Duration {
+		return time.Duration(math.Hypot(pos[i][0]-pos[j][0], pos[i][1]-pos[j][1]) * float64(time.Millisecond))
+	}
+	r := mrand.New(mrand.NewSource(1))
+	cs := make([]Coordinate, len(pos))
+	for i := range cs {
+		cs[i] = newCoordinate()
+	}
+	for round := 0; round < 1000; round++ {
+		i, j := r.Intn(len(pos)), r.Intn(len(pos))
+		if i == j {
+			continue
+		}
+		cs[i] = cs[i].update(cs[j], rtt(i, j), r)
+	}
+	for i := range cs {
+		for j := range cs {
+			if i == j {
+				continue
+			}
+			est, act := cs[i].DistanceTo(cs[j]), rtt(i, j)
+			if e := math.Abs(float64(est-act)) / float64(act); e > 0.2 {
+				t.Errorf("estimate from %d to %d is off by %.0f%%: got %v, expected %v", i, j, e*100, est, act)
+			}
+		}
+		if cs[i].Error >= vivaldiMaxError {
+			t.Errorf("error of %d did not decrease: %v", i, cs[i].Error)
+		}
+	}
+}
+
+func TestVivaldiUpdateCoincident(t *testing.T) {
+	r := mrand.New(mrand.NewSource(1))
+	c := newCoordinate().update(newCoordinate(), 10*time.Millisecond, r)
+	if c.Vec == [2]float64{} {
+		t.Error("expected coordinate to move away from a coincident remote")
+	}
+	if c := newCoordinate().update(newCoordinate(), 0, r); c != newCoordinate() {
+		t.Errorf("expected a zero round trip time to be ignored, got %v", c)
+	}
+}
+
+func TestVivaldiUpdateInBackground(t *testing.T) {
+	remote := newVivaldi()
+	var calls int32
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		coordinateHandler(remote)(w, r)
+	}))
+	defer s.Close()
+	v := newVivaldi()
+	lats := []*Latency{{Destination: s.URL, Duration: 10 * time.Millisecond, Ok: true}}
+	v.UpdateInBackground(lats, 5*time.Second)
+	// The update is still running, so this one is skipped.
+	v.UpdateInBackground(lats, 5*time.Second)
+	close(release)
+	for i := 0; v.Coordinate() == newCoordinate(); i++ {
+		if i == 100 {
+			t.Fatal("expected the coordinate to be updated")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if c := atomic.LoadInt32(&calls); c != 1 {
+		t.Errorf("expected 1 request for the coordinate, got %d", c)
+	}
+}
+
+func TestEmbedding(t *testing.T) {
+	m := squareMatrix([][]int{
+		{0, 3, 4},
+		{3, 0, -1},
+		{4, -1, 0},
+	})
+	c0 := Coordinate{Vec: [2]float64{0, 0}, Error: 0.1}
+	c1 := Coordinate{Vec: [2]float64{0.003, 0}, Error: 0.1}
+	c2 := Coordinate{Vec: [2]float64{0, 0.004}, Error: 0.3}
+	m[0].Coordinate, m[1].Coordinate, m[2].Coordinate = &c0, &c1, &c2
+	ms := func(n int) *time.Duration {
+		d := time.Duration(n) * time.Millisecond
+		return &d
+	}
+	e := Embedding{
+		Nodes: []NodeCoordinate{
+			{Node: "n0", Coordinate: c0},
+			{Node: "n1", Coordinate: c1},
+			{Node: "n2", Coordinate: c2},
+		},
+		Estimates: []Estimate{
+			{Source: "n0", Destination: "n1", Estimate: 3 * time.Millisecond, Error: 300 * time.Microsecond, Measured: ms(3)},
+			{Source: "n0", Destination: "n2", Estimate: 4 * time.Millisecond, Error: 800 * time.Microsecond, Measured: ms(4)},
+			{Source: "n1", Destination: "n0", Estimate: 3 * time.Millisecond, Error: 300 * time.Microsecond, Measured: ms(3)},
+			{Source: "n1", Destination: "n2", Estimate: 5 * time.Millisecond, Error: time.Millisecond},
+			{Source: "n2", Destination: "n0", Estimate: 4 * time.Millisecond, Error: 800 * time.Microsecond, Measured: ms(4)},
+			{Source: "n2", Destination: "n1", Estimate: 5 * time.Millisecond, Error: time.Millisecond},
+		},
+	}
+	got := m.Embedding()
+	// Floating point arithmetic can be off by a nanosecond.
+	for i := range got.Estimates {
+		got.Estimates[i].Estimate = got.Estimates[i].Estimate.Round(time.Microsecond)
+		got.Estimates[i].Error = got.Estimates[i].Error.Round(time.Microsecond)
+	}
+	if diff := pretty.Compare(got, e); diff != "" {
+		t.Errorf("unexpected embedding:\n%s", diff)
+	}
+	if err := got.SVG(&bytes.Buffer{}); err != nil {
+		t.Errorf("failed to render embedding: %v", err)
+	}
+}
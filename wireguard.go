@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// defaultStaleHandshake is the age after which the handshake of a peer is stale.
+// WireGuard renews sessions every two minutes and rejects them after three,
+// so a handshake that is older than three minutes means that the peer is not talking to this node.
+const defaultStaleHandshake = 3 * time.Minute
+
+// WireGuardPeer is the state of the WireGuard peer of a destination.
+type WireGuardPeer struct {
+	PublicKey string `json:"publicKey"`
+	Endpoint  string `json:"endpoint,omitempty"`
+	// LastHandshake is the zero time if there was no handshake yet.
+	LastHandshake time.Time     `json:"lastHandshake"`
+	HandshakeAge  time.Duration `json:"handshakeAge"`
+	RxBytes       int64         `json:"rxBytes"`
+	TxBytes       int64         `json:"txBytes"`
+	// Stale is true if the handshake is stale although the probe succeeded,
+	// e.g. because the probe took another path than the WireGuard tunnel.
+	Stale bool `json:"stale,omitempty"`
+}
+
+// wgClient reads the state of WireGuard devices.
+// It is implemented by *wgctrl.Client.
+type wgClient interface {
+	Device(name string) (*wgtypes.Device, error)
+}
+
+// wireguard annotates latencies with the state of the WireGuard peers of their destinations.
+// It is safe to use concurrently, if the client is.
+type wireguard struct {
+	c      wgClient
+	device string
+	stale  time.Duration
+}
+
+func newWireGuard(c wgClient, device string, stale time.Duration) *wireguard {
+	return &wireguard{c: c, device: device, stale: stale}
+}
+
+// peerFor returns the peer whose allowed IPs contain the IP or whose endpoint has the IP.
+func peerFor(peers []wgtypes.Peer, ip net.IP) *wgtypes.Peer {
+	for i, p := range peers {
+		if p.Endpoint != nil && p.Endpoint.IP.Equal(ip) {
+			return &peers[i]
+		}
+		for _, a := range p.AllowedIPs {
+			if a.Contains(ip) {
+				return &peers[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Annotate sets the WireGuard peers of the destinations of the latencies.
+// Destinations without a peer, e.g. this node itself, are left alone.
+func (wg *wireguard) Annotate(lats []*Latency, now time.Time) error {
+	d, err := wg.c.Device(wg.device)
+	if err != nil {
+		return fmt.Errorf("failed to read WireGuard device %s: %w", wg.device, err)
+	}
+	for _, l := range lats {
+		ip := net.ParseIP(l.IP)
+		if ip == nil {
+			continue
+		}
+		p := peerFor(d.Peers, ip)
+		if p == nil {
+			continue
+		}
+		wp := &WireGuardPeer{
+			PublicKey:     p.PublicKey.String(),
+			LastHandshake: p.LastHandshakeTime,
+			RxBytes:       p.ReceiveBytes,
+			TxBytes:       p.TransmitBytes,
+		}
+		if p.Endpoint != nil {
+			wp.Endpoint = p.Endpoint.String()
+		}
+		if !p.LastHandshakeTime.IsZero() {
+			wp.HandshakeAge = now.Sub(p.LastHandshakeTime)
+		}
+		wp.Stale = l.Ok && (p.LastHandshakeTime.IsZero() || wp.HandshakeAge > wg.stale)
+		l.WireGuard = wp
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// fakeWG returns a fixed device.
+type fakeWG struct {
+	d *wgtypes.Device
+}
+
+func (f *fakeWG) Device(name string) (*wgtypes.Device, error) {
+	if f.d == nil || f.d.Name != name {
+		return nil, errors.New("no such device")
+	}
+	return f.d, nil
+}
+
+func mustCIDR(t *testing.T, s string) net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *n
+}
+
+func TestWireGuardAnnotate(t *testing.T) {
+	now := time.Now()
+	fresh, stale := wgtypes.Key{1}, wgtypes.Key{2}
+	wg := newWireGuard(&fakeWG{d: &wgtypes.Device{
+		Name: "kilo0",
+		Peers: []wgtypes.Peer{
+			{
+				PublicKey:         fresh,
+				Endpoint:          &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 51820},
+				AllowedIPs:        []net.IPNet{mustCIDR(t, "10.4.0.1/32")},
+				LastHandshakeTime: now.Add(-time.Minute),
+				ReceiveBytes:      100,
+				TransmitBytes:     200,
+			},
+			{
+				PublicKey:         stale,
+				AllowedIPs:        []net.IPNet{mustCIDR(t, "10.4.0.2/32")},
+				LastHandshakeTime: now.Add(-10 * time.Minute),
+			},
+		},
+	}}, "kilo0", defaultStaleHandshake)
+	lats := []*Latency{
+		// The overlay IP of the first peer.
+		{Destination: "http://10.4.0.1:3000", IP: "10.4.0.1", Ok: true},
+		// The endpoint of the first peer.
+		{Destination: "http://192.0.2.1:3000", IP: "192.0.2.1", Ok: true},
+		{Destination: "http://10.4.0.2:3000", IP: "10.4.0.2", Ok: true},
+		// The handshake is only stale if the probe succeeded.
+		{Destination: "http://10.4.0.2:3001", IP: "10.4.0.2", Ok: false},
+		// Not a peer, e.g. this node.
+		{Destination: "http://10.4.0.3:3000", IP: "10.4.0.3", Ok: true},
+		{Destination: "http://n:3000", IP: naIP, Ok: true},
+	}
+	if err := wg.Annotate(lats, now); err != nil {
+		t.Fatalf("failed to annotate: %v", err)
+	}
+	freshPeer := &WireGuardPeer{
+		PublicKey:     fresh.String(),
+		Endpoint:      "192.0.2.1:51820",
+		LastHandshake: now.Add(-time.Minute),
+		HandshakeAge:  time.Minute,
+		RxBytes:       100,
+		TxBytes:       200,
+	}
+	stalePeer := &WireGuardPeer{
+		PublicKey:     stale.String(),
+		LastHandshake: now.Add(-10 * time.Minute),
+		HandshakeAge:  10 * time.Minute,
+		Stale:         true,
+	}
+	notOkPeer := *stalePeer
+	notOkPeer.Stale = false
+	for i, expected := range []*WireGuardPeer{freshPeer, freshPeer, stalePeer, &notOkPeer, nil, nil} {
+		if diff := pretty.Compare(lats[i].WireGuard, expected); diff != "" {
+			t.Errorf("%d: got diff:\n%s", i, diff)
+		}
+	}
+	if s := lats[2].String(); s != "0s ~" {
+		t.Errorf("expected stale handshake to be highlighted, got %q", s)
+	}
+	if err := newWireGuard(&fakeWG{}, "wg0", defaultStaleHandshake).Annotate(lats, now); err == nil {
+		t.Error("expected an error for a missing device")
+	}
+}